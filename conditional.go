@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// etag is a strong entity tag for a response body. Hashing the bytes sent,
+// rather than tagging rows by updated_at, makes it change with anything in
+// the representation, including per-caller fields like bookmarked_by_me.
+func etag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// representationETag is the tag sendConditionalJSONResponse would send for
+// v, for comparing against If-Match before changing a resource.
+func representationETag[T any](v T) (string, error) {
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return etag(data), nil
+}
+
+// sendConditionalJSONResponse is sendJSONResponse with validators: it tags
+// the body, adds Last-Modified unless lastModified is zero, and answers a
+// GET whose If-None-Match or If-Modified-Since shows the client's copy is
+// current with 304 Not Modified. Clients must revalidate before reusing a
+// copy, and shared caches must not keep one, since bodies depend on the
+// caller.
+func sendConditionalJSONResponse[T any](w http.ResponseWriter, req *http.Request, statusCode int, v T, lastModified time.Time) {
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("Error marshalling JSON", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tag := etag(data)
+	h := w.Header()
+	h.Set("ETag", tag)
+	if !lastModified.IsZero() {
+		h.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	h.Set("Cache-Control", "private, no-cache")
+	h.Add("Vary", "Authorization")
+
+	if statusCode == http.StatusOK && (req.Method == http.MethodGet || req.Method == http.MethodHead) && notModified(req, tag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	sendResponse(w, CONTENT_TYPE_JSON, statusCode, data)
+
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when there is
+// none, as RFC 9110 orders them.
+func notModified(req *http.Request, tag string, lastModified time.Time) bool {
+
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		return matchETag(inm, tag, false)
+	}
+
+	ims := req.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+
+	// HTTP dates have no fraction of a second.
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// ifMatch reports whether req may change a resource whose current
+// representation is current: it sent no If-Match, or one listing current's
+// tag or "*".
+func ifMatch[T any](req *http.Request, current T) (bool, error) {
+
+	header := req.Header.Get("If-Match")
+	if header == "" {
+		return true, nil
+	}
+
+	tag, err := representationETag(current)
+	if err != nil {
+		return false, err
+	}
+
+	return matchETag(header, tag, true), nil
+}
+
+// matchETag reports whether a comma-separated If-Match or If-None-Match
+// list contains tag. If-Match compares strongly, so weak tags never match
+// it; If-None-Match compares weakly.
+func matchETag(list, tag string, strong bool) bool {
+
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak, ok := strings.CutPrefix(candidate, "W/"); ok {
+			if strong {
+				continue
+			}
+			candidate = weak
+		}
+		if candidate == tag {
+			return true
+		}
+	}
+
+	return false
+}
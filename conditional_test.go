@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestMatchETag(t *testing.T) {
+	tests := []struct {
+		list   string
+		strong bool
+		want   bool
+	}{
+		{list: `"abc"`, want: true},
+		{list: `"xyz", "abc"`, want: true},
+		{list: `*`, want: true},
+		{list: `W/"abc"`, want: true},
+		{list: `W/"abc"`, strong: true, want: false},
+		{list: `"abc"`, strong: true, want: true},
+		{list: `"xyz"`, want: false},
+		{list: `abc`, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := matchETag(tt.list, `"abc"`, tt.strong); got != tt.want {
+			t.Errorf("matchETag(%s, strong %v) = %v, want %v", tt.list, tt.strong, got, tt.want)
+		}
+	}
+}
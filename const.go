@@ -1,22 +1,121 @@
 package main
 
+import "time"
+
 const WEBHOOKS_UPGRADE_EVENT = "user.upgraded"
 
 const CONTENT_TYPE_PLAIN_TEXT = "text/plain; charset=utf-8"
 const CONTENT_TYPE_HTML = "text/html"
 const CONTENT_TYPE_JSON = "application/json"
+const CONTENT_TYPE_EVENT_STREAM = "text/event-stream"
+const CONTENT_TYPE_NDJSON = "application/x-ndjson"
+const CONTENT_TYPE_CSV = "text/csv; charset=utf-8"
 
 const METRICS_HTML = `<html>
   <body>
     <h1>Welcome, Chirpy Admin</h1>
     <p>Chirpy has been visited %d times!</p>
+    <ul>
+%s    </ul>
   </body>
 </html>`
 
-const VALID_CHIRP_LENGTH = 140
+// MAX_REQUEST_BODY_SIZE caps the JSON bodies handlers decode.
+const MAX_REQUEST_BODY_SIZE = 64 << 10
+
+const DEFAULT_PAGE_SIZE = 20
+const MAX_PAGE_SIZE = 100
+
+// COMPRESSION_MIN_SIZE is the smallest response body worth compressing;
+// below it the encoding overhead outweighs the savings.
+const COMPRESSION_MIN_SIZE = 1024
+
+// EXPORT_FLUSH_INTERVAL is how many rows of an NDJSON or CSV export are
+// written between flushes.
+const EXPORT_FLUSH_INTERVAL = 100
+
+// CHIRP_EXPORT_LIMIT is the most chirps an NDJSON or CSV export may hold.
+const CHIRP_EXPORT_LIMIT = 10000
+
+const MEDIA_PUBLIC_DIR = "media/public"
+const MEDIA_PRIVATE_DIR = "media/private"
+const SIGNED_URL_TTL = 15 * time.Minute
+
+const STREAM_BUFFER_SIZE = 64
+const STREAM_REPLAY_LIMIT = 1000
+const STREAM_HEARTBEAT = 15 * time.Second
+const STREAM_RETRY = 3 * time.Second
+
+// STREAM_POLL_INTERVAL is how often the event log is read on databases that
+// cannot notify the relay of new events.
+const STREAM_POLL_INTERVAL = time.Second
+
+const WS_READ_LIMIT = 4096
+const WS_WRITE_TIMEOUT = 5 * time.Second
+const WS_SESSION_CHECK = 30 * time.Second
+const WS_TOPIC_TIMELINE = "timeline"
+const WS_TOPIC_THREAD = "thread"
+const WS_TOPIC_NOTIFICATIONS = "notifications"
+
+const SCOPE_READ = "read"
+const SCOPE_WRITE = "write"
+const SCOPE_FOLLOW = "follow"
+const SCOPE_ADMIN = "admin"
+
+const OAUTH_CODE_TTL = 10 * time.Minute
+const OAUTH_ACCESS_TOKEN_TTL = time.Hour
+const OAUTH_CLIENT_ID_PREFIX = "chirpy_"
+
+const READINESS_TIMEOUT = 2 * time.Second
+
+const LOGIN_METHOD_PASSWORD = "password"
+const LOGIN_METHOD_OIDC = "oidc"
+
+const OIDC_STATE_TTL = 10 * time.Minute
+const OIDC_DISCOVERY_TIMEOUT = 10 * time.Second
+
+// PASSWORD_UNSET is the hashed_password column default; accounts created
+// through an identity provider keep it until the user sets a password.
+const PASSWORD_UNSET = "unset"
+
+const API_KEY_PREFIX = "chirpy_pk_"
+const API_KEY_DISPLAY_LENGTH = 16
+
+// API_KEY_TOUCH_INTERVAL is how stale an API key's last_used_at may get
+// before a request using it updates it.
+const API_KEY_TOUCH_INTERVAL = time.Minute
+
+var OAUTH_SCOPES = []string{
+	SCOPE_READ,
+	SCOPE_WRITE,
+	SCOPE_FOLLOW,
+	SCOPE_ADMIN,
+}
+
+// METRICS_SUMMARY lists the series shown on the admin metrics page.
+var METRICS_SUMMARY = []metricSummary{
+	{label: "Requests served", metric: "chirpy_http_requests_total"},
+	{label: "Chirps created", metric: "chirpy_chirps_created_total"},
+	{label: "Logins", metric: "chirpy_logins_total", labels: map[string]string{"result": "success"}},
+	{label: "Failed logins", metric: "chirpy_logins_total", labels: map[string]string{"result": "failure"}},
+	{label: "Webhook deliveries", metric: "chirpy_webhook_deliveries_total", labels: map[string]string{"result": "success"}},
+	{label: "Failed webhook deliveries", metric: "chirpy_webhook_deliveries_total", labels: map[string]string{"result": "failure"}},
+	{label: "Database connections in use", metric: "go_sql_in_use_connections"},
+}
+
+// CHIRP_LIST_FORMATS are the representations of a list of chirps, preferred
+// first when the client weighs them equally.
+var CHIRP_LIST_FORMATS = []string{
+	CONTENT_TYPE_JSON,
+	CONTENT_TYPE_NDJSON,
+	CONTENT_TYPE_CSV,
+}
+
+var CHIRP_CSV_HEADER = []string{"id", "created_at", "updated_at", "body", "user_id", "bookmarked_by_me"}
 
-var PROFANE_WORDS = map[string]bool{
-	"kerfuffle": true,
-	"sharbert":  true,
-	"fornax":    true,
+var ASSET_ALLOW_LIST = []string{
+	"index.html",
+	"assets",
+	MEDIA_PUBLIC_DIR,
+	MEDIA_PRIVATE_DIR,
 }
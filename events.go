@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/logging"
+	"github.com/ghis9917/chirpy/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+// The helpers below fan domain events out to the notification and webhook
+// subsystems. They log instead of failing: the write that caused the event
+// has already succeeded.
+
+func (cfg *apiConfig) chirpCreated(ctx context.Context, chirp database.Chirp) {
+
+	cfg.metrics.ChirpCreated()
+	cfg.notifier.ChirpCreated(ctx, chirp)
+
+	if err := webhooks.Enqueue(ctx, cfg.db, chirp.UserID, webhooks.EventChirpCreated, toChirp(chirp)); err != nil {
+		logging.FromContext(ctx).Error("Error enqueueing webhooks", "event", webhooks.EventChirpCreated, "err", err)
+	}
+
+}
+
+func (cfg *apiConfig) chirpDeleted(ctx context.Context, chirp database.Chirp) {
+
+	if err := webhooks.Enqueue(ctx, cfg.db, chirp.UserID, webhooks.EventChirpDeleted, toChirp(chirp)); err != nil {
+		logging.FromContext(ctx).Error("Error enqueueing webhooks", "event", webhooks.EventChirpDeleted, "err", err)
+	}
+
+}
+
+func (cfg *apiConfig) userUpgraded(ctx context.Context, userID uuid.UUID) {
+
+	data := upgradeUserParamsData{UserID: userID.String()}
+	if err := webhooks.Enqueue(ctx, cfg.db, userID, webhooks.EventUserUpgraded, data); err != nil {
+		logging.FromContext(ctx).Error("Error enqueueing webhooks", "event", webhooks.EventUserUpgraded, "err", err)
+	}
+
+}
@@ -1,19 +1,33 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"path"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/ghis9917/chirpy/internal/auth"
 	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/logging"
+	"github.com/ghis9917/chirpy/internal/store"
 	"github.com/google/uuid"
 )
 
-func handleReadiness(w http.ResponseWriter, req *http.Request) {
+// Handlers that run in a transaction return these from it to roll it back
+// and pick the response status.
+var (
+	errPreconditionFailed = errors.New("Resource has changed since it was read; fetch it again")
+)
+
+// handleLiveness only reports that the process is serving requests; it
+// never checks dependencies, so a database outage does not get us
+// restarted.
+func (cfg *apiConfig) handleLiveness(w http.ResponseWriter, req *http.Request) {
 	sendResponse(
 		w,
 		CONTENT_TYPE_PLAIN_TEXT,
@@ -22,22 +36,106 @@ func handleReadiness(w http.ResponseWriter, req *http.Request) {
 	)
 }
 
+// handleReadiness runs every registered check. It also starts failing as
+// soon as shutdown begins so that load balancers stop sending us traffic
+// while in-flight requests drain.
+func (cfg *apiConfig) handleReadiness(w http.ResponseWriter, req *http.Request) {
+
+	report := cfg.health.Run(req.Context())
+
+	status := http.StatusOK
+	if !report.OK() {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	sendJSONResponse(w, status, report)
+}
+
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		cfg.fileserverHits.Add(1)
+		cfg.metrics.FileserverHit()
+		next.ServeHTTP(w, req)
+	})
+
+}
+
+// middlewareScope rejects requests without valid credentials and access
+// tokens issued to OAuth clients without the given scope.
+func (cfg *apiConfig) middlewareScope(scope string, next http.HandlerFunc) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req, claims, err := cfg.withAuthorization(req)
+		if err != nil {
+			sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+			return
+		}
+		if !claims.HasScope(scope) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, scope))
+			sendJSONResponse(w, http.StatusForbidden, jsonErr{Error: fmt.Sprintf("Token is missing the %s scope", scope)})
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+
+}
+
+// middlewareFirstParty keeps OAuth clients and API keys away from routes
+// that manage credentials, whatever scopes they were granted.
+func (cfg *apiConfig) middlewareFirstParty(next http.HandlerFunc) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req, claims, err := cfg.withAuthorization(req)
+		if err != nil {
+			sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+			return
+		}
+		if !claims.FirstParty() {
+			sendJSONResponse(w, http.StatusForbidden, jsonErr{Error: "Only available to signed-in users"})
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+
+}
+
+// middlewareOptionalAuth is for public routes that show more to a caller
+// with credentials. Anonymous requests and invalid credentials are let
+// through; handlers check the scope of any claims they use.
+func (cfg *apiConfig) middlewareOptionalAuth(next http.HandlerFunc) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req, _, _ = cfg.withAuthorization(req)
 		next.ServeHTTP(w, req)
 	})
 
 }
 
+// handleGetMetrics renders a summary of the same registry /metrics exposes.
 func (cfg *apiConfig) handleGetMetrics(w http.ResponseWriter, req *http.Request) {
 
+	hits, err := cfg.metrics.Total("chirpy_fileserver_hits_total", nil)
+	if err != nil {
+		sendResponse(w, CONTENT_TYPE_PLAIN_TEXT, http.StatusInternalServerError, []byte{})
+		return
+	}
+
+	var items strings.Builder
+	for _, s := range METRICS_SUMMARY {
+		value, err := cfg.metrics.Total(s.metric, s.labels)
+		if err != nil {
+			sendResponse(w, CONTENT_TYPE_PLAIN_TEXT, http.StatusInternalServerError, []byte{})
+			return
+		}
+		fmt.Fprintf(&items, "      <li>%s: %v</li>\n", s.label, value)
+	}
+
 	sendResponse(
 		w,
 		CONTENT_TYPE_HTML,
 		http.StatusOK,
-		[]byte(fmt.Sprintf(METRICS_HTML, cfg.fileserverHits.Load())),
+		[]byte(fmt.Sprintf(METRICS_HTML, int64(hits), items.String())),
 	)
 
 }
@@ -53,9 +151,10 @@ func (cfg *apiConfig) handleResetMetrics(w http.ResponseWriter, req *http.Reques
 			[]byte{},
 		)
 
+		return
 	}
 
-	cfg.fileserverHits.Store(0)
+	cfg.metrics.ResetFileserverHits()
 	if err := cfg.db.DeleteAllUsers(req.Context()); err != nil {
 
 		sendResponse(
@@ -65,13 +164,14 @@ func (cfg *apiConfig) handleResetMetrics(w http.ResponseWriter, req *http.Reques
 			[]byte{},
 		)
 
+		return
 	}
 
 	sendResponse(
 		w,
 		CONTENT_TYPE_PLAIN_TEXT,
 		http.StatusOK,
-		[]byte(fmt.Sprintf("Hits: %v\n", cfg.fileserverHits.Load())),
+		[]byte("Hits: 0\n"),
 	)
 
 }
@@ -80,17 +180,11 @@ func (cfg *apiConfig) handleCreateUser(w http.ResponseWriter, req *http.Request)
 
 	params, err := extractParams(createUserParameters{}, req)
 	if err != nil {
-
-		sendJSONResponse(
-			w,
-			http.StatusInternalServerError,
-			jsonErr{Error: fmt.Sprintf("%s", err)},
-		)
-
+		sendParamsError(w, err)
 		return
 	}
 
-	hash, err := auth.HashPassword(params.Password)
+	hash, err := auth.HashPassword(req.Context(), params.Password)
 	if err != nil {
 		sendJSONResponse(
 			w,
@@ -136,33 +230,33 @@ func (cfg *apiConfig) handleCreateUser(w http.ResponseWriter, req *http.Request)
 
 func (cfg *apiConfig) handleCreateChirp(w http.ResponseWriter, req *http.Request) {
 
-	bearer, err := auth.GetBearerToken(req.Header)
+	userID, err := cfg.authenticate(req)
 	if err != nil {
-		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
 		return
 	}
 
 	params, err := extractParams(createChirpParameters{}, req)
 	if err != nil {
-		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		sendParamsError(w, err)
 		return
 	}
 
-	userID, err := auth.ValidateJWT(bearer, cfg.serverSecret)
+	body, err := cfg.validateChirp(params.Body)
 	if err != nil {
-		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		sendParamsError(w, err)
 		return
 	}
 
-	if len(params.Body) > VALID_CHIRP_LENGTH {
-		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: "Chirp is too long"})
+	if params.ScheduledAt != nil && params.ScheduledAt.After(time.Now()) {
+		cfg.scheduleChirp(w, req, userID, body, *params.ScheduledAt)
 		return
 	}
 
 	chirp, err := cfg.db.CreateChirp(
 		req.Context(),
 		database.CreateChirpParams{
-			Body:   cleanChirp(params.Body),
+			Body:   body,
 			UserID: userID,
 		},
 	)
@@ -172,6 +266,8 @@ func (cfg *apiConfig) handleCreateChirp(w http.ResponseWriter, req *http.Request
 
 	}
 
+	cfg.chirpCreated(req.Context(), chirp)
+
 	sendJSONResponse(
 		w,
 		http.StatusCreated,
@@ -186,6 +282,100 @@ func (cfg *apiConfig) handleCreateChirp(w http.ResponseWriter, req *http.Request
 
 }
 
+func (cfg *apiConfig) scheduleChirp(w http.ResponseWriter, req *http.Request, userID uuid.UUID, body string, scheduledAt time.Time) {
+
+	user, err := cfg.db.GetUserByID(req.Context(), userID)
+	if err != nil {
+		sendJSONResponse(w, http.StatusNotFound, jsonErr{Error: fmt.Sprintf("Couldn't find UserID: %v", err)})
+		return
+	}
+
+	if !user.IsChirpyRed {
+		sendJSONResponse(w, http.StatusForbidden, jsonErr{Error: "Scheduling chirps requires Chirpy Red"})
+		return
+	}
+
+	scheduled, err := cfg.db.CreateScheduledChirp(
+		req.Context(),
+		database.CreateScheduledChirpParams{
+			Body:        body,
+			UserID:      userID,
+			ScheduledAt: scheduledAt.UTC(),
+		},
+	)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	sendJSONResponse(
+		w,
+		http.StatusAccepted,
+		toScheduledChirp(scheduled),
+	)
+
+}
+
+func (cfg *apiConfig) handleGetScheduledChirps(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	scheduled, err := cfg.db.GetScheduledChirpsByUser(req.Context(), userID)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	data := []ScheduledChirp{}
+	for _, s := range scheduled {
+		data = append(data, toScheduledChirp(s))
+	}
+
+	sendJSONResponse(
+		w,
+		http.StatusOK,
+		data,
+	)
+
+}
+
+func (cfg *apiConfig) handleDeleteScheduledChirp(w http.ResponseWriter, req *http.Request) {
+
+	scheduledUUID, err := uuid.Parse(req.PathValue("scheduledID"))
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: fmt.Sprintf("Invalid ScheduledID: %v", err)})
+		return
+	}
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	scheduled, err := cfg.db.GetScheduledChirpByID(req.Context(), scheduledUUID)
+	if err != nil {
+		sendJSONResponse(w, http.StatusNotFound, jsonErr{Error: fmt.Sprintf("Scheduled chirp not found: %v", err)})
+		return
+	}
+
+	if scheduled.UserID != userID {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if err = cfg.db.DeleteScheduledChirpByID(req.Context(), scheduledUUID); err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (cfg *apiConfig) handleGetAllChirps(w http.ResponseWriter, req *http.Request) {
 
 	authorId := req.URL.Query().Get("author_id")
@@ -229,18 +419,19 @@ func (cfg *apiConfig) handleGetAllChirps(w http.ResponseWriter, req *http.Reques
 		)
 	}
 
-	sendJSONResponse(
-		w,
-		http.StatusOK,
-		data,
-	)
+	if err := cfg.markBookmarks(req, data); err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	sendChirps(w, req, data)
 }
 
 func (cfg *apiConfig) handleGetChirpByID(w http.ResponseWriter, req *http.Request) {
 
 	chirpID := req.PathValue("chirpID")
 	if chirpID == "" {
-		log.Printf("Missing chirpID")
+		logging.FromContext(req.Context()).Info("Missing chirpID")
 
 		sendJSONResponse(
 			w,
@@ -253,7 +444,7 @@ func (cfg *apiConfig) handleGetChirpByID(w http.ResponseWriter, req *http.Reques
 
 	chirpUUID, err := uuid.Parse(chirpID)
 	if err != nil {
-		log.Printf("Could not parse chirpID string into a valid UUID")
+		logging.FromContext(req.Context()).Info("Could not parse chirpID string into a valid UUID", "chirp_id", chirpID)
 
 		sendJSONResponse(
 			w,
@@ -269,7 +460,7 @@ func (cfg *apiConfig) handleGetChirpByID(w http.ResponseWriter, req *http.Reques
 		chirpUUID,
 	)
 	if err != nil {
-		log.Printf("Chirp not found")
+		logging.FromContext(req.Context()).Info("Chirp not found", "chirp_id", chirpUUID)
 
 		sendJSONResponse(
 			w,
@@ -280,16 +471,18 @@ func (cfg *apiConfig) handleGetChirpByID(w http.ResponseWriter, req *http.Reques
 		return
 	}
 
-	sendJSONResponse(
+	data := []Chirp{toChirp(chirp)}
+	if err := cfg.markBookmarks(req, data); err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	sendConditionalJSONResponse(
 		w,
+		req,
 		http.StatusOK,
-		Chirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
-		},
+		data[0],
+		chirp.UpdatedAt,
 	)
 
 }
@@ -298,72 +491,95 @@ func (cfg *apiConfig) handleLogin(w http.ResponseWriter, req *http.Request) {
 
 	params, err := extractParams(loginUserParameters{}, req)
 	if err != nil {
-		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		sendParamsError(w, err)
 		return
 	}
 
-	user, err := cfg.db.GetUserByEmail(
-		req.Context(),
-		params.Email,
-	)
+	user, err := cfg.db.GetUserCredentialsByEmail(req.Context(), params.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.metrics.Login(LOGIN_METHOD_PASSWORD, false)
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: "Incorrect password"})
+		return
+	}
 	if err != nil {
+		cfg.metrics.Login(LOGIN_METHOD_PASSWORD, false)
 		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
 		return
 	}
 
-	check, err := auth.CheckPassword(params.Password, user.HashedPassword)
+	if user.HashedPassword == PASSWORD_UNSET {
+		cfg.metrics.Login(LOGIN_METHOD_PASSWORD, false)
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: "Incorrect password"})
+		return
+	}
+
+	check, err := auth.CheckPassword(req.Context(), params.Password, user.HashedPassword)
 	if err != nil {
+		cfg.metrics.Login(LOGIN_METHOD_PASSWORD, false)
 		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
 		return
 	}
-
 	if !check {
+		cfg.metrics.Login(LOGIN_METHOD_PASSWORD, false)
 		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: "Incorrect password"})
 		return
 	}
 
+	var response loginUserResponse
+	err = cfg.db.InTx(req.Context(), func(q store.Queries) error {
+		response, err = cfg.startSession(req.Context(), q, user)
+		return err
+	})
+	if err != nil {
+		cfg.metrics.Login(LOGIN_METHOD_PASSWORD, false)
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+	cfg.metrics.Login(LOGIN_METHOD_PASSWORD, true)
+
+	sendJSONResponse(w, http.StatusOK, response)
+
+}
+
+// startSession issues the first-party access and refresh tokens returned by
+// every way of signing in. The refresh token is stored through q, so it is
+// only kept if the sign-in's transaction commits.
+func (cfg *apiConfig) startSession(ctx context.Context, q store.Queries, user database.User) (loginUserResponse, error) {
+
 	accessToken, err := auth.MakeJWT(
 		user.ID,
 		cfg.serverSecret,
 		time.Hour,
 	)
 	if err != nil {
-		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
-		return
+		return loginUserResponse{}, err
 	}
 
 	refreshToken, err := auth.MakeRefreshToken()
 	if err != nil {
-		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
-		return
+		return loginUserResponse{}, err
 	}
 
-	_, err = cfg.db.CreateRefreshRoken(
-		req.Context(),
+	_, err = q.CreateRefreshRoken(
+		ctx,
 		database.CreateRefreshRokenParams{
 			Token:  refreshToken,
 			UserID: user.ID,
 		},
 	)
 	if err != nil {
-		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
-		return
+		return loginUserResponse{}, err
 	}
 
-	sendJSONResponse(
-		w,
-		http.StatusOK,
-		loginUserResponse{
-			ID:           user.ID,
-			CreatedAt:    user.CreatedAt,
-			UpdatedAt:    user.UpdatedAt,
-			Email:        user.Email,
-			IsChirpyRed:  user.IsChirpyRed,
-			Token:        accessToken,
-			RefreshToken: refreshToken,
-		},
-	)
-
+	return loginUserResponse{
+		ID:           user.ID,
+		CreatedAt:    user.CreatedAt,
+		UpdatedAt:    user.UpdatedAt,
+		Email:        user.Email,
+		IsChirpyRed:  user.IsChirpyRed,
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+	}, nil
 }
 
 func (cfg *apiConfig) handleRefresh(w http.ResponseWriter, req *http.Request) {
@@ -383,6 +599,11 @@ func (cfg *apiConfig) handleRefresh(w http.ResponseWriter, req *http.Request) {
 		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: "Token has been revoked"})
 		return
 	}
+	// Tokens issued to OAuth clients can only be refreshed at /api/oauth/token.
+	if token.ClientID.Valid {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: "Token not found"})
+		return
+	}
 
 	accessToken, err := auth.MakeJWT(
 		token.UserID,
@@ -439,15 +660,34 @@ func (cfg *apiConfig) handleRevoke(w http.ResponseWriter, req *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (cfg *apiConfig) handleUpdateUser(w http.ResponseWriter, req *http.Request) {
+func (cfg *apiConfig) handleGetUser(w http.ResponseWriter, req *http.Request) {
 
-	bearer, err := auth.GetBearerToken(req.Header)
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(req.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		sendJSONResponse(w, http.StatusNotFound, jsonErr{Error: fmt.Sprintf("Couldn't find UserID: %v", err)})
+		return
+	}
 	if err != nil {
-		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: "Could not find bearer token"})
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
 		return
 	}
 
-	userID, err := auth.ValidateJWT(bearer, cfg.serverSecret)
+	sendConditionalJSONResponse(w, req, http.StatusOK, toUserResponse(user), user.UpdatedAt)
+
+}
+
+// handleUpdateUser honours If-Match, so two clients editing the account
+// cannot silently overwrite each other: the second gets 412 and has to
+// fetch the user again.
+func (cfg *apiConfig) handleUpdateUser(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
 	if err != nil {
 		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
 		return
@@ -455,42 +695,94 @@ func (cfg *apiConfig) handleUpdateUser(w http.ResponseWriter, req *http.Request)
 
 	params, err := extractParams(updateUserParameters{}, req)
 	if err != nil {
-		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		sendParamsError(w, err)
 		return
 	}
 
-	hash, err := auth.HashPassword(params.Password)
+	hash, err := auth.HashPassword(req.Context(), params.Password)
 	if err != nil {
 		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
 		return
 	}
 
-	user, err := cfg.db.UpdateUser(
-		req.Context(),
-		database.UpdateUserParams{
-			Email:          params.Email,
-			HashedPassword: hash,
-			ID:             userID,
-		},
-	)
+	var user database.User
+	err = cfg.db.InTx(req.Context(), func(q store.Queries) error {
+
+		current, err := q.GetUserByID(req.Context(), userID)
+		if err != nil {
+			return err
+		}
+		if ok, err := ifMatch(req, toUserResponse(current)); err != nil {
+			return err
+		} else if !ok {
+			return errPreconditionFailed
+		}
+
+		user, err = q.UpdateUser(
+			req.Context(),
+			database.UpdateUserParams{
+				Email:          params.Email,
+				HashedPassword: hash,
+				ID:             userID,
+			},
+		)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		sendJSONResponse(w, http.StatusNotFound, jsonErr{Error: fmt.Sprintf("Couldn't find UserID: %v", err)})
+		return
+	}
+	if errors.Is(err, errPreconditionFailed) {
+		sendJSONResponse(w, http.StatusPreconditionFailed, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
 	if err != nil {
 		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
 		return
+	}
+
+	sendConditionalJSONResponse(w, req, http.StatusOK, toUserResponse(user), user.UpdatedAt)
+
+}
 
+// handleDeleteUser closes the caller's account. Everything the account owns
+// goes with it through the foreign keys' ON DELETE CASCADE.
+func (cfg *apiConfig) handleDeleteUser(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
 	}
 
-	sendJSONResponse(
-		w,
-		http.StatusOK,
-		updateUserResponse{
-			ID:          user.ID,
-			CreatedAt:   user.CreatedAt,
-			UpdatedAt:   user.UpdatedAt,
-			Email:       user.Email,
-			IsChirpyRed: user.IsChirpyRed,
-		},
-	)
+	err = cfg.db.InTx(req.Context(), func(q store.Queries) error {
 
+		user, err := q.GetUserByID(req.Context(), userID)
+		if err != nil {
+			return err
+		}
+		if ok, err := ifMatch(req, toUserResponse(user)); err != nil {
+			return err
+		} else if !ok {
+			return errPreconditionFailed
+		}
+
+		return q.DeleteUserByID(req.Context(), userID)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		sendJSONResponse(w, http.StatusNotFound, jsonErr{Error: fmt.Sprintf("Couldn't find UserID: %v", err)})
+		return
+	}
+	if errors.Is(err, errPreconditionFailed) {
+		sendJSONResponse(w, http.StatusPreconditionFailed, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (cfg *apiConfig) handleDeleteChirpByID(w http.ResponseWriter, req *http.Request) {
@@ -507,13 +799,7 @@ func (cfg *apiConfig) handleDeleteChirpByID(w http.ResponseWriter, req *http.Req
 		return
 	}
 
-	bearer, err := auth.GetBearerToken(req.Header)
-	if err != nil {
-		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: "Could not find bearer token"})
-		return
-	}
-
-	userID, err := auth.ValidateJWT(bearer, cfg.serverSecret)
+	userID, err := cfg.authenticate(req)
 	if err != nil {
 		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
 		return
@@ -533,6 +819,22 @@ func (cfg *apiConfig) handleDeleteChirpByID(w http.ResponseWriter, req *http.Req
 		return
 	}
 
+	// Chirps never change once published, but the tag also covers the
+	// caller's bookmark, so it is checked against the same representation
+	// GET returns.
+	data := []Chirp{toChirp(chirp)}
+	if err := cfg.markBookmarks(req, data); err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+	if ok, err := ifMatch(req, data[0]); err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	} else if !ok {
+		sendJSONResponse(w, http.StatusPreconditionFailed, jsonErr{Error: fmt.Sprintf("%s", errPreconditionFailed)})
+		return
+	}
+
 	if err = cfg.db.DeleteChirpByID(
 		req.Context(),
 		chirpUUID,
@@ -541,6 +843,8 @@ func (cfg *apiConfig) handleDeleteChirpByID(w http.ResponseWriter, req *http.Req
 		return
 	}
 
+	cfg.chirpDeleted(req.Context(), chirp)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -559,7 +863,7 @@ func (cfg *apiConfig) handleUpgradeUser(w http.ResponseWriter, req *http.Request
 
 	params, err := extractParams(upgradeUserParams{}, req)
 	if err != nil {
-		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		sendParamsError(w, err)
 		return
 	}
 
@@ -574,13 +878,71 @@ func (cfg *apiConfig) handleUpgradeUser(w http.ResponseWriter, req *http.Request
 		return
 	}
 
-	if err = cfg.db.UpgradeUser(
-		req.Context(),
-		userID,
-	); err != nil {
+	// Polka retries webhooks it considers undelivered, so upgrading a user
+	// who is already Chirpy Red succeeds without raising the event again.
+	upgraded := false
+	err = cfg.db.InTx(req.Context(), func(q store.Queries) error {
+
+		upgraded = false
+		user, err := q.GetUserByID(req.Context(), userID)
+		if err != nil {
+			return err
+		}
+		if user.IsChirpyRed {
+			return nil
+		}
+
+		if err := q.UpgradeUser(req.Context(), userID); err != nil {
+			return err
+		}
+		upgraded = true
+
+		return nil
+	})
+	if errors.Is(err, sql.ErrNoRows) {
 		sendJSONResponse(w, http.StatusNotFound, jsonErr{Error: fmt.Sprintf("Couldn't find UserID: %v", err)})
 		return
 	}
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	if upgraded {
+		cfg.userUpgraded(req.Context(), userID)
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (cfg *apiConfig) handleSignMediaURL(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	mediaPath := path.Clean("/" + req.URL.Query().Get("path"))
+	if !cfg.assets.IsPrivate(mediaPath) {
+		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: "Path is not private media"})
+		return
+	}
+
+	ownerDir := "/" + MEDIA_PRIVATE_DIR + "/" + userID.String() + "/"
+	if !strings.HasPrefix(mediaPath, ownerDir) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	expiresAt := time.Now().Add(SIGNED_URL_TTL).UTC()
+	sendJSONResponse(
+		w,
+		http.StatusOK,
+		signedURLResponse{
+			URL:       "/app" + cfg.assets.Sign(mediaPath, expiresAt),
+			ExpiresAt: expiresAt,
+		},
+	)
+
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ghis9917/chirpy/internal/auth"
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handleCreateAPIKey(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	params, err := extractParams(createAPIKeyParameters{}, req)
+	if err != nil {
+		sendParamsError(w, err)
+		return
+	}
+
+	scopes, err := parseScopes(strings.Join(params.Scopes, " "), OAUTH_SCOPES)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	expiresAt := sql.NullTime{}
+	if params.ExpiresAt != nil {
+		expiresAt = sql.NullTime{Time: params.ExpiresAt.UTC(), Valid: true}
+	}
+
+	secret, err := auth.MakeRefreshToken()
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+	apiKey := API_KEY_PREFIX + secret
+
+	key, err := cfg.db.CreateAPIKey(
+		req.Context(),
+		database.CreateAPIKeyParams{
+			UserID:    userID,
+			Name:      params.Name,
+			Prefix:    apiKey[:API_KEY_DISPLAY_LENGTH],
+			KeyHash:   auth.HashToken(apiKey),
+			Scopes:    scopes,
+			ExpiresAt: expiresAt,
+		},
+	)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	// The key itself is only ever returned here; we keep just its hash.
+	response := toAPIKey(key)
+	response.Key = apiKey
+
+	sendJSONResponse(w, http.StatusCreated, response)
+
+}
+
+func (cfg *apiConfig) handleGetAPIKeys(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	keys, err := cfg.db.GetAPIKeysByUser(req.Context(), userID)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	data := []APIKey{}
+	for _, k := range keys {
+		data = append(data, toAPIKey(k))
+	}
+
+	sendJSONResponse(w, http.StatusOK, data)
+
+}
+
+func (cfg *apiConfig) handleRevokeAPIKey(w http.ResponseWriter, req *http.Request) {
+
+	keyUUID, err := uuid.Parse(req.PathValue("keyID"))
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: fmt.Sprintf("Invalid KeyID: %v", err)})
+		return
+	}
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	key, err := cfg.db.GetAPIKeyByID(req.Context(), keyUUID)
+	if err != nil {
+		sendJSONResponse(w, http.StatusNotFound, jsonErr{Error: fmt.Sprintf("API key not found: %v", err)})
+		return
+	}
+
+	if key.UserID != userID {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if err := cfg.db.RevokeAPIKey(req.Context(), key.ID); err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
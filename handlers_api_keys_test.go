@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// doWithAPIKey sends a request authenticated by a personal API key.
+func (s *testServer) doWithAPIKey(method, target, apiKey string, body any) *httptest.ResponseRecorder {
+	req := s.request(method, target, body)
+	req.Header.Set("Authorization", "ApiKey "+apiKey)
+	return s.serve(req)
+}
+
+func TestAPIKeys(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.createUser("alice@example.com")
+	_, otherToken := s.createUser("bob@example.com")
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name   string
+		params createAPIKeyParameters
+		want   int
+	}{
+		{name: "read only", params: createAPIKeyParameters{Name: "CI", Scopes: []string{SCOPE_READ}}, want: http.StatusCreated},
+		{name: "no name", params: createAPIKeyParameters{Scopes: []string{SCOPE_READ}}, want: http.StatusBadRequest},
+		{name: "unknown scope", params: createAPIKeyParameters{Name: "CI", Scopes: []string{"everything"}}, want: http.StatusBadRequest},
+		{name: "expired", params: createAPIKeyParameters{Name: "CI", ExpiresAt: &past}, want: http.StatusBadRequest},
+	}
+
+	var key APIKey
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do("POST", "/api/keys", token, tt.params)
+			expectStatus(t, rec, tt.want)
+			if tt.want != http.StatusCreated {
+				return
+			}
+			key = decode[APIKey](t, rec)
+			if !strings.HasPrefix(key.Key, key.Prefix) || !strings.HasPrefix(key.Prefix, API_KEY_PREFIX) {
+				t.Errorf("key = %+v, want %s prefixed key", key, API_KEY_PREFIX)
+			}
+		})
+	}
+	expectStatus(t, s.do("POST", "/api/keys", "", tests[0].params), http.StatusUnauthorized)
+
+	// Each request looks the key up once, and only the first within
+	// API_KEY_TOUCH_INTERVAL records the use.
+	counted := &countingStore{Store: s.cfg.db}
+	s.cfg.db = counted
+	for _, target := range []string{"/api/drafts", "/api/chirps", "/api/drafts"} {
+		expectStatus(t, s.doWithAPIKey("GET", target, key.Key, nil), http.StatusOK)
+	}
+	if lookups, touches := counted.apiKeyLookups.Load(), counted.apiKeyTouches.Load(); lookups != 3 || touches != 1 {
+		t.Errorf("3 requests made %d key lookups and %d touches, want 3 and 1", lookups, touches)
+	}
+
+	expectStatus(t, s.doWithAPIKey("GET", "/api/drafts", key.Key, nil), http.StatusOK)
+	expectStatus(t, s.doWithAPIKey("POST", "/api/drafts", key.Key, draftParameters{Body: "x"}), http.StatusForbidden)
+	expectStatus(t, s.doWithAPIKey("GET", "/api/keys", key.Key, nil), http.StatusForbidden)
+	expectStatus(t, s.doWithAPIKey("GET", "/api/drafts", API_KEY_PREFIX+"unknown", nil), http.StatusUnauthorized)
+
+	rec := s.do("GET", "/api/keys", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	keys := decode[[]APIKey](t, rec)
+	if len(keys) != 1 || keys[0].Key != "" || keys[0].LastUsedAt == nil {
+		t.Fatalf("keys = %+v, want one used key without its secret", keys)
+	}
+	expectStatus(t, s.do("GET", "/api/keys", "", nil), http.StatusUnauthorized)
+
+	target := "/api/keys/" + key.ID.String()
+	expectStatus(t, s.do("DELETE", "/api/keys/not-a-uuid", token, nil), http.StatusBadRequest)
+	expectStatus(t, s.do("DELETE", "/api/keys/"+uuid.NewString(), token, nil), http.StatusNotFound)
+	expectStatus(t, s.do("DELETE", target, otherToken, nil), http.StatusForbidden)
+	expectStatus(t, s.do("DELETE", target, token, nil), http.StatusNoContent)
+
+	expectStatus(t, s.doWithAPIKey("GET", "/api/drafts", key.Key, nil), http.StatusUnauthorized)
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handleCreateBookmark(w http.ResponseWriter, req *http.Request) {
+
+	userID, chirpID, ok := cfg.bookmarkTarget(w, req)
+	if !ok {
+		return
+	}
+
+	if err := cfg.db.CreateBookmark(
+		req.Context(),
+		database.CreateBookmarkParams{
+			UserID:  userID,
+			ChirpID: chirpID,
+		},
+	); err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handleDeleteBookmark(w http.ResponseWriter, req *http.Request) {
+
+	userID, chirpID, ok := cfg.bookmarkTarget(w, req)
+	if !ok {
+		return
+	}
+
+	if err := cfg.db.DeleteBookmark(
+		req.Context(),
+		database.DeleteBookmarkParams{
+			UserID:  userID,
+			ChirpID: chirpID,
+		},
+	); err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handleGetBookmarks(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	limit, offset, err := parsePagination(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	chirps, err := cfg.db.GetBookmarkedChirps(
+		req.Context(),
+		database.GetBookmarkedChirpsParams{
+			UserID: userID,
+			Limit:  limit,
+			Offset: offset,
+		},
+	)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	bookmarked := true
+	data := []Chirp{}
+	for _, c := range chirps {
+		chirp := toChirp(c)
+		chirp.BookmarkedByMe = &bookmarked
+		data = append(data, chirp)
+	}
+
+	sendChirps(w, req, data)
+
+}
+
+func (cfg *apiConfig) handleCreateCollection(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	params, err := extractParams(collectionParameters{}, req)
+	if err != nil {
+		sendParamsError(w, err)
+		return
+	}
+
+	collection, err := cfg.db.CreateCollection(
+		req.Context(),
+		database.CreateCollectionParams{
+			UserID: userID,
+			Name:   params.Name,
+		},
+	)
+	if err != nil {
+		sendJSONResponse(w, http.StatusConflict, jsonErr{Error: fmt.Sprintf("Could not create collection: %v", err)})
+		return
+	}
+
+	sendJSONResponse(w, http.StatusCreated, toCollection(collection))
+
+}
+
+func (cfg *apiConfig) handleGetCollections(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	limit, offset, err := parsePagination(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	collections, err := cfg.db.GetCollectionsByUser(
+		req.Context(),
+		database.GetCollectionsByUserParams{
+			UserID: userID,
+			Limit:  limit,
+			Offset: offset,
+		},
+	)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	data := []Collection{}
+	for _, c := range collections {
+		data = append(data, toCollection(c))
+	}
+
+	sendJSONResponse(w, http.StatusOK, data)
+
+}
+
+func (cfg *apiConfig) handleRenameCollection(w http.ResponseWriter, req *http.Request) {
+
+	collection, ok := cfg.ownedCollection(w, req)
+	if !ok {
+		return
+	}
+
+	params, err := extractParams(collectionParameters{}, req)
+	if err != nil {
+		sendParamsError(w, err)
+		return
+	}
+
+	renamed, err := cfg.db.RenameCollection(
+		req.Context(),
+		database.RenameCollectionParams{
+			Name: params.Name,
+			ID:   collection.ID,
+		},
+	)
+	if err != nil {
+		sendJSONResponse(w, http.StatusConflict, jsonErr{Error: fmt.Sprintf("Could not rename collection: %v", err)})
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, toCollection(renamed))
+
+}
+
+func (cfg *apiConfig) handleDeleteCollection(w http.ResponseWriter, req *http.Request) {
+
+	collection, ok := cfg.ownedCollection(w, req)
+	if !ok {
+		return
+	}
+
+	if err := cfg.db.DeleteCollectionByID(req.Context(), collection.ID); err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handleGetCollectionChirps(w http.ResponseWriter, req *http.Request) {
+
+	collection, ok := cfg.ownedCollection(w, req)
+	if !ok {
+		return
+	}
+
+	limit, offset, err := parsePagination(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	chirps, err := cfg.db.GetCollectionChirps(
+		req.Context(),
+		database.GetCollectionChirpsParams{
+			CollectionID: collection.ID,
+			Limit:        limit,
+			Offset:       offset,
+		},
+	)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	data := []Chirp{}
+	for _, c := range chirps {
+		data = append(data, toChirp(c))
+	}
+
+	if err := cfg.markBookmarks(req, data); err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	sendChirps(w, req, data)
+
+}
+
+func (cfg *apiConfig) handleAddCollectionChirp(w http.ResponseWriter, req *http.Request) {
+
+	collection, ok := cfg.ownedCollection(w, req)
+	if !ok {
+		return
+	}
+
+	params, err := extractParams(collectionChirpParameters{}, req)
+	if err != nil {
+		sendParamsError(w, err)
+		return
+	}
+
+	chirpUUID, err := uuid.Parse(params.ChirpID)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: fmt.Sprintf("Invalid ChirpID: %v", err)})
+		return
+	}
+
+	if _, err = cfg.db.GetChirpByID(req.Context(), chirpUUID); err != nil {
+		sendJSONResponse(w, http.StatusNotFound, jsonErr{Error: fmt.Sprintf("Chirp not found: %v", err)})
+		return
+	}
+
+	if err = cfg.db.AddChirpToCollection(
+		req.Context(),
+		database.AddChirpToCollectionParams{
+			CollectionID: collection.ID,
+			ChirpID:      chirpUUID,
+		},
+	); err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handleRemoveCollectionChirp(w http.ResponseWriter, req *http.Request) {
+
+	collection, ok := cfg.ownedCollection(w, req)
+	if !ok {
+		return
+	}
+
+	chirpUUID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: fmt.Sprintf("Invalid ChirpID: %v", err)})
+		return
+	}
+
+	if err = cfg.db.RemoveChirpFromCollection(
+		req.Context(),
+		database.RemoveChirpFromCollectionParams{
+			CollectionID: collection.ID,
+			ChirpID:      chirpUUID,
+		},
+	); err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) bookmarkTarget(w http.ResponseWriter, req *http.Request) (uuid.UUID, uuid.UUID, bool) {
+
+	chirpUUID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: fmt.Sprintf("Invalid ChirpID: %v", err)})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	if _, err = cfg.db.GetChirpByID(req.Context(), chirpUUID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			sendJSONResponse(w, http.StatusNotFound, jsonErr{Error: fmt.Sprintf("Chirp not found: %v", err)})
+			return uuid.Nil, uuid.Nil, false
+		}
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, chirpUUID, true
+}
+
+func (cfg *apiConfig) ownedCollection(w http.ResponseWriter, req *http.Request) (database.Collection, bool) {
+
+	collectionUUID, err := uuid.Parse(req.PathValue("collectionID"))
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: fmt.Sprintf("Invalid CollectionID: %v", err)})
+		return database.Collection{}, false
+	}
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return database.Collection{}, false
+	}
+
+	collection, err := cfg.db.GetCollectionByID(req.Context(), collectionUUID)
+	if err != nil {
+		sendJSONResponse(w, http.StatusNotFound, jsonErr{Error: fmt.Sprintf("Collection not found: %v", err)})
+		return database.Collection{}, false
+	}
+
+	if collection.UserID != userID {
+		w.WriteHeader(http.StatusForbidden)
+		return database.Collection{}, false
+	}
+
+	return collection, true
+}
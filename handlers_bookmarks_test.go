@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestBookmarks(t *testing.T) {
+	s := newTestServer(t)
+	user, token := s.createUser("alice@example.com")
+	first := s.createChirp(user.ID, "first")
+	second := s.createChirp(user.ID, "second")
+
+	expectStatus(t, s.do("POST", "/api/bookmarks/not-a-uuid", token, nil), http.StatusBadRequest)
+	expectStatus(t, s.do("POST", "/api/bookmarks/"+first.ID.String(), "", nil), http.StatusUnauthorized)
+	expectStatus(t, s.do("POST", "/api/bookmarks/"+uuid.NewString(), token, nil), http.StatusNotFound)
+
+	for _, c := range []uuid.UUID{first.ID, second.ID} {
+		expectStatus(t, s.do("POST", "/api/bookmarks/"+c.String(), token, nil), http.StatusNoContent)
+	}
+	// Bookmarking twice is not an error.
+	expectStatus(t, s.do("POST", "/api/bookmarks/"+first.ID.String(), token, nil), http.StatusNoContent)
+
+	rec := s.do("GET", "/api/bookmarks", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	got := decode[[]Chirp](t, rec)
+	if len(got) != 2 {
+		t.Fatalf("bookmarks = %+v, want 2", got)
+	}
+	for _, c := range got {
+		if c.BookmarkedByMe == nil || !*c.BookmarkedByMe {
+			t.Errorf("bookmark %s bookmarked_by_me = %v, want true", c.ID, c.BookmarkedByMe)
+		}
+	}
+
+	rec = s.do("GET", "/api/bookmarks?limit=1&offset=1", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if got := decode[[]Chirp](t, rec); len(got) != 1 {
+		t.Errorf("second page = %+v, want 1 chirp", got)
+	}
+	expectStatus(t, s.do("GET", "/api/bookmarks?limit=0", token, nil), http.StatusBadRequest)
+	expectStatus(t, s.do("GET", "/api/bookmarks?offset=-1", token, nil), http.StatusBadRequest)
+	expectStatus(t, s.do("GET", "/api/bookmarks", "", nil), http.StatusUnauthorized)
+
+	expectStatus(t, s.do("DELETE", "/api/bookmarks/"+first.ID.String(), token, nil), http.StatusNoContent)
+	rec = s.do("GET", "/api/bookmarks", token, nil)
+	if got := decode[[]Chirp](t, rec); len(got) != 1 || got[0].ID != second.ID {
+		t.Errorf("bookmarks after delete = %+v, want [%s]", got, second.ID)
+	}
+}
+
+func TestCollections(t *testing.T) {
+	s := newTestServer(t)
+	user, token := s.createUser("alice@example.com")
+	_, otherToken := s.createUser("bob@example.com")
+	chirp := s.createChirp(user.ID, "worth keeping")
+
+	expectStatus(t, s.do("POST", "/api/collections", "", collectionParameters{Name: "Favourites"}), http.StatusUnauthorized)
+	expectStatus(t, s.do("POST", "/api/collections", token, collectionParameters{}), http.StatusBadRequest)
+	expectStatus(t, s.do("POST", "/api/collections", token, "{"), http.StatusBadRequest)
+
+	rec := s.do("POST", "/api/collections", token, collectionParameters{Name: "Favourites"})
+	expectStatus(t, rec, http.StatusCreated)
+	collection := decode[Collection](t, rec)
+	target := "/api/collections/" + collection.ID.String()
+
+	expectStatus(t, s.do("POST", "/api/collections", token, collectionParameters{Name: "Favourites"}), http.StatusConflict)
+	expectStatus(t, s.do("POST", "/api/collections", otherToken, collectionParameters{Name: "Favourites"}), http.StatusCreated)
+	expectStatus(t, s.do("POST", "/api/collections", token, collectionParameters{Name: "Later"}), http.StatusCreated)
+
+	rec = s.do("GET", "/api/collections", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if got := decode[[]Collection](t, rec); len(got) != 2 {
+		t.Errorf("collections = %+v, want 2", got)
+	}
+	expectStatus(t, s.do("GET", "/api/collections?limit=1000", token, nil), http.StatusBadRequest)
+	expectStatus(t, s.do("GET", "/api/collections", "", nil), http.StatusUnauthorized)
+
+	rec = s.do("PUT", target, token, collectionParameters{Name: "Best of"})
+	expectStatus(t, rec, http.StatusOK)
+	if got := decode[Collection](t, rec); got.Name != "Best of" {
+		t.Errorf("renamed collection = %q, want %q", got.Name, "Best of")
+	}
+	expectStatus(t, s.do("PUT", target, token, collectionParameters{Name: "Later"}), http.StatusConflict)
+	expectStatus(t, s.do("PUT", target, token, collectionParameters{}), http.StatusBadRequest)
+	expectStatus(t, s.do("PUT", target, otherToken, collectionParameters{Name: "Mine"}), http.StatusForbidden)
+	expectStatus(t, s.do("PUT", "/api/collections/not-a-uuid", token, collectionParameters{Name: "x"}), http.StatusBadRequest)
+	expectStatus(t, s.do("PUT", "/api/collections/"+uuid.NewString(), token, collectionParameters{Name: "x"}), http.StatusNotFound)
+
+	chirps := target + "/chirps"
+	expectStatus(t, s.do("POST", chirps, token, collectionChirpParameters{ChirpID: "nope"}), http.StatusBadRequest)
+	expectStatus(t, s.do("POST", chirps, token, collectionChirpParameters{ChirpID: uuid.NewString()}), http.StatusNotFound)
+	expectStatus(t, s.do("POST", chirps, otherToken, collectionChirpParameters{ChirpID: chirp.ID.String()}), http.StatusForbidden)
+	expectStatus(t, s.do("POST", chirps, token, collectionChirpParameters{ChirpID: chirp.ID.String()}), http.StatusNoContent)
+
+	rec = s.do("GET", chirps, token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if got := decode[[]Chirp](t, rec); len(got) != 1 || got[0].ID != chirp.ID {
+		t.Errorf("collection chirps = %+v, want [%s]", got, chirp.ID)
+	}
+	expectStatus(t, s.do("GET", chirps, otherToken, nil), http.StatusForbidden)
+
+	expectStatus(t, s.do("DELETE", chirps+"/not-a-uuid", token, nil), http.StatusBadRequest)
+	expectStatus(t, s.do("DELETE", chirps+"/"+chirp.ID.String(), token, nil), http.StatusNoContent)
+	rec = s.do("GET", chirps, token, nil)
+	if got := decode[[]Chirp](t, rec); len(got) != 0 {
+		t.Errorf("collection chirps after removal = %+v, want none", got)
+	}
+
+	expectStatus(t, s.do("DELETE", target, otherToken, nil), http.StatusForbidden)
+	expectStatus(t, s.do("DELETE", target, token, nil), http.StatusNoContent)
+	expectStatus(t, s.do("GET", chirps, token, nil), http.StatusNotFound)
+}
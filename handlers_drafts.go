@@ -0,0 +1,193 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/store"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handleCreateDraft(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	params, err := extractParams(draftParameters{}, req)
+	if err != nil {
+		sendParamsError(w, err)
+		return
+	}
+
+	draft, err := cfg.db.CreateDraft(
+		req.Context(),
+		database.CreateDraftParams{
+			Body:   params.Body,
+			UserID: userID,
+		},
+	)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	sendJSONResponse(w, http.StatusCreated, toDraft(draft))
+
+}
+
+func (cfg *apiConfig) handleGetDrafts(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	drafts, err := cfg.db.GetDraftsByUser(req.Context(), userID)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	data := []Draft{}
+	for _, d := range drafts {
+		data = append(data, toDraft(d))
+	}
+
+	sendJSONResponse(w, http.StatusOK, data)
+
+}
+
+func (cfg *apiConfig) handleUpdateDraft(w http.ResponseWriter, req *http.Request) {
+
+	draft, ok := cfg.ownedDraft(w, req)
+	if !ok {
+		return
+	}
+
+	params, err := extractParams(draftParameters{}, req)
+	if err != nil {
+		sendParamsError(w, err)
+		return
+	}
+
+	updated, err := cfg.db.UpdateDraft(
+		req.Context(),
+		database.UpdateDraftParams{
+			Body: params.Body,
+			ID:   draft.ID,
+		},
+	)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, toDraft(updated))
+
+}
+
+func (cfg *apiConfig) handleDeleteDraft(w http.ResponseWriter, req *http.Request) {
+
+	draft, ok := cfg.ownedDraft(w, req)
+	if !ok {
+		return
+	}
+
+	if err := cfg.db.DeleteDraftByID(req.Context(), draft.ID); err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlePublishDraft(w http.ResponseWriter, req *http.Request) {
+
+	draft, ok := cfg.ownedDraft(w, req)
+	if !ok {
+		return
+	}
+
+	// Claiming the draft deletes it, so of several concurrent publishes only
+	// the first finds it and the rest get a 404 instead of a second chirp.
+	var chirp database.Chirp
+	err := cfg.db.InTx(req.Context(), func(q store.Queries) error {
+
+		claimed, err := q.ClaimDraft(
+			req.Context(),
+			database.ClaimDraftParams{
+				ID:     draft.ID,
+				UserID: draft.UserID,
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		body, err := cfg.validateChirp(claimed.Body)
+		if err != nil {
+			return err
+		}
+
+		chirp, err = q.CreateChirp(
+			req.Context(),
+			database.CreateChirpParams{
+				Body:   body,
+				UserID: claimed.UserID,
+			},
+		)
+		return err
+	})
+	var invalid *validationError
+	switch {
+	case errors.As(err, &invalid):
+		sendParamsError(w, err)
+		return
+	case errors.Is(err, sql.ErrNoRows):
+		sendJSONResponse(w, http.StatusNotFound, jsonErr{Error: fmt.Sprintf("Draft not found: %v", err)})
+		return
+	case err != nil:
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	cfg.chirpCreated(req.Context(), chirp)
+
+	sendJSONResponse(w, http.StatusCreated, toChirp(chirp))
+
+}
+
+func (cfg *apiConfig) ownedDraft(w http.ResponseWriter, req *http.Request) (database.Draft, bool) {
+
+	draftUUID, err := uuid.Parse(req.PathValue("draftID"))
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: fmt.Sprintf("Invalid DraftID: %v", err)})
+		return database.Draft{}, false
+	}
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return database.Draft{}, false
+	}
+
+	draft, err := cfg.db.GetDraftByID(req.Context(), draftUUID)
+	if err != nil {
+		sendJSONResponse(w, http.StatusNotFound, jsonErr{Error: fmt.Sprintf("Draft not found: %v", err)})
+		return database.Draft{}, false
+	}
+
+	if draft.UserID != userID {
+		w.WriteHeader(http.StatusForbidden)
+		return database.Draft{}, false
+	}
+
+	return draft, true
+}
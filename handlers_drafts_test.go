@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestDrafts(t *testing.T) {
+	s := newTestServer(t)
+	user, token := s.createUser("alice@example.com")
+	_, otherToken := s.createUser("bob@example.com")
+
+	expectStatus(t, s.do("POST", "/api/drafts", "", draftParameters{Body: "idea"}), http.StatusUnauthorized)
+	expectStatus(t, s.do("POST", "/api/drafts", token, "{"), http.StatusBadRequest)
+
+	rec := s.do("POST", "/api/drafts", token, draftParameters{Body: "idea"})
+	expectStatus(t, rec, http.StatusCreated)
+	draft := decode[Draft](t, rec)
+	target := "/api/drafts/" + draft.ID.String()
+
+	rec = s.do("PUT", target, token, draftParameters{Body: "better idea"})
+	expectStatus(t, rec, http.StatusOK)
+	if got := decode[Draft](t, rec); got.Body != "better idea" {
+		t.Errorf("updated draft body = %q, want %q", got.Body, "better idea")
+	}
+	expectStatus(t, s.do("PUT", target, token, "{"), http.StatusBadRequest)
+	expectStatus(t, s.do("PUT", target, otherToken, draftParameters{Body: "mine now"}), http.StatusForbidden)
+	expectStatus(t, s.do("PUT", "/api/drafts/not-a-uuid", token, draftParameters{}), http.StatusBadRequest)
+
+	rec = s.do("GET", "/api/drafts", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if got := decode[[]Draft](t, rec); len(got) != 1 || got[0].ID != draft.ID {
+		t.Errorf("drafts = %+v, want [%s]", got, draft.ID)
+	}
+	rec = s.do("GET", "/api/drafts", otherToken, nil)
+	if got := decode[[]Draft](t, rec); len(got) != 0 {
+		t.Errorf("other user's drafts = %+v, want none", got)
+	}
+	expectStatus(t, s.do("GET", "/api/drafts", "", nil), http.StatusUnauthorized)
+
+	expectStatus(t, s.do("DELETE", target, otherToken, nil), http.StatusForbidden)
+	expectStatus(t, s.do("DELETE", target, "", nil), http.StatusUnauthorized)
+	expectStatus(t, s.do("DELETE", target, token, nil), http.StatusNoContent)
+	expectStatus(t, s.do("DELETE", target, token, nil), http.StatusNotFound)
+
+	rec = s.do("POST", "/api/drafts", token, draftParameters{Body: "ready to go"})
+	published := decode[Draft](t, rec)
+	target = "/api/drafts/" + published.ID.String() + "/publish"
+	expectStatus(t, s.do("POST", target, otherToken, nil), http.StatusForbidden)
+
+	rec = s.do("POST", target, token, nil)
+	expectStatus(t, rec, http.StatusCreated)
+	if got := decode[Chirp](t, rec); got.Body != "ready to go" || got.UserID != user.ID {
+		t.Errorf("published chirp = %+v, want %q by %s", got, "ready to go", user.ID)
+	}
+	expectStatus(t, s.do("POST", target, token, nil), http.StatusNotFound)
+}
+
+func TestPublishDraftTooLong(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.createUser("alice@example.com")
+	s.cfg.chirps.MaxLength = 5
+
+	rec := s.do("POST", "/api/drafts", token, draftParameters{Body: "far too long"})
+	expectStatus(t, rec, http.StatusCreated)
+	draft := decode[Draft](t, rec)
+
+	expectStatus(t, s.do("POST", "/api/drafts/"+draft.ID.String()+"/publish", token, nil), http.StatusBadRequest)
+	rec = s.do("GET", "/api/drafts", token, nil)
+	if got := decode[[]Draft](t, rec); len(got) != 1 {
+		t.Errorf("drafts = %+v, want the draft kept", got)
+	}
+}
+
+func TestPublishDraftConcurrently(t *testing.T) {
+	s := newTestServer(t)
+	user, token := s.createUser("alice@example.com")
+
+	rec := s.do("POST", "/api/drafts", token, draftParameters{Body: "only once"})
+	expectStatus(t, rec, http.StatusCreated)
+	target := "/api/drafts/" + decode[Draft](t, rec).ID.String() + "/publish"
+
+	codes := make([]int, 8)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Go(func() {
+			codes[i] = s.do("POST", target, token, nil).Code
+		})
+	}
+	wg.Wait()
+
+	created := 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusCreated:
+			created++
+		case http.StatusNotFound:
+		default:
+			t.Errorf("publish status = %d, want %d or %d", code, http.StatusCreated, http.StatusNotFound)
+		}
+	}
+	if created != 1 {
+		t.Errorf("%d publishes succeeded, want 1", created)
+	}
+
+	rec = s.do("GET", "/api/chirps?author_id="+user.ID.String(), "", nil)
+	if got := decode[[]Chirp](t, rec); len(got) != 1 {
+		t.Errorf("chirps = %+v, want exactly one", got)
+	}
+}
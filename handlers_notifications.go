@@ -0,0 +1,171 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/notifications"
+	"github.com/ghis9917/chirpy/internal/store"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handleGetNotifications(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	limit, offset, err := parsePagination(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	unread, err := cfg.db.CountUnreadNotifications(req.Context(), userID)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	groups, err := cfg.db.GetGroupedNotifications(
+		req.Context(),
+		database.GetGroupedNotificationsParams{
+			UserID: userID,
+			Limit:  limit,
+			Offset: offset,
+		},
+	)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	data := []NotificationGroup{}
+	for _, g := range groups {
+		group := NotificationGroup{
+			Type:     g.Type,
+			Count:    g.Count,
+			Unread:   g.Unread,
+			LatestAt: g.LatestAt,
+			ActorIDs: g.ActorIds,
+			Summary:  notifications.Summary(notifications.Type(g.Type), g.Count),
+		}
+		if g.ChirpID.Valid {
+			group.ChirpID = &g.ChirpID.UUID
+		}
+		data = append(data, group)
+	}
+
+	sendJSONResponse(
+		w,
+		http.StatusOK,
+		notificationsResponse{
+			UnreadCount:   unread,
+			Notifications: data,
+		},
+	)
+
+}
+
+func (cfg *apiConfig) handleMarkNotificationsRead(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	params, err := extractParams(markNotificationsReadParameters{}, req)
+	if err != nil {
+		sendParamsError(w, err)
+		return
+	}
+
+	markParams := database.MarkNotificationsReadParams{UserID: userID}
+	if params.Type != "" {
+		markParams.Type = sql.NullString{String: params.Type, Valid: true}
+	}
+	if params.ChirpID != "" {
+		chirpUUID, err := uuid.Parse(params.ChirpID)
+		if err != nil {
+			sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: fmt.Sprintf("Invalid ChirpID: %v", err)})
+			return
+		}
+		markParams.ChirpID = uuid.NullUUID{UUID: chirpUUID, Valid: true}
+	}
+
+	if err = cfg.db.MarkNotificationsRead(req.Context(), markParams); err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handleGetNotificationPreferences(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	muted, err := cfg.db.GetMutedNotificationTypes(req.Context(), userID)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+	if muted == nil {
+		muted = []string{}
+	}
+
+	sendJSONResponse(w, http.StatusOK, notificationPreferences{Muted: muted})
+
+}
+
+func (cfg *apiConfig) handleUpdateNotificationPreferences(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	params, err := extractParams(notificationPreferences{}, req)
+	if err != nil {
+		sendParamsError(w, err)
+		return
+	}
+
+	err = cfg.db.InTx(req.Context(), func(q store.Queries) error {
+
+		if err := q.DeleteMutedNotificationTypes(req.Context(), userID); err != nil {
+			return err
+		}
+
+		for _, t := range params.Muted {
+			if err := q.MuteNotificationType(
+				req.Context(),
+				database.MuteNotificationTypeParams{
+					UserID: userID,
+					Type:   t,
+				},
+			); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	cfg.handleGetNotificationPreferences(w, req)
+
+}
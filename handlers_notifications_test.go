@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/ghis9917/chirpy/internal/notifications"
+)
+
+func TestNotifications(t *testing.T) {
+	s := newTestServer(t)
+	alice, aliceToken := s.createUser("alice@example.com")
+	_, bobToken := s.createUser("bob@example.com")
+	_, carolToken := s.createUser("carol@example.com")
+
+	// Mentions are recorded when a chirp is published; mentioning yourself
+	// is not.
+	for i, token := range []string{bobToken, carolToken, aliceToken} {
+		body := fmt.Sprintf("hi @alice@example.com #%d", i)
+		rec := s.do("POST", "/api/chirps", token, createChirpParameters{Body: body})
+		expectStatus(t, rec, http.StatusCreated)
+	}
+
+	rec := s.do("GET", "/api/notifications", aliceToken, nil)
+	expectStatus(t, rec, http.StatusOK)
+	got := decode[notificationsResponse](t, rec)
+	if got.UnreadCount != 2 {
+		t.Errorf("unread count = %d, want 2", got.UnreadCount)
+	}
+	if len(got.Notifications) != 2 {
+		t.Fatalf("notification groups = %+v, want one per chirp", got.Notifications)
+	}
+	for _, g := range got.Notifications {
+		if g.Type != string(notifications.TypeMention) || g.Count != 1 || g.Unread != 1 || g.ChirpID == nil {
+			t.Errorf("group = %+v, want one unread mention of a chirp", g)
+		}
+		if slices.Contains(g.ActorIDs, alice.ID) {
+			t.Errorf("group = %+v, want alice's own mention left out", g)
+		}
+	}
+
+	expectStatus(t, s.do("GET", "/api/notifications", "", nil), http.StatusUnauthorized)
+	expectStatus(t, s.do("GET", "/api/notifications?limit=nope", aliceToken, nil), http.StatusBadRequest)
+
+	first := got.Notifications[0].ChirpID.String()
+	expectStatus(t, s.do("POST", "/api/notifications/read", aliceToken, markNotificationsReadParameters{ChirpID: "nope"}), http.StatusBadRequest)
+	expectStatus(t, s.do("POST", "/api/notifications/read", aliceToken, "{"), http.StatusBadRequest)
+	expectStatus(t, s.do("POST", "/api/notifications/read", "", markNotificationsReadParameters{}), http.StatusUnauthorized)
+	expectStatus(t, s.do("POST", "/api/notifications/read", aliceToken, markNotificationsReadParameters{ChirpID: first}), http.StatusNoContent)
+
+	rec = s.do("GET", "/api/notifications", aliceToken, nil)
+	if got := decode[notificationsResponse](t, rec); got.UnreadCount != 1 {
+		t.Errorf("unread count after marking one chirp = %d, want 1", got.UnreadCount)
+	}
+
+	expectStatus(t, s.do("POST", "/api/notifications/read", aliceToken, markNotificationsReadParameters{Type: string(notifications.TypeMention)}), http.StatusNoContent)
+	rec = s.do("GET", "/api/notifications", aliceToken, nil)
+	if got := decode[notificationsResponse](t, rec); got.UnreadCount != 0 {
+		t.Errorf("unread count after marking mentions = %d, want 0", got.UnreadCount)
+	}
+}
+
+func TestNotificationPreferences(t *testing.T) {
+	s := newTestServer(t)
+	_, aliceToken := s.createUser("alice@example.com")
+	_, bobToken := s.createUser("bob@example.com")
+
+	rec := s.do("GET", "/api/notifications/preferences", aliceToken, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if got := decode[notificationPreferences](t, rec); got.Muted == nil || len(got.Muted) != 0 {
+		t.Errorf("preferences = %+v, want an empty list", got)
+	}
+
+	mentions := notificationPreferences{Muted: []string{string(notifications.TypeMention)}}
+	expectStatus(t, s.do("PUT", "/api/notifications/preferences", aliceToken, notificationPreferences{Muted: []string{"spam"}}), http.StatusBadRequest)
+	expectStatus(t, s.do("PUT", "/api/notifications/preferences", aliceToken, "{"), http.StatusBadRequest)
+	expectStatus(t, s.do("PUT", "/api/notifications/preferences", "", mentions), http.StatusUnauthorized)
+	expectStatus(t, s.do("GET", "/api/notifications/preferences", "", nil), http.StatusUnauthorized)
+
+	rec = s.do("PUT", "/api/notifications/preferences", aliceToken, mentions)
+	expectStatus(t, rec, http.StatusOK)
+	if got := decode[notificationPreferences](t, rec); !slices.Equal(got.Muted, mentions.Muted) {
+		t.Errorf("preferences = %+v, want %+v", got, mentions)
+	}
+
+	expectStatus(t, s.do("POST", "/api/chirps", bobToken, createChirpParameters{Body: "hi @alice@example.com"}), http.StatusCreated)
+	rec = s.do("GET", "/api/notifications", aliceToken, nil)
+	if got := decode[notificationsResponse](t, rec); got.UnreadCount != 0 || len(got.Notifications) != 0 {
+		t.Errorf("notifications = %+v, want muted mentions dropped", got)
+	}
+
+	// The list replaces what was muted before.
+	rec = s.do("PUT", "/api/notifications/preferences", aliceToken, notificationPreferences{Muted: []string{}})
+	if got := decode[notificationPreferences](t, rec); len(got.Muted) != 0 {
+		t.Errorf("preferences = %+v, want none muted", got)
+	}
+}
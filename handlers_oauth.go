@@ -0,0 +1,680 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/auth"
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/store"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handleCreateOAuthClient(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	params, err := extractParams(createOAuthClientParameters{}, req)
+	if err != nil {
+		sendParamsError(w, err)
+		return
+	}
+
+	scopes, err := parseScopes(strings.Join(params.Scopes, " "), OAUTH_SCOPES)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	clientID, err := auth.MakeRefreshToken()
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	// Public clients such as mobile apps cannot keep a secret and rely on
+	// PKCE alone.
+	secret, secretHash := "", sql.NullString{}
+	if params.Confidential {
+		secret, err = auth.MakeRefreshToken()
+		if err != nil {
+			sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+			return
+		}
+		secretHash = sql.NullString{String: auth.HashToken(secret), Valid: true}
+	}
+
+	client, err := cfg.db.CreateOAuthClient(
+		req.Context(),
+		database.CreateOAuthClientParams{
+			ID:           OAUTH_CLIENT_ID_PREFIX + clientID[:32],
+			UserID:       userID,
+			Name:         params.Name,
+			SecretHash:   secretHash,
+			RedirectUris: params.RedirectURIs,
+			Scopes:       scopes,
+		},
+	)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	// The client secret is only ever returned here.
+	response := toOAuthClient(client)
+	response.Secret = secret
+
+	sendJSONResponse(w, http.StatusCreated, response)
+
+}
+
+func (cfg *apiConfig) handleGetOAuthClients(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	clients, err := cfg.db.GetOAuthClientsByUser(req.Context(), userID)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	data := []OAuthClient{}
+	for _, c := range clients {
+		data = append(data, toOAuthClient(c))
+	}
+
+	sendJSONResponse(w, http.StatusOK, data)
+
+}
+
+func (cfg *apiConfig) handleDeleteOAuthClient(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	client, err := cfg.db.GetOAuthClientByID(req.Context(), req.PathValue("clientID"))
+	if err != nil {
+		sendJSONResponse(w, http.StatusNotFound, jsonErr{Error: fmt.Sprintf("Client not found: %v", err)})
+		return
+	}
+
+	if client.UserID != userID {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if err := cfg.db.DeleteOAuthClientByID(req.Context(), client.ID); err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAuthorize starts the authorization code flow for the signed-in user.
+// It redirects straight back to the client when the user already consented
+// to the requested scopes and otherwise returns the consent prompt.
+func (cfg *apiConfig) handleAuthorize(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	query := req.URL.Query()
+	if query.Get("response_type") != "code" {
+		sendJSONResponse(w, http.StatusBadRequest, oauthErr{Error: "unsupported_response_type"})
+		return
+	}
+
+	params := authorizeParameters{
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		Scope:               query.Get("scope"),
+		State:               query.Get("state"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+	}
+
+	client, scopes, err := cfg.authorizationRequest(req.Context(), params)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, oauthErr{Error: "invalid_request", ErrorDescription: err.Error()})
+		return
+	}
+
+	consent, err := cfg.db.GetOAuthConsent(
+		req.Context(),
+		database.GetOAuthConsentParams{
+			UserID:   userID,
+			ClientID: client.ID,
+		},
+	)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	if err == nil && containsAll(consent.Scopes, scopes) {
+		redirectTo, err := cfg.grantAuthorizationCode(req.Context(), cfg.db, userID, client, params, scopes)
+		if err != nil {
+			sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+			return
+		}
+		http.Redirect(w, req, redirectTo, http.StatusFound)
+		return
+	}
+
+	sendJSONResponse(
+		w,
+		http.StatusOK,
+		consentPrompt{
+			Client:      toOAuthClient(client),
+			Scopes:      scopes,
+			RedirectURI: params.RedirectURI,
+			State:       params.State,
+		},
+	)
+
+}
+
+// handleApproveAuthorization records the user's answer to a consent prompt
+// and returns where the client should be sent next.
+func (cfg *apiConfig) handleApproveAuthorization(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	params, err := extractParams(authorizeParameters{}, req)
+	if err != nil {
+		sendParamsError(w, err)
+		return
+	}
+
+	client, scopes, err := cfg.authorizationRequest(req.Context(), params)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, oauthErr{Error: "invalid_request", ErrorDescription: err.Error()})
+		return
+	}
+
+	if !params.Approve {
+		sendJSONResponse(
+			w,
+			http.StatusOK,
+			authorizeResponse{
+				RedirectTo: redirectURL(params.RedirectURI, url.Values{"error": {"access_denied"}}, params.State),
+			},
+		)
+		return
+	}
+
+	var redirectTo string
+	err = cfg.db.InTx(req.Context(), func(q store.Queries) error {
+
+		if _, err := q.UpsertOAuthConsent(
+			req.Context(),
+			database.UpsertOAuthConsentParams{
+				UserID:   userID,
+				ClientID: client.ID,
+				Scopes:   scopes,
+			},
+		); err != nil {
+			return err
+		}
+
+		var err error
+		redirectTo, err = cfg.grantAuthorizationCode(req.Context(), q, userID, client, params, scopes)
+		return err
+	})
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, authorizeResponse{RedirectTo: redirectTo})
+
+}
+
+// handleOAuthToken exchanges authorization codes and refresh tokens issued to
+// a client for a new access token. Refresh tokens are rotated on every use.
+func (cfg *apiConfig) handleOAuthToken(w http.ResponseWriter, req *http.Request) {
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	client, err := cfg.oauthClient(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, oauthErr{Error: "invalid_client", ErrorDescription: err.Error()})
+		return
+	}
+
+	// rejected grants still commit, so a code presented with the wrong
+	// verifier cannot be tried again.
+	var response oauthTokenResponse
+	var rejected *oauthErr
+	err = cfg.db.InTx(req.Context(), func(q store.Queries) error {
+
+		rejected = nil
+		var userID uuid.UUID
+		var scopes []string
+
+		switch req.PostFormValue("grant_type") {
+		case "authorization_code":
+			code, err := q.ConsumeAuthorizationCode(req.Context(), auth.HashToken(req.PostFormValue("code")))
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			if err != nil ||
+				code.ClientID != client.ID ||
+				code.RedirectUri != req.PostFormValue("redirect_uri") {
+				rejected = &oauthErr{Error: "invalid_grant"}
+				return nil
+			}
+			if !auth.VerifyPKCE(req.PostFormValue("code_verifier"), code.CodeChallenge) {
+				rejected = &oauthErr{Error: "invalid_grant", ErrorDescription: "code_verifier does not match"}
+				return nil
+			}
+			userID, scopes = code.UserID, code.Scopes
+		case "refresh_token":
+			token, err := q.GetRefreshToken(req.Context(), req.PostFormValue("refresh_token"))
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			if err != nil ||
+				token.ClientID.String != client.ID ||
+				token.RevokedAt.Valid ||
+				time.Now().After(token.ExpiresAt) {
+				rejected = &oauthErr{Error: "invalid_grant"}
+				return nil
+			}
+			scopes = token.Scopes
+			if requested := req.PostFormValue("scope"); requested != "" {
+				scopes, err = parseScopes(requested, token.Scopes)
+				if err != nil {
+					rejected = &oauthErr{Error: "invalid_scope", ErrorDescription: err.Error()}
+					return nil
+				}
+			}
+			if err := q.RevokeRefreshToken(
+				req.Context(),
+				database.RevokeRefreshTokenParams{
+					RevokedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+					Token:     token.Token,
+				},
+			); err != nil {
+				return err
+			}
+			userID = token.UserID
+		default:
+			rejected = &oauthErr{Error: "unsupported_grant_type"}
+			return nil
+		}
+
+		var err error
+		response, err = cfg.issueOAuthTokens(req.Context(), q, userID, client.ID, scopes)
+		return err
+	})
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+	if rejected != nil {
+		sendJSONResponse(w, http.StatusBadRequest, *rejected)
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, response)
+
+}
+
+// handleOAuthIntrospect implements RFC 7662. Clients may only introspect
+// their own tokens; anything else is reported as inactive.
+func (cfg *apiConfig) handleOAuthIntrospect(w http.ResponseWriter, req *http.Request) {
+
+	client, err := cfg.oauthClient(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, oauthErr{Error: "invalid_client", ErrorDescription: err.Error()})
+		return
+	}
+
+	token := req.PostFormValue("token")
+
+	if claims, err := cfg.parseAccessToken(req.Context(), token); err == nil && claims.ClientID == client.ID {
+		sendJSONResponse(
+			w,
+			http.StatusOK,
+			introspectionResponse{
+				Active:    true,
+				Scope:     strings.Join(claims.Scopes, " "),
+				ClientID:  claims.ClientID,
+				Sub:       claims.UserID.String(),
+				TokenType: "access_token",
+				Exp:       claims.ExpiresAt.Unix(),
+			},
+		)
+		return
+	}
+
+	refreshToken, err := cfg.db.GetRefreshToken(req.Context(), token)
+	if err == nil &&
+		refreshToken.ClientID.String == client.ID &&
+		!refreshToken.RevokedAt.Valid &&
+		time.Now().Before(refreshToken.ExpiresAt) {
+		sendJSONResponse(
+			w,
+			http.StatusOK,
+			introspectionResponse{
+				Active:    true,
+				Scope:     strings.Join(refreshToken.Scopes, " "),
+				ClientID:  client.ID,
+				Sub:       refreshToken.UserID.String(),
+				TokenType: "refresh_token",
+				Exp:       refreshToken.ExpiresAt.Unix(),
+			},
+		)
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, introspectionResponse{Active: false})
+
+}
+
+// handleOAuthRevoke implements RFC 7009: unknown tokens are not an error.
+func (cfg *apiConfig) handleOAuthRevoke(w http.ResponseWriter, req *http.Request) {
+
+	client, err := cfg.oauthClient(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, oauthErr{Error: "invalid_client", ErrorDescription: err.Error()})
+		return
+	}
+
+	token := req.PostFormValue("token")
+
+	if claims, err := auth.ParseJWT(token, cfg.serverSecret); err == nil {
+		if claims.ClientID == client.ID {
+			if err := cfg.db.RevokeOAuthAccessToken(req.Context(), claims.TokenID); err != nil {
+				sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	refreshToken, err := cfg.db.GetRefreshToken(req.Context(), token)
+	if err == nil && refreshToken.ClientID.String == client.ID && !refreshToken.RevokedAt.Valid {
+		if err := cfg.db.RevokeRefreshToken(
+			req.Context(),
+			database.RevokeRefreshTokenParams{
+				RevokedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+				Token:     refreshToken.Token,
+			},
+		); err != nil {
+			sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (cfg *apiConfig) handleGetOAuthConsents(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	consents, err := cfg.db.GetOAuthConsentsByUser(req.Context(), userID)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	data := []OAuthConsent{}
+	for _, c := range consents {
+		data = append(data, toOAuthConsent(c))
+	}
+
+	sendJSONResponse(w, http.StatusOK, data)
+
+}
+
+// handleDeleteOAuthConsent withdraws the user's consent and revokes every
+// token already issued to that client on their behalf.
+func (cfg *apiConfig) handleDeleteOAuthConsent(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	clientID := req.PathValue("clientID")
+
+	err = cfg.db.InTx(req.Context(), func(q store.Queries) error {
+
+		if err := q.DeleteOAuthConsent(
+			req.Context(),
+			database.DeleteOAuthConsentParams{
+				UserID:   userID,
+				ClientID: clientID,
+			},
+		); err != nil {
+			return err
+		}
+
+		if err := q.RevokeOAuthAccessTokensForClient(
+			req.Context(),
+			database.RevokeOAuthAccessTokensForClientParams{
+				UserID:   userID,
+				ClientID: clientID,
+			},
+		); err != nil {
+			return err
+		}
+
+		return q.RevokeRefreshTokensForClient(
+			req.Context(),
+			database.RevokeRefreshTokensForClientParams{
+				UserID:   userID,
+				ClientID: sql.NullString{String: clientID, Valid: true},
+			},
+		)
+	})
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorizationRequest checks an authorization request against the client
+// registration and returns the scopes being asked for.
+func (cfg *apiConfig) authorizationRequest(ctx context.Context, params authorizeParameters) (database.OauthClient, []string, error) {
+
+	client, err := cfg.db.GetOAuthClientByID(ctx, params.ClientID)
+	if err != nil {
+		return database.OauthClient{}, nil, errors.New("unknown client_id")
+	}
+
+	if !slices.Contains(client.RedirectUris, params.RedirectURI) {
+		return database.OauthClient{}, nil, errors.New("redirect_uri is not registered for this client")
+	}
+
+	if params.CodeChallenge == "" || params.CodeChallengeMethod != "S256" {
+		return database.OauthClient{}, nil, errors.New("a S256 code_challenge is required")
+	}
+
+	scopes, err := parseScopes(params.Scope, client.Scopes)
+	if err != nil {
+		return database.OauthClient{}, nil, err
+	}
+
+	return client, scopes, nil
+}
+
+func (cfg *apiConfig) grantAuthorizationCode(ctx context.Context, q store.Queries, userID uuid.UUID, client database.OauthClient, params authorizeParameters, scopes []string) (string, error) {
+
+	code, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := q.CreateAuthorizationCode(
+		ctx,
+		database.CreateAuthorizationCodeParams{
+			CodeHash:      auth.HashToken(code),
+			ClientID:      client.ID,
+			UserID:        userID,
+			RedirectUri:   params.RedirectURI,
+			Scopes:        scopes,
+			CodeChallenge: params.CodeChallenge,
+			ExpiresAt:     time.Now().UTC().Add(OAUTH_CODE_TTL),
+		},
+	); err != nil {
+		return "", err
+	}
+
+	return redirectURL(params.RedirectURI, url.Values{"code": {code}}, params.State), nil
+}
+
+func (cfg *apiConfig) issueOAuthTokens(ctx context.Context, q store.Queries, userID uuid.UUID, clientID string, scopes []string) (oauthTokenResponse, error) {
+
+	accessToken, tokenID, err := auth.MakeScopedJWT(userID, cfg.serverSecret, OAUTH_ACCESS_TOKEN_TTL, clientID, scopes)
+	if err != nil {
+		return oauthTokenResponse{}, err
+	}
+
+	if err := q.CreateOAuthAccessToken(
+		ctx,
+		database.CreateOAuthAccessTokenParams{
+			ID:        tokenID,
+			ClientID:  clientID,
+			UserID:    userID,
+			Scopes:    scopes,
+			ExpiresAt: time.Now().UTC().Add(OAUTH_ACCESS_TOKEN_TTL),
+		},
+	); err != nil {
+		return oauthTokenResponse{}, err
+	}
+
+	refreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		return oauthTokenResponse{}, err
+	}
+
+	if _, err := q.CreateOAuthRefreshToken(
+		ctx,
+		database.CreateOAuthRefreshTokenParams{
+			Token:    refreshToken,
+			UserID:   userID,
+			ClientID: sql.NullString{String: clientID, Valid: true},
+			Scopes:   scopes,
+		},
+	); err != nil {
+		return oauthTokenResponse{}, err
+	}
+
+	return oauthTokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(OAUTH_ACCESS_TOKEN_TTL.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        strings.Join(scopes, " "),
+	}, nil
+}
+
+// oauthClient authenticates the calling client from HTTP Basic credentials or
+// the client_id/client_secret form fields. Public clients only send an id.
+func (cfg *apiConfig) oauthClient(req *http.Request) (database.OauthClient, error) {
+
+	clientID, secret, ok := req.BasicAuth()
+	if !ok {
+		clientID, secret = req.PostFormValue("client_id"), req.PostFormValue("client_secret")
+	}
+
+	client, err := cfg.db.GetOAuthClientByID(req.Context(), clientID)
+	if err != nil {
+		return database.OauthClient{}, errors.New("unknown client")
+	}
+
+	if client.SecretHash.Valid &&
+		subtle.ConstantTimeCompare([]byte(auth.HashToken(secret)), []byte(client.SecretHash.String)) != 1 {
+		return database.OauthClient{}, errors.New("invalid client credentials")
+	}
+
+	return client, nil
+}
+
+// parseScopes splits a space separated scope string, defaulting to read, and
+// rejects anything outside allowed.
+func parseScopes(scope string, allowed []string) ([]string, error) {
+
+	scopes := []string{}
+	for _, s := range strings.Fields(scope) {
+		if !slices.Contains(allowed, s) {
+			return nil, fmt.Errorf("scope %q is not allowed", s)
+		}
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+
+	if len(scopes) == 0 {
+		if !slices.Contains(allowed, SCOPE_READ) {
+			return nil, errors.New("scope is required")
+		}
+		scopes = append(scopes, SCOPE_READ)
+	}
+
+	return scopes, nil
+}
+
+func containsAll(granted, requested []string) bool {
+	for _, s := range requested {
+		if !slices.Contains(granted, s) {
+			return false
+		}
+	}
+	return true
+}
+
+func redirectURL(redirectURI string, values url.Values, state string) string {
+
+	if state != "" {
+		values.Set("state", state)
+	}
+
+	target, _ := url.Parse(redirectURI)
+	query := target.Query()
+	for key, v := range values {
+		query[key] = v
+	}
+	target.RawQuery = query.Encode()
+
+	return target.String()
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const testRedirectURI = "https://client.example.com/callback"
+
+// postForm sends form values with the client credentials in HTTP Basic auth.
+func (s *testServer) postForm(target string, client OAuthClient, values url.Values) *http.Request {
+	req := s.request("POST", target, values.Encode())
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(client.ID, client.Secret)
+	return req
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestOAuthClients(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.createUser("alice@example.com")
+	_, otherToken := s.createUser("bob@example.com")
+
+	tests := []struct {
+		name   string
+		params createOAuthClientParameters
+		want   int
+	}{
+		{name: "confidential", params: createOAuthClientParameters{Name: "App", RedirectURIs: []string{testRedirectURI}, Scopes: []string{SCOPE_READ}, Confidential: true}, want: http.StatusCreated},
+		{name: "no name", params: createOAuthClientParameters{RedirectURIs: []string{testRedirectURI}}, want: http.StatusBadRequest},
+		{name: "no redirect uri", params: createOAuthClientParameters{Name: "App"}, want: http.StatusBadRequest},
+		{name: "redirect uri with fragment", params: createOAuthClientParameters{Name: "App", RedirectURIs: []string{testRedirectURI + "#x"}}, want: http.StatusBadRequest},
+		{name: "unknown scope", params: createOAuthClientParameters{Name: "App", RedirectURIs: []string{testRedirectURI}, Scopes: []string{"everything"}}, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do("POST", "/api/oauth/clients", token, tt.params)
+			expectStatus(t, rec, tt.want)
+			if tt.want != http.StatusCreated {
+				return
+			}
+			client := decode[OAuthClient](t, rec)
+			if !strings.HasPrefix(client.ID, OAUTH_CLIENT_ID_PREFIX) || client.Secret == "" || !client.Confidential {
+				t.Errorf("client = %+v, want a confidential client with a secret", client)
+			}
+		})
+	}
+	expectStatus(t, s.do("POST", "/api/oauth/clients", "", tests[0].params), http.StatusUnauthorized)
+
+	rec := s.do("GET", "/api/oauth/clients", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	clients := decode[[]OAuthClient](t, rec)
+	if len(clients) != 1 || clients[0].Secret != "" {
+		t.Fatalf("clients = %+v, want one without its secret", clients)
+	}
+	expectStatus(t, s.do("GET", "/api/oauth/clients", "", nil), http.StatusUnauthorized)
+
+	target := "/api/oauth/clients/" + clients[0].ID
+	expectStatus(t, s.do("DELETE", "/api/oauth/clients/unknown", token, nil), http.StatusNotFound)
+	expectStatus(t, s.do("DELETE", target, otherToken, nil), http.StatusForbidden)
+	expectStatus(t, s.do("DELETE", target, token, nil), http.StatusNoContent)
+	expectStatus(t, s.do("DELETE", target, token, nil), http.StatusNotFound)
+}
+
+func TestOAuthAuthorizationCodeFlow(t *testing.T) {
+	s := newTestServer(t)
+	user, token := s.createUser("alice@example.com")
+
+	rec := s.do("POST", "/api/oauth/clients", token, createOAuthClientParameters{
+		Name:         "App",
+		RedirectURIs: []string{testRedirectURI},
+		Scopes:       []string{SCOPE_READ, SCOPE_WRITE},
+		Confidential: true,
+	})
+	expectStatus(t, rec, http.StatusCreated)
+	client := decode[OAuthClient](t, rec)
+
+	verifier := "a-code-verifier-that-is-long-enough-to-be-realistic"
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {client.ID},
+		"redirect_uri":          {testRedirectURI},
+		"scope":                 {"read write"},
+		"state":                 {"xyz"},
+		"code_challenge":        {pkceChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	authorize := "/api/oauth/authorize?" + query.Encode()
+
+	expectStatus(t, s.do("GET", authorize, "", nil), http.StatusUnauthorized)
+	expectStatus(t, s.do("GET", strings.Replace(authorize, "response_type=code", "response_type=token", 1), token, nil), http.StatusBadRequest)
+	expectStatus(t, s.do("GET", strings.Replace(authorize, "scope=read+write", "scope=admin", 1), token, nil), http.StatusBadRequest)
+
+	rec = s.do("GET", authorize, token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if prompt := decode[consentPrompt](t, rec); prompt.Client.ID != client.ID || len(prompt.Scopes) != 2 {
+		t.Fatalf("consent prompt = %+v, want read and write for %s", prompt, client.ID)
+	}
+
+	approval := authorizeParameters{
+		ClientID:            client.ID,
+		RedirectURI:         testRedirectURI,
+		Scope:               "read write",
+		State:               "xyz",
+		CodeChallenge:       pkceChallenge(verifier),
+		CodeChallengeMethod: "S256",
+	}
+	rec = s.do("POST", "/api/oauth/authorize", token, approval)
+	expectStatus(t, rec, http.StatusOK)
+	if denied, _ := url.Parse(decode[authorizeResponse](t, rec).RedirectTo); denied.Query().Get("error") != "access_denied" {
+		t.Fatalf("declined redirect = %s, want access_denied", denied)
+	}
+
+	approval.Approve = true
+	rec = s.do("POST", "/api/oauth/authorize", token, approval)
+	expectStatus(t, rec, http.StatusOK)
+	redirect, _ := url.Parse(decode[authorizeResponse](t, rec).RedirectTo)
+	if redirect.Query().Get("state") != "xyz" {
+		t.Errorf("redirect = %s, want state echoed", redirect)
+	}
+	code := redirect.Query().Get("code")
+
+	exchange := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {testRedirectURI},
+		"code_verifier": {"wrong"},
+	}
+	expectStatus(t, s.serve(s.postForm("/api/oauth/token", client, exchange)), http.StatusBadRequest)
+
+	// A failed exchange still burns the code; get a new one. Consent was
+	// recorded, so the user is redirected without a prompt.
+	rec = s.do("GET", authorize, token, nil)
+	expectStatus(t, rec, http.StatusFound)
+	redirect, _ = url.Parse(rec.Header().Get("Location"))
+	exchange.Set("code", redirect.Query().Get("code"))
+	exchange.Set("code_verifier", verifier)
+
+	wrongSecret := client
+	wrongSecret.Secret = "nope"
+	expectStatus(t, s.serve(s.postForm("/api/oauth/token", wrongSecret, exchange)), http.StatusUnauthorized)
+
+	rec = s.serve(s.postForm("/api/oauth/token", client, exchange))
+	expectStatus(t, rec, http.StatusOK)
+	tokens := decode[oauthTokenResponse](t, rec)
+	if tokens.Scope != "read write" || tokens.TokenType != "Bearer" {
+		t.Errorf("token response = %+v, want read write bearer tokens", tokens)
+	}
+	expectStatus(t, s.serve(s.postForm("/api/oauth/token", client, exchange)), http.StatusBadRequest)
+	expectStatus(t, s.do("POST", "/api/chirps", tokens.AccessToken, createChirpParameters{Body: "via app"}), http.StatusCreated)
+
+	rec = s.serve(s.postForm("/api/oauth/introspect", client, url.Values{"token": {tokens.AccessToken}}))
+	expectStatus(t, rec, http.StatusOK)
+	if got := decode[introspectionResponse](t, rec); !got.Active || got.Sub != user.ID.String() || got.TokenType != "access_token" {
+		t.Errorf("introspection = %+v, want an active access token for %s", got, user.ID)
+	}
+
+	rec = s.serve(s.postForm("/api/oauth/token", client, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {tokens.RefreshToken},
+		"scope":         {"read"},
+	}))
+	expectStatus(t, rec, http.StatusOK)
+	refreshed := decode[oauthTokenResponse](t, rec)
+	if refreshed.Scope != SCOPE_READ {
+		t.Errorf("refreshed scope = %q, want narrowed to read", refreshed.Scope)
+	}
+	rec = s.serve(s.postForm("/api/oauth/introspect", client, url.Values{"token": {tokens.RefreshToken}}))
+	if got := decode[introspectionResponse](t, rec); got.Active {
+		t.Errorf("rotated refresh token introspection = %+v, want inactive", got)
+	}
+	expectStatus(t, s.serve(s.postForm("/api/oauth/token", client, url.Values{"grant_type": {"password"}})), http.StatusBadRequest)
+
+	// Client tokens cannot be refreshed as first-party sessions.
+	expectStatus(t, s.do("POST", "/api/refresh", refreshed.RefreshToken, nil), http.StatusUnauthorized)
+
+	expectStatus(t, s.serve(s.postForm("/api/oauth/revoke", client, url.Values{"token": {refreshed.AccessToken}})), http.StatusOK)
+	expectStatus(t, s.do("GET", "/api/drafts", refreshed.AccessToken, nil), http.StatusUnauthorized)
+	expectStatus(t, s.serve(s.postForm("/api/oauth/revoke", client, url.Values{"token": {"unknown"}})), http.StatusOK)
+}
+
+func TestOAuthConsents(t *testing.T) {
+	s := newTestServer(t)
+	user, token := s.createUser("alice@example.com")
+	accessToken, _ := s.oauthToken(user.ID, SCOPE_READ)
+
+	clients := decode[[]OAuthClient](t, s.do("GET", "/api/oauth/clients", token, nil))
+	if len(clients) != 1 {
+		t.Fatalf("clients = %+v, want 1", clients)
+	}
+	verifier := "another-code-verifier-that-is-long-enough-too"
+	rec := s.do("POST", "/api/oauth/authorize", token, authorizeParameters{
+		ClientID:            clients[0].ID,
+		RedirectURI:         testRedirectURI,
+		CodeChallenge:       pkceChallenge(verifier),
+		CodeChallengeMethod: "S256",
+		Approve:             true,
+	})
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = s.do("GET", "/api/oauth/consents", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	consents := decode[[]OAuthConsent](t, rec)
+	if len(consents) != 1 || consents[0].ClientID != clients[0].ID {
+		t.Fatalf("consents = %+v, want one for %s", consents, clients[0].ID)
+	}
+	expectStatus(t, s.do("GET", "/api/oauth/consents", "", nil), http.StatusUnauthorized)
+
+	expectStatus(t, s.do("DELETE", "/api/oauth/consents/"+clients[0].ID, token, nil), http.StatusNoContent)
+	expectStatus(t, s.do("GET", "/api/drafts", accessToken, nil), http.StatusUnauthorized)
+	if got := decode[[]OAuthConsent](t, s.do("GET", "/api/oauth/consents", token, nil)); len(got) != 0 {
+		t.Errorf("consents after withdrawal = %+v, want none", got)
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/auth"
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/logging"
+	"github.com/ghis9917/chirpy/internal/oidc"
+	"github.com/ghis9917/chirpy/internal/store"
+	"github.com/google/uuid"
+)
+
+var (
+	errUnverifiedEmail = errors.New("Identity provider did not return a verified email")
+	errEmailTaken      = errors.New("An account with this email already exists; sign in and link this provider instead")
+	errLastIdentity    = errors.New("Set a password before unlinking your only identity")
+)
+
+func (cfg *apiConfig) handleOIDCLogin(w http.ResponseWriter, req *http.Request) {
+
+	provider, ok := cfg.oidcProviders[req.PathValue("provider")]
+	if !ok {
+		sendJSONResponse(w, http.StatusNotFound, jsonErr{Error: "Unknown identity provider"})
+		return
+	}
+
+	redirectTo, err := cfg.startOIDC(req.Context(), provider, uuid.NullUUID{})
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	http.Redirect(w, req, redirectTo, http.StatusFound)
+}
+
+// handleLinkIdentity starts the same flow as handleOIDCLogin for a signed-in
+// user; the callback then links the identity instead of signing in.
+func (cfg *apiConfig) handleLinkIdentity(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	provider, ok := cfg.oidcProviders[req.PathValue("provider")]
+	if !ok {
+		sendJSONResponse(w, http.StatusNotFound, jsonErr{Error: "Unknown identity provider"})
+		return
+	}
+
+	redirectTo, err := cfg.startOIDC(req.Context(), provider, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, authorizeResponse{RedirectTo: redirectTo})
+
+}
+
+func (cfg *apiConfig) handleOIDCCallback(w http.ResponseWriter, req *http.Request) {
+
+	provider, ok := cfg.oidcProviders[req.PathValue("provider")]
+	if !ok {
+		sendJSONResponse(w, http.StatusNotFound, jsonErr{Error: "Unknown identity provider"})
+		return
+	}
+
+	query := req.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("Sign-in failed: %s", providerErr)})
+		return
+	}
+
+	state, err := cfg.db.ConsumeOIDCLoginState(req.Context(), auth.HashToken(query.Get("state")))
+	if err != nil || state.Provider != provider.Name {
+		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: "Invalid or expired state"})
+		return
+	}
+
+	identity, err := provider.Exchange(req.Context(), query.Get("code"), state.CodeVerifier, state.Nonce)
+	if err != nil {
+		if !state.UserID.Valid {
+			cfg.metrics.Login(LOGIN_METHOD_OIDC, false)
+		}
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	existing, err := cfg.db.GetIdentityBySubject(
+		req.Context(),
+		database.GetIdentityBySubjectParams{
+			Provider: provider.Name,
+			Subject:  identity.Subject,
+		},
+	)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+	found := err == nil
+
+	if state.UserID.Valid {
+		if found {
+			if existing.UserID != state.UserID.UUID {
+				sendJSONResponse(w, http.StatusConflict, jsonErr{Error: "Identity is already linked to another account"})
+				return
+			}
+			sendJSONResponse(w, http.StatusOK, toIdentity(existing))
+			return
+		}
+
+		linked, err := cfg.db.CreateIdentity(
+			req.Context(),
+			database.CreateIdentityParams{
+				UserID:   state.UserID.UUID,
+				Provider: provider.Name,
+				Subject:  identity.Subject,
+				Email:    identity.Email,
+			},
+		)
+		if err != nil {
+			sendJSONResponse(w, http.StatusConflict, jsonErr{Error: fmt.Sprintf("Could not link identity: %v", err)})
+			return
+		}
+
+		sendJSONResponse(w, http.StatusCreated, toIdentity(linked))
+		return
+	}
+
+	var response loginUserResponse
+	err = cfg.db.InTx(req.Context(), func(q store.Queries) error {
+
+		var user database.User
+		if found {
+			user, err = q.GetUserByID(req.Context(), existing.UserID)
+		} else {
+			user, err = cfg.signUpWithIdentity(req.Context(), q, provider, identity)
+		}
+		if err != nil {
+			return err
+		}
+
+		response, err = cfg.startSession(req.Context(), q, user)
+		return err
+	})
+	switch {
+	case errors.Is(err, errUnverifiedEmail):
+		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	case errors.Is(err, errEmailTaken):
+		sendJSONResponse(w, http.StatusConflict, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	case err != nil:
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+	cfg.metrics.Login(LOGIN_METHOD_OIDC, true)
+
+	sendJSONResponse(w, http.StatusOK, response)
+
+}
+
+func (cfg *apiConfig) handleGetIdentities(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	identities, err := cfg.db.GetIdentitiesByUser(req.Context(), userID)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	data := []Identity{}
+	for _, i := range identities {
+		data = append(data, toIdentity(i))
+	}
+
+	sendJSONResponse(w, http.StatusOK, data)
+
+}
+
+// handleUnlinkIdentity refuses to remove the last way to sign in to an
+// account that has never set a password.
+func (cfg *apiConfig) handleUnlinkIdentity(w http.ResponseWriter, req *http.Request) {
+
+	identityUUID, err := uuid.Parse(req.PathValue("identityID"))
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: fmt.Sprintf("Invalid IdentityID: %v", err)})
+		return
+	}
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	identity, err := cfg.db.GetIdentityByID(req.Context(), identityUUID)
+	if err != nil {
+		sendJSONResponse(w, http.StatusNotFound, jsonErr{Error: fmt.Sprintf("Identity not found: %v", err)})
+		return
+	}
+
+	if identity.UserID != userID {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	// Checking the count and deleting in one transaction keeps two
+	// concurrent unlinks from leaving the account without a way to sign in.
+	err = cfg.db.InTx(req.Context(), func(q store.Queries) error {
+
+		user, err := q.GetUserByID(req.Context(), userID)
+		if err != nil {
+			return err
+		}
+
+		if user.HashedPassword == PASSWORD_UNSET {
+			count, err := q.CountIdentitiesByUser(req.Context(), userID)
+			if err != nil {
+				return err
+			}
+			if count <= 1 {
+				return errLastIdentity
+			}
+		}
+
+		return q.DeleteIdentityByID(req.Context(), identity.ID)
+	})
+	if errors.Is(err, errLastIdentity) {
+		sendJSONResponse(w, http.StatusConflict, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// startOIDC stores a single-use state with its nonce and PKCE verifier and
+// returns the provider URL to send the user to. linkTo is set when a
+// signed-in user is linking an identity.
+func (cfg *apiConfig) startOIDC(ctx context.Context, provider *oidc.Provider, linkTo uuid.NullUUID) (string, error) {
+
+	state, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	verifier := oidc.NewVerifier()
+
+	if err := cfg.db.DeleteExpiredOIDCLoginStates(ctx); err != nil {
+		logging.FromContext(ctx).Error("Error pruning sign-in states", "err", err)
+	}
+
+	if err := cfg.db.CreateOIDCLoginState(
+		ctx,
+		database.CreateOIDCLoginStateParams{
+			StateHash:    auth.HashToken(state),
+			Provider:     provider.Name,
+			Nonce:        nonce,
+			CodeVerifier: verifier,
+			UserID:       linkTo,
+			ExpiresAt:    time.Now().UTC().Add(OIDC_STATE_TTL),
+		},
+	); err != nil {
+		return "", err
+	}
+
+	return provider.AuthCodeURL(state, nonce, verifier), nil
+}
+
+// signUpWithIdentity creates an account for a first-time sign-in. Existing
+// accounts are never linked by email alone; their owner has to link the
+// identity while signed in.
+func (cfg *apiConfig) signUpWithIdentity(ctx context.Context, q store.Queries, provider *oidc.Provider, identity oidc.Identity) (database.User, error) {
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return database.User{}, errUnverifiedEmail
+	}
+
+	if _, err := q.GetUserByEmail(ctx, identity.Email); err == nil {
+		return database.User{}, errEmailTaken
+	}
+
+	user, err := q.CreateUser(
+		ctx,
+		database.CreateUserParams{
+			Email:          identity.Email,
+			HashedPassword: PASSWORD_UNSET,
+		},
+	)
+	if err != nil {
+		return database.User{}, err
+	}
+
+	if _, err := q.CreateIdentity(
+		ctx,
+		database.CreateIdentityParams{
+			UserID:   user.ID,
+			Provider: provider.Name,
+			Subject:  identity.Subject,
+			Email:    identity.Email,
+		},
+	); err != nil {
+		return database.User{}, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ghis9917/chirpy/internal/oidc"
+	"github.com/ghis9917/chirpy/internal/oidc/oidctest"
+	"github.com/google/uuid"
+)
+
+// addOIDCProvider registers a mock identity provider under the name "mock".
+func (s *testServer) addOIDCProvider() *oidctest.Provider {
+	s.t.Helper()
+
+	mock := oidctest.NewProvider(s.t)
+	provider, err := oidc.NewProvider(context.Background(), oidc.Config{
+		Name:        "mock",
+		Issuer:      mock.URL,
+		ClientID:    "chirpy",
+		RedirectURL: "http://localhost:8080/api/auth/mock/callback",
+	})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	s.cfg.oidcProviders[provider.Name] = provider
+
+	return mock
+}
+
+// oidcCallback completes a sign-in started at redirectTo the way the
+// browser would.
+func (s *testServer) oidcCallback(mock *oidctest.Provider, redirectTo string) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	state := mock.Authorize(s.t, redirectTo)
+	query := url.Values{"state": {state}, "code": {oidctest.Code}}
+	return s.do("GET", "/api/auth/mock/callback?"+query.Encode(), "", nil)
+}
+
+func TestOIDCLogin(t *testing.T) {
+	s := newTestServer(t)
+	mock := s.addOIDCProvider()
+
+	expectStatus(t, s.do("GET", "/api/auth/unknown/login", "", nil), http.StatusNotFound)
+	expectStatus(t, s.do("GET", "/api/auth/unknown/callback", "", nil), http.StatusNotFound)
+	expectStatus(t, s.do("GET", "/api/auth/mock/callback?error=access_denied", "", nil), http.StatusUnauthorized)
+	expectStatus(t, s.do("GET", "/api/auth/mock/callback?state=forged&code="+oidctest.Code, "", nil), http.StatusBadRequest)
+
+	login := func() *httptest.ResponseRecorder {
+		rec := s.do("GET", "/api/auth/mock/login", "", nil)
+		expectStatus(t, rec, http.StatusFound)
+		return s.oidcCallback(mock, rec.Header().Get("Location"))
+	}
+
+	// The first sign-in creates the account, later ones find it again.
+	rec := login()
+	expectStatus(t, rec, http.StatusOK)
+	session := decode[loginUserResponse](t, rec)
+	if session.Email != "alice@example.com" || session.Token == "" {
+		t.Fatalf("session = %+v, want a session for alice@example.com", session)
+	}
+	rec = login()
+	expectStatus(t, rec, http.StatusOK)
+	if got := decode[loginUserResponse](t, rec); got.ID != session.ID {
+		t.Errorf("second sign-in user = %s, want %s", got.ID, session.ID)
+	}
+
+	// An account without a password cannot sign in with one.
+	expectStatus(t, s.do("POST", "/api/login", "", loginUserParameters{Email: "alice@example.com", Password: PASSWORD_UNSET}), http.StatusUnauthorized)
+
+	mock.SetIdentity("user-456", "bob@example.com", false)
+	expectStatus(t, login(), http.StatusBadRequest)
+
+	// Email alone never links to an existing account.
+	s.createUser("carol@example.com")
+	mock.SetIdentity("user-789", "carol@example.com", true)
+	expectStatus(t, login(), http.StatusConflict)
+
+	mock.SetAudience("someone-else")
+	expectStatus(t, login(), http.StatusUnauthorized)
+}
+
+func TestOIDCLinkIdentity(t *testing.T) {
+	s := newTestServer(t)
+	mock := s.addOIDCProvider()
+	user, token := s.createUser("alice@example.com")
+	_, otherToken := s.createUser("bob@example.com")
+
+	link := func(token string) *httptest.ResponseRecorder {
+		rec := s.do("POST", "/api/auth/mock/link", token, nil)
+		expectStatus(t, rec, http.StatusOK)
+		return s.oidcCallback(mock, decode[authorizeResponse](t, rec).RedirectTo)
+	}
+
+	expectStatus(t, s.do("POST", "/api/auth/mock/link", "", nil), http.StatusUnauthorized)
+	expectStatus(t, s.do("POST", "/api/auth/unknown/link", token, nil), http.StatusNotFound)
+
+	rec := link(token)
+	expectStatus(t, rec, http.StatusCreated)
+	identity := decode[Identity](t, rec)
+	expectStatus(t, link(token), http.StatusOK)
+	expectStatus(t, link(otherToken), http.StatusConflict)
+
+	// Signing in with the linked identity reaches the existing account.
+	rec = s.do("GET", "/api/auth/mock/login", "", nil)
+	rec = s.oidcCallback(mock, rec.Header().Get("Location"))
+	expectStatus(t, rec, http.StatusOK)
+	if got := decode[loginUserResponse](t, rec); got.ID != user.ID {
+		t.Errorf("signed in as %s, want %s", got.ID, user.ID)
+	}
+
+	rec = s.do("GET", "/api/identities", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if got := decode[[]Identity](t, rec); len(got) != 1 || got[0].ID != identity.ID {
+		t.Errorf("identities = %+v, want [%s]", got, identity.ID)
+	}
+	expectStatus(t, s.do("GET", "/api/identities", "", nil), http.StatusUnauthorized)
+
+	target := "/api/identities/" + identity.ID.String()
+	expectStatus(t, s.do("DELETE", "/api/identities/not-a-uuid", token, nil), http.StatusBadRequest)
+	expectStatus(t, s.do("DELETE", "/api/identities/"+uuid.NewString(), token, nil), http.StatusNotFound)
+	expectStatus(t, s.do("DELETE", target, otherToken, nil), http.StatusForbidden)
+	expectStatus(t, s.do("DELETE", target, token, nil), http.StatusNoContent)
+}
+
+func TestOIDCUnlinkLastIdentity(t *testing.T) {
+	s := newTestServer(t)
+	mock := s.addOIDCProvider()
+
+	rec := s.do("GET", "/api/auth/mock/login", "", nil)
+	rec = s.oidcCallback(mock, rec.Header().Get("Location"))
+	expectStatus(t, rec, http.StatusOK)
+	token := decode[loginUserResponse](t, rec).Token
+
+	identities := decode[[]Identity](t, s.do("GET", "/api/identities", token, nil))
+	if len(identities) != 1 {
+		t.Fatalf("identities = %+v, want 1", identities)
+	}
+	target := "/api/identities/" + identities[0].ID.String()
+	expectStatus(t, s.do("DELETE", target, token, nil), http.StatusConflict)
+
+	expectStatus(t, s.do("PUT", "/api/users", token, updateUserParameters{Email: "alice@example.com", Password: testPassword}), http.StatusOK)
+	expectStatus(t, s.do("DELETE", target, token, nil), http.StatusNoContent)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/stream"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handleStream(w http.ResponseWriter, req *http.Request) {
+
+	authors := map[uuid.UUID]bool{}
+	for _, value := range req.URL.Query()["author_id"] {
+		for _, id := range strings.Split(value, ",") {
+			authorUUID, err := uuid.Parse(strings.TrimSpace(id))
+			if err != nil {
+				sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: fmt.Sprintf("Invalid UserID: %v", err)})
+				return
+			}
+			authors[authorUUID] = true
+		}
+	}
+
+	var lastEventID int64
+	if v := req.Header.Get("Last-Event-ID"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: "Invalid Last-Event-ID"})
+			return
+		}
+		lastEventID = parsed
+	}
+
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	// Subscribe before replaying so nothing published in between is missed;
+	// duplicates are filtered by event ID below. Everything up to
+	// Last-Event-ID counts as delivered, so reconnecting never repeats events;
+	// an event that commits that far out of order is not sent to this client.
+	sub := cfg.stream.Chirps.Subscribe(STREAM_BUFFER_SIZE)
+	defer cfg.stream.Chirps.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", CONTENT_TYPE_EVENT_STREAM)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", STREAM_RETRY.Milliseconds())
+
+	seen := stream.NewSeen(lastEventID)
+	send := func(event database.ChirpEvent) error {
+		if event.ID <= lastEventID || !seen.Add(event.ID) {
+			return nil
+		}
+		if len(authors) > 0 && !authors[event.UserID] {
+			return nil
+		}
+		data, err := json.Marshal(
+			Chirp{
+				ID:        event.ChirpID,
+				CreatedAt: event.ChirpCreatedAt,
+				UpdatedAt: event.ChirpUpdatedAt,
+				Body:      event.Body,
+				UserID:    event.UserID,
+			},
+		)
+		if err != nil {
+			return err
+		}
+		if _, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	if lastEventID > 0 {
+		missed, err := cfg.db.GetChirpEventsSince(
+			req.Context(),
+			database.GetChirpEventsSinceParams{
+				ID:    lastEventID,
+				Limit: STREAM_REPLAY_LIMIT,
+			},
+		)
+		if err != nil {
+			return
+		}
+		for _, event := range missed {
+			if err := send(event); err != nil {
+				return
+			}
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(STREAM_HEARTBEAT)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-cfg.closing:
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		case event, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind; the client reconnects with
+				// Last-Event-ID and catches up from chirp_events.
+				return
+			}
+			if err := send(event); err != nil {
+				return
+			}
+		}
+	}
+
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ghis9917/chirpy/internal/database"
+)
+
+// chirpEvents returns the event log; the memory store records it the way the
+// chirps trigger does, but nothing relays it, so tests publish by hand.
+func (s *testServer) chirpEvents() []database.ChirpEvent {
+	s.t.Helper()
+
+	events, err := s.db.GetChirpEventsSince(
+		context.Background(),
+		database.GetChirpEventsSinceParams{ID: 0, Limit: STREAM_REPLAY_LIMIT},
+	)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	return events
+}
+
+// readEvent returns the fields of the next server-sent event, skipping the
+// retry hint and heartbeats.
+func readEvent(t *testing.T, r *bufio.Reader) map[string]string {
+	t.Helper()
+
+	fields := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if fields["id"] != "" {
+				return fields
+			}
+			fields = map[string]string{}
+			continue
+		}
+		if name, value, ok := strings.Cut(line, ": "); ok {
+			fields[name] = value
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	s := newTestServer(t)
+	alice, _ := s.createUser("alice@example.com")
+	bob, _ := s.createUser("bob@example.com")
+	s.createChirp(alice.ID, "one")
+	s.createChirp(bob.ID, "two")
+	s.createChirp(alice.ID, "three")
+	events := s.chirpEvents()
+	if len(events) != 3 {
+		t.Fatalf("chirp events = %+v, want 3", events)
+	}
+
+	expectStatus(t, s.do("GET", "/api/stream?author_id=nope", "", nil), http.StatusBadRequest)
+	req := s.request("GET", "/api/stream", nil)
+	req.Header.Set("Last-Event-ID", "nope")
+	expectStatus(t, s.serve(req), http.StatusBadRequest)
+
+	srv := httptest.NewServer(s.handler)
+	defer srv.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", srv.URL+"/api/stream?author_id="+alice.ID.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Last-Event-ID", strconv.FormatInt(events[0].ID, 10))
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != CONTENT_TYPE_EVENT_STREAM {
+		t.Fatalf("Content-Type = %q, want %q", got, CONTENT_TYPE_EVENT_STREAM)
+	}
+	r := bufio.NewReader(resp.Body)
+
+	// Missed events are replayed after Last-Event-ID, leaving out other
+	// authors and the ones the client already has.
+	if event := readEvent(t, r); event["id"] != strconv.FormatInt(events[2].ID, 10) || event["event"] != "chirp.created" {
+		t.Fatalf("replayed event = %v, want chirp.created %d", event, events[2].ID)
+	}
+
+	chirp := s.createChirp(alice.ID, "four")
+	live := s.chirpEvents()[3]
+	s.cfg.stream.Chirps.Publish(live)
+	event := readEvent(t, r)
+	if event["id"] != strconv.FormatInt(live.ID, 10) {
+		t.Fatalf("live event = %v, want %d", event, live.ID)
+	}
+	var got Chirp
+	if err := json.Unmarshal([]byte(event["data"]), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != chirp.ID || got.Body != "four" {
+		t.Errorf("live chirp = %+v, want %+v", got, chirp)
+	}
+
+	// An event that commits after one with a higher ID still arrives, once.
+	s.createChirp(alice.ID, "five")
+	s.createChirp(alice.ID, "six")
+	late := s.chirpEvents()[4:]
+	for _, e := range []database.ChirpEvent{late[1], late[0], late[1]} {
+		s.cfg.stream.Chirps.Publish(e)
+	}
+	for _, want := range []database.ChirpEvent{late[1], late[0]} {
+		if event := readEvent(t, r); event["id"] != strconv.FormatInt(want.ID, 10) {
+			t.Fatalf("out of order event = %v, want %d", event, want.ID)
+		}
+	}
+	s.cfg.stream.Chirps.Publish(s.chirpEvents()[0])
+	s.createChirp(alice.ID, "seven")
+	next := s.chirpEvents()[6]
+	s.cfg.stream.Chirps.Publish(next)
+	if event := readEvent(t, r); event["id"] != strconv.FormatInt(next.ID, 10) {
+		t.Errorf("event = %v, want %d after the repeats were skipped", event, next.ID)
+	}
+}
@@ -0,0 +1,914 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/auth"
+	"github.com/ghis9917/chirpy/internal/config"
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/fileserver"
+	"github.com/ghis9917/chirpy/internal/health"
+	"github.com/ghis9917/chirpy/internal/metrics"
+	"github.com/ghis9917/chirpy/internal/notifications"
+	"github.com/ghis9917/chirpy/internal/oidc"
+	"github.com/ghis9917/chirpy/internal/scheduler"
+	"github.com/ghis9917/chirpy/internal/store"
+	"github.com/ghis9917/chirpy/internal/store/memory"
+	"github.com/ghis9917/chirpy/internal/stream"
+	"github.com/google/uuid"
+)
+
+const (
+	testServerSecret = "test-server-secret"
+	testPolkaSecret  = "test-polka-secret"
+	testPassword     = "correct horse battery staple"
+)
+
+// testPasswordHash is computed once: argon2id is deliberately slow and most
+// tests only need users to exist.
+var testPasswordHash = sync.OnceValue(func() string {
+	hash, err := auth.HashPassword(context.Background(), testPassword)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+// testServer wires the real routes to an in-memory store.
+type testServer struct {
+	t       *testing.T
+	cfg     *apiConfig
+	db      *memory.Store
+	handler http.Handler
+	assets  string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("<h1>Chirpy</h1>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	assets, err := fileserver.New(
+		dir,
+		fileserver.Options{
+			Allow:      ASSET_ALLOW_LIST,
+			Private:    []string{MEDIA_PRIVATE_DIR},
+			SigningKey: []byte(testServerSecret),
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	closing := make(chan struct{})
+	t.Cleanup(func() { close(closing) })
+
+	db := memory.New()
+	cfg := &apiConfig{
+		metrics:      metrics.New(nil),
+		closing:      closing,
+		db:           db,
+		platform:     "dev",
+		serverSecret: testServerSecret,
+		polkaSecret:  testPolkaSecret,
+		chirps: config.Chirps{
+			MaxLength:    140,
+			ProfaneWords: []string{"kerfuffle", "sharbert", "fornax"},
+		},
+		assets:        assets,
+		notifier:      notifications.New(db),
+		stream:        stream.NewRelay(db, time.Hour),
+		oidcProviders: map[string]*oidc.Provider{},
+		health:        health.NewRegistry(READINESS_TIMEOUT),
+	}
+	cfg.health.Register(health.Flag("server", cfg.ready.Load, "not accepting traffic"))
+	cfg.ready.Store(true)
+
+	return &testServer{t: t, cfg: cfg, db: db, handler: cfg.routes(), assets: dir}
+}
+
+// do sends a request with an optional bearer token and JSON body.
+func (s *testServer) do(method, target, token string, body any) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	req := s.request(method, target, body)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return s.serve(req)
+}
+
+func (s *testServer) request(method, target string, body any) *http.Request {
+	s.t.Helper()
+
+	var r io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		r = strings.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, target, r)
+	if r != nil {
+		req.Header.Set("Content-Type", CONTENT_TYPE_JSON)
+	}
+	return req
+}
+
+func (s *testServer) serve(req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// createUser stores a user with testPassword and returns it with a
+// first-party access token.
+func (s *testServer) createUser(email string) (database.User, string) {
+	s.t.Helper()
+
+	user, err := s.db.CreateUser(
+		context.Background(),
+		database.CreateUserParams{
+			Email:          email,
+			HashedPassword: testPasswordHash(),
+		},
+	)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	token, err := auth.MakeJWT(user.ID, testServerSecret, time.Hour)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	return user, token
+}
+
+func (s *testServer) createChirp(userID uuid.UUID, body string) database.Chirp {
+	s.t.Helper()
+
+	chirp, err := s.db.CreateChirp(
+		context.Background(),
+		database.CreateChirpParams{
+			Body:   body,
+			UserID: userID,
+		},
+	)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	return chirp
+}
+
+// oauthToken registers a client for userID and returns an access token
+// granted to it with the given scopes.
+func (s *testServer) oauthToken(userID uuid.UUID, scopes ...string) (string, string) {
+	s.t.Helper()
+
+	ctx := context.Background()
+	client, err := s.db.CreateOAuthClient(
+		ctx,
+		database.CreateOAuthClientParams{
+			ID:           OAUTH_CLIENT_ID_PREFIX + uuid.NewString(),
+			UserID:       userID,
+			Name:         "Test client",
+			RedirectUris: []string{"https://client.example.com/callback"},
+			Scopes:       OAUTH_SCOPES,
+		},
+	)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	token, tokenID, err := auth.MakeScopedJWT(userID, testServerSecret, time.Hour, client.ID, scopes)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if err := s.db.CreateOAuthAccessToken(
+		ctx,
+		database.CreateOAuthAccessTokenParams{
+			ID:        tokenID,
+			ClientID:  client.ID,
+			UserID:    userID,
+			Scopes:    scopes,
+			ExpiresAt: time.Now().Add(time.Hour),
+		},
+	); err != nil {
+		s.t.Fatal(err)
+	}
+
+	return token, tokenID
+}
+
+func decode[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	var v T
+	if err := json.NewDecoder(rec.Body).Decode(&v); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, want int) {
+	t.Helper()
+
+	if rec.Code != want {
+		t.Fatalf("status = %d, want %d (body %q)", rec.Code, want, rec.Body.String())
+	}
+}
+
+func TestHealth(t *testing.T) {
+	s := newTestServer(t)
+
+	expectStatus(t, s.do("GET", "/api/healthz", "", nil), http.StatusOK)
+	expectStatus(t, s.do("GET", "/api/readyz", "", nil), http.StatusOK)
+
+	s.cfg.ready.Store(false)
+	rec := s.do("GET", "/api/readyz", "", nil)
+	expectStatus(t, rec, http.StatusServiceUnavailable)
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", got)
+	}
+	expectStatus(t, s.do("GET", "/api/healthz", "", nil), http.StatusOK)
+}
+
+func TestAdminMetrics(t *testing.T) {
+	s := newTestServer(t)
+
+	expectStatus(t, s.do("GET", "/app/", "", nil), http.StatusOK)
+	expectStatus(t, s.do("GET", "/app/", "", nil), http.StatusOK)
+
+	rec := s.do("GET", "/admin/metrics", "", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if !strings.Contains(rec.Body.String(), "visited 2 times") {
+		t.Errorf("metrics page = %q, want 2 visits", rec.Body.String())
+	}
+
+	expectStatus(t, s.do("GET", "/metrics", "", nil), http.StatusOK)
+}
+
+func TestAdminReset(t *testing.T) {
+	tests := []struct {
+		platform  string
+		want      int
+		wantUsers bool
+	}{
+		{platform: "dev", want: http.StatusOK, wantUsers: false},
+		{platform: "prod", want: http.StatusForbidden, wantUsers: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.platform, func(t *testing.T) {
+			s := newTestServer(t)
+			s.cfg.platform = tt.platform
+			user, _ := s.createUser("alice@example.com")
+
+			expectStatus(t, s.do("POST", "/admin/reset", "", nil), tt.want)
+
+			_, err := s.db.GetUserByID(context.Background(), user.ID)
+			if got := err == nil; got != tt.wantUsers {
+				t.Errorf("user kept = %v, want %v", got, tt.wantUsers)
+			}
+		})
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := s.do("POST", "/api/users", "", createUserParameters{Email: "alice@example.com", Password: testPassword})
+	expectStatus(t, rec, http.StatusCreated)
+	user := decode[createUserResponse](t, rec)
+	if user.Email != "alice@example.com" || user.IsChirpyRed {
+		t.Errorf("user = %+v, want alice@example.com without Chirpy Red", user)
+	}
+
+	rec = s.do("POST", "/api/users", "", createUserParameters{Email: "alice@example.com", Password: testPassword})
+	expectStatus(t, rec, http.StatusInternalServerError)
+
+	expectStatus(t, s.do("POST", "/api/users", "", "{"), http.StatusBadRequest)
+
+	rec = s.do("POST", "/api/users", "", createUserParameters{Email: "Alice <alice@example.com>"})
+	expectStatus(t, rec, http.StatusBadRequest)
+	got := decode[validationErr](t, rec)
+	want := []fieldError{
+		{Field: "email", Message: "must be an email address"},
+		{Field: "password", Message: "is required"},
+	}
+	if !slices.Equal(got.Fields, want) {
+		t.Errorf("fields = %+v, want %+v", got.Fields, want)
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	s := newTestServer(t)
+	user, token := s.createUser("alice@example.com")
+	params := updateUserParameters{Email: "alice@example.org", Password: "hunter2"}
+
+	expectStatus(t, s.do("PUT", "/api/users", "", params), http.StatusUnauthorized)
+
+	// Delegated credentials cannot take over the account, whatever their
+	// scopes.
+	oauthToken, _ := s.oauthToken(user.ID, OAUTH_SCOPES...)
+	expectStatus(t, s.do("PUT", "/api/users", oauthToken, params), http.StatusForbidden)
+	rec := s.do("POST", "/api/keys", token, createAPIKeyParameters{Name: "CI", Scopes: OAUTH_SCOPES})
+	expectStatus(t, rec, http.StatusCreated)
+	expectStatus(t, s.doWithAPIKey("PUT", "/api/users", decode[APIKey](t, rec).Key, params), http.StatusForbidden)
+
+	rec = s.do("PUT", "/api/users", token, params)
+	expectStatus(t, rec, http.StatusOK)
+	if got := decode[updateUserResponse](t, rec); got.ID != user.ID || got.Email != params.Email {
+		t.Errorf("updated user = %+v, want %s with %s", got, user.ID, params.Email)
+	}
+
+	expectStatus(t, s.do("POST", "/api/login", "", loginUserParameters{Email: params.Email, Password: "hunter2"}), http.StatusOK)
+}
+
+func TestGetUser(t *testing.T) {
+	s := newTestServer(t)
+	user, token := s.createUser("alice@example.com")
+
+	expectStatus(t, s.do("GET", "/api/users", "", nil), http.StatusUnauthorized)
+
+	rec := s.do("GET", "/api/users", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Header().Get("ETag") == "" || rec.Header().Get("Last-Modified") != user.UpdatedAt.UTC().Format(http.TimeFormat) {
+		t.Errorf("validators = %q, %q, want an ETag and the user's updated_at", rec.Header().Get("ETag"), rec.Header().Get("Last-Modified"))
+	}
+	if got := decode[updateUserResponse](t, rec); got.ID != user.ID || got.Email != user.Email {
+		t.Errorf("user = %+v, want %s", got, user.ID)
+	}
+}
+
+// TestIfMatch checks that a client holding a stale copy of a resource cannot
+// change it.
+func TestIfMatch(t *testing.T) {
+	s := newTestServer(t)
+	user, token := s.createUser("alice@example.com")
+	tag := s.do("GET", "/api/users", token, nil).Header().Get("ETag")
+
+	update := func(tag string, params updateUserParameters) *httptest.ResponseRecorder {
+		req := s.request("PUT", "/api/users", params)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("If-Match", tag)
+		return s.serve(req)
+	}
+
+	rec := update(tag, updateUserParameters{Email: "alice@example.org", Password: "hunter2"})
+	expectStatus(t, rec, http.StatusOK)
+	newTag := rec.Header().Get("ETag")
+	if newTag == "" || newTag == tag {
+		t.Errorf("ETag after update = %q, want a new tag", newTag)
+	}
+
+	// A second client still holding the first version.
+	expectStatus(t, update(tag, updateUserParameters{Email: "alice@example.net", Password: "hunter3"}), http.StatusPreconditionFailed)
+	expectStatus(t, update("W/"+newTag, updateUserParameters{Email: "alice@example.net", Password: "hunter3"}), http.StatusPreconditionFailed)
+	if got, _ := s.db.GetUserByID(context.Background(), user.ID); got.Email != "alice@example.org" {
+		t.Errorf("email = %s, want the stale update rejected", got.Email)
+	}
+
+	chirp := s.createChirp(user.ID, "hello world")
+	target := "/api/chirps/" + chirp.ID.String()
+	chirpTag := s.do("GET", target, token, nil).Header().Get("ETag")
+	expectStatus(t, s.do("POST", "/api/bookmarks/"+chirp.ID.String(), token, nil), http.StatusNoContent)
+
+	del := func(target, tag string) *httptest.ResponseRecorder {
+		req := s.request("DELETE", target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("If-Match", tag)
+		return s.serve(req)
+	}
+	expectStatus(t, del(target, chirpTag), http.StatusPreconditionFailed)
+	expectStatus(t, del(target, s.do("GET", target, token, nil).Header().Get("ETag")), http.StatusNoContent)
+
+	expectStatus(t, del("/api/users", tag), http.StatusPreconditionFailed)
+	expectStatus(t, del("/api/users", "*"), http.StatusNoContent)
+}
+
+func TestDeleteUser(t *testing.T) {
+	s := newTestServer(t)
+	user, token := s.createUser("alice@example.com")
+	_, otherToken := s.createUser("bob@example.com")
+	chirp := s.createChirp(user.ID, "hello world")
+	oauthToken, _ := s.oauthToken(user.ID, SCOPE_ADMIN)
+
+	expectStatus(t, s.do("DELETE", "/api/users", "", nil), http.StatusUnauthorized)
+	expectStatus(t, s.do("DELETE", "/api/users", oauthToken, nil), http.StatusForbidden)
+	expectStatus(t, s.do("DELETE", "/api/users", token, nil), http.StatusNoContent)
+	expectStatus(t, s.do("DELETE", "/api/users", token, nil), http.StatusNotFound)
+
+	expectStatus(t, s.do("GET", "/api/chirps/"+chirp.ID.String(), "", nil), http.StatusNotFound)
+	expectStatus(t, s.do("POST", "/api/login", "", loginUserParameters{Email: "alice@example.com", Password: testPassword}), http.StatusUnauthorized)
+	expectStatus(t, s.do("GET", "/api/drafts", otherToken, nil), http.StatusOK)
+}
+
+func TestLogin(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("alice@example.com")
+	if _, err := s.db.CreateUser(
+		context.Background(),
+		database.CreateUserParams{Email: "bob@example.com", HashedPassword: PASSWORD_UNSET},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		want     int
+	}{
+		{name: "correct password", email: "alice@example.com", password: testPassword, want: http.StatusOK},
+		{name: "wrong password", email: "alice@example.com", password: "hunter2", want: http.StatusUnauthorized},
+		{name: "unknown email", email: "carol@example.com", password: testPassword, want: http.StatusUnauthorized},
+		{name: "no password set", email: "bob@example.com", password: PASSWORD_UNSET, want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do("POST", "/api/login", "", loginUserParameters{Email: tt.email, Password: tt.password})
+			expectStatus(t, rec, tt.want)
+			if tt.want != http.StatusOK {
+				return
+			}
+
+			session := decode[loginUserResponse](t, rec)
+			if session.Token == "" || session.RefreshToken == "" {
+				t.Fatalf("session = %+v, want both tokens", session)
+			}
+			expectStatus(t, s.do("GET", "/api/drafts", session.Token, nil), http.StatusOK)
+		})
+	}
+}
+
+func TestRefreshAndRevoke(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("alice@example.com")
+
+	rec := s.do("POST", "/api/login", "", loginUserParameters{Email: "alice@example.com", Password: testPassword})
+	expectStatus(t, rec, http.StatusOK)
+	refreshToken := decode[loginUserResponse](t, rec).RefreshToken
+
+	expectStatus(t, s.do("POST", "/api/refresh", "", nil), http.StatusInternalServerError)
+	expectStatus(t, s.do("POST", "/api/refresh", "unknown", nil), http.StatusUnauthorized)
+	expectStatus(t, s.do("POST", "/api/revoke", "", nil), http.StatusInternalServerError)
+	expectStatus(t, s.do("POST", "/api/revoke", "unknown", nil), http.StatusNotFound)
+
+	rec = s.do("POST", "/api/refresh", refreshToken, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if got := decode[refreshReponse](t, rec); got.Token == "" {
+		t.Error("refresh returned no access token")
+	}
+
+	expectStatus(t, s.do("POST", "/api/revoke", refreshToken, nil), http.StatusNoContent)
+	expectStatus(t, s.do("POST", "/api/refresh", refreshToken, nil), http.StatusUnauthorized)
+}
+
+func TestCreateChirp(t *testing.T) {
+	s := newTestServer(t)
+	user, token := s.createUser("alice@example.com")
+
+	tests := []struct {
+		name     string
+		token    string
+		body     any
+		want     int
+		wantBody string
+	}{
+		{name: "clean", token: token, body: createChirpParameters{Body: "hello world"}, want: http.StatusCreated, wantBody: "hello world"},
+		{name: "profane", token: token, body: createChirpParameters{Body: "what a Kerfuffle today"}, want: http.StatusCreated, wantBody: "what a **** today"},
+		{name: "too long", token: token, body: createChirpParameters{Body: strings.Repeat("a", 141)}, want: http.StatusBadRequest},
+		{name: "unauthenticated", body: createChirpParameters{Body: "hello world"}, want: http.StatusUnauthorized},
+		{name: "emoji", token: token, body: createChirpParameters{Body: strings.Repeat("👍🏽", 140)}, want: http.StatusCreated, wantBody: strings.Repeat("👍🏽", 140)},
+		{name: "too many emoji", token: token, body: createChirpParameters{Body: strings.Repeat("👍🏽", 141)}, want: http.StatusBadRequest},
+		{name: "empty", token: token, body: createChirpParameters{Body: " "}, want: http.StatusBadRequest},
+		{name: "malformed", token: token, body: "{", want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do("POST", "/api/chirps", tt.token, tt.body)
+			expectStatus(t, rec, tt.want)
+			if tt.want != http.StatusCreated {
+				return
+			}
+
+			chirp := decode[Chirp](t, rec)
+			if chirp.Body != tt.wantBody || chirp.UserID != user.ID {
+				t.Errorf("chirp = %+v, want %q by %s", chirp, tt.wantBody, user.ID)
+			}
+		})
+	}
+}
+
+func TestScheduledChirps(t *testing.T) {
+	s := newTestServer(t)
+	user, token := s.createUser("alice@example.com")
+	_, otherToken := s.createUser("bob@example.com")
+	params := createChirpParameters{Body: "from the future"}
+	later := time.Now().Add(time.Hour)
+	params.ScheduledAt = &later
+
+	expectStatus(t, s.do("POST", "/api/chirps", token, params), http.StatusForbidden)
+
+	if err := s.db.UpgradeUser(context.Background(), user.ID); err != nil {
+		t.Fatal(err)
+	}
+	rec := s.do("POST", "/api/chirps", token, params)
+	expectStatus(t, rec, http.StatusAccepted)
+	scheduled := decode[ScheduledChirp](t, rec)
+
+	rec = s.do("GET", "/api/chirps/scheduled", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if got := decode[[]ScheduledChirp](t, rec); len(got) != 1 || got[0].ID != scheduled.ID {
+		t.Errorf("scheduled chirps = %+v, want [%s]", got, scheduled.ID)
+	}
+	expectStatus(t, s.do("GET", "/api/chirps/scheduled", "", nil), http.StatusUnauthorized)
+
+	for range scheduler.MaxAttempts {
+		if err := s.db.RecordScheduledChirpFailure(
+			context.Background(),
+			database.RecordScheduledChirpFailureParams{
+				LastError:     sql.NullString{String: "boom", Valid: true},
+				NextAttemptAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+				ID:            scheduled.ID,
+			},
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rec = s.do("GET", "/api/chirps/scheduled", token, nil)
+	if got := decode[[]ScheduledChirp](t, rec); len(got) != 1 || !got[0].Failed || got[0].LastError != "boom" || got[0].NextAttemptAt != nil {
+		t.Errorf("scheduled chirps = %+v, want it shown as failed", got)
+	}
+
+	target := "/api/chirps/scheduled/" + scheduled.ID.String()
+	expectStatus(t, s.do("DELETE", "/api/chirps/scheduled/not-a-uuid", token, nil), http.StatusBadRequest)
+	expectStatus(t, s.do("DELETE", target, "", nil), http.StatusUnauthorized)
+	expectStatus(t, s.do("DELETE", target, otherToken, nil), http.StatusForbidden)
+	expectStatus(t, s.do("DELETE", target, token, nil), http.StatusNoContent)
+	expectStatus(t, s.do("DELETE", target, token, nil), http.StatusNotFound)
+}
+
+func TestGetChirps(t *testing.T) {
+	s := newTestServer(t)
+	alice, token := s.createUser("alice@example.com")
+	bob, _ := s.createUser("bob@example.com")
+	first := s.createChirp(alice.ID, "first")
+	second := s.createChirp(bob.ID, "second")
+	third := s.createChirp(alice.ID, "third")
+
+	tests := []struct {
+		target string
+		want   []uuid.UUID
+	}{
+		{target: "/api/chirps", want: []uuid.UUID{first.ID, second.ID, third.ID}},
+		{target: "/api/chirps?sort=desc", want: []uuid.UUID{third.ID, second.ID, first.ID}},
+		{target: "/api/chirps?author_id=" + alice.ID.String(), want: []uuid.UUID{first.ID, third.ID}},
+	}
+
+	for _, tt := range tests {
+		rec := s.do("GET", tt.target, "", nil)
+		expectStatus(t, rec, http.StatusOK)
+
+		var got []uuid.UUID
+		for _, c := range decode[[]Chirp](t, rec) {
+			got = append(got, c.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("GET %s = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+
+	expectStatus(t, s.do("GET", "/api/chirps?author_id=nope", "", nil), http.StatusBadRequest)
+
+	// Anonymous readers do not get the bookmark flag; signed-in ones do.
+	rec := s.do("GET", "/api/chirps", "", nil)
+	if got := decode[[]Chirp](t, rec); got[0].BookmarkedByMe != nil {
+		t.Errorf("anonymous bookmarked_by_me = %v, want omitted", *got[0].BookmarkedByMe)
+	}
+	expectStatus(t, s.do("POST", "/api/bookmarks/"+first.ID.String(), token, nil), http.StatusNoContent)
+	rec = s.do("GET", "/api/chirps", token, nil)
+	for _, c := range decode[[]Chirp](t, rec) {
+		if c.BookmarkedByMe == nil || *c.BookmarkedByMe != (c.ID == first.ID) {
+			t.Errorf("chirp %s bookmarked_by_me = %v, want %v", c.ID, c.BookmarkedByMe, c.ID == first.ID)
+		}
+	}
+}
+
+func TestGetChirpByID(t *testing.T) {
+	s := newTestServer(t)
+	user, _ := s.createUser("alice@example.com")
+	chirp := s.createChirp(user.ID, "hello world")
+
+	rec := s.do("GET", "/api/chirps/"+chirp.ID.String(), "", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if got := decode[Chirp](t, rec); got.ID != chirp.ID || got.Body != chirp.Body {
+		t.Errorf("chirp = %+v, want %+v", got, chirp)
+	}
+
+	expectStatus(t, s.do("GET", "/api/chirps/"+uuid.NewString(), "", nil), http.StatusNotFound)
+	expectStatus(t, s.do("GET", "/api/chirps/not-a-uuid", "", nil), http.StatusInternalServerError)
+}
+
+func TestGetChirpConditional(t *testing.T) {
+	s := newTestServer(t)
+	user, token := s.createUser("alice@example.com")
+	chirp := s.createChirp(user.ID, "hello world")
+	target := "/api/chirps/" + chirp.ID.String()
+
+	get := func(target string, header ...string) *httptest.ResponseRecorder {
+		req := s.request("GET", target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		return s.serve(req)
+	}
+
+	rec := get(target)
+	expectStatus(t, rec, http.StatusOK)
+	tag := rec.Header().Get("ETag")
+	lastModified := rec.Header().Get("Last-Modified")
+	if tag == "" || lastModified == "" {
+		t.Fatalf("validators = %q, %q, want both", tag, lastModified)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "private, no-cache" {
+		t.Errorf("Cache-Control = %q, want private, no-cache", got)
+	}
+
+	rec = get(target, "If-None-Match", `"other", `+tag)
+	expectStatus(t, rec, http.StatusNotModified)
+	if rec.Body.Len() != 0 || rec.Header().Get("ETag") != tag {
+		t.Errorf("304 body = %q, ETag = %q, want no body and the same tag", rec.Body.String(), rec.Header().Get("ETag"))
+	}
+	expectStatus(t, get(target, "If-None-Match", "W/"+tag), http.StatusNotModified)
+	expectStatus(t, get(target, "If-Modified-Since", lastModified), http.StatusNotModified)
+	expectStatus(t, get(target, "If-Modified-Since", chirp.UpdatedAt.Add(-time.Hour).UTC().Format(http.TimeFormat)), http.StatusOK)
+	// If-None-Match wins over If-Modified-Since.
+	expectStatus(t, get(target, "If-None-Match", `"other"`, "If-Modified-Since", lastModified), http.StatusOK)
+
+	// Bookmarking changes the caller's representation, so the tag changes.
+	expectStatus(t, s.do("POST", "/api/bookmarks/"+chirp.ID.String(), token, nil), http.StatusNoContent)
+	expectStatus(t, get(target, "If-None-Match", tag), http.StatusOK)
+
+	rec = get("/api/chirps")
+	expectStatus(t, rec, http.StatusOK)
+	listTag := rec.Header().Get("ETag")
+	expectStatus(t, get("/api/chirps", "If-None-Match", listTag), http.StatusNotModified)
+	s.createChirp(user.ID, "second chirp")
+	expectStatus(t, get("/api/chirps", "If-None-Match", listTag), http.StatusOK)
+}
+
+func TestGetChirpsFormats(t *testing.T) {
+	s := newTestServer(t)
+	user, token := s.createUser("alice@example.com")
+	first := s.createChirp(user.ID, "hello, \"world\"")
+	second := s.createChirp(user.ID, "second")
+	expectStatus(t, s.do("POST", "/api/bookmarks/"+second.ID.String(), token, nil), http.StatusNoContent)
+
+	get := func(accept string) *httptest.ResponseRecorder {
+		req := s.request("GET", "/api/chirps", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept", accept)
+		return s.serve(req)
+	}
+
+	rec := get("application/x-ndjson")
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Content-Type"); got != CONTENT_TYPE_NDJSON {
+		t.Errorf("Content-Type = %q, want %q", got, CONTENT_TYPE_NDJSON)
+	}
+	if got := rec.Header().Values("Vary"); !slices.Contains(got, "Accept") {
+		t.Errorf("Vary = %v, want Accept", got)
+	}
+	decoder := json.NewDecoder(rec.Body)
+	var lines []Chirp
+	for decoder.More() {
+		var chirp Chirp
+		if err := decoder.Decode(&chirp); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, chirp)
+	}
+	if len(lines) != 2 || lines[0].ID != first.ID || lines[1].ID != second.ID {
+		t.Errorf("NDJSON chirps = %+v, want both in order", lines)
+	}
+
+	rec = get("text/csv")
+	expectStatus(t, rec, http.StatusOK)
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || !slices.Equal(records[0], CHIRP_CSV_HEADER) {
+		t.Fatalf("CSV = %q, want a header and 2 rows", records)
+	}
+	want := []string{
+		second.ID.String(),
+		second.CreatedAt.UTC().Format(time.RFC3339Nano),
+		second.UpdatedAt.UTC().Format(time.RFC3339Nano),
+		"second",
+		user.ID.String(),
+		"true",
+	}
+	if records[1][3] != first.Body || !slices.Equal(records[2], want) {
+		t.Errorf("CSV rows = %q, want %q last", records[1:], want)
+	}
+
+	rec = get("text/html")
+	expectStatus(t, rec, http.StatusNotAcceptable)
+	decode[jsonErr](t, rec)
+
+	rec = get("text/html, application/json;q=0.1")
+	expectStatus(t, rec, http.StatusOK)
+	if got := decode[[]Chirp](t, rec); len(got) != 2 {
+		t.Errorf("JSON chirps = %d, want 2", len(got))
+	}
+}
+
+func TestDeleteChirp(t *testing.T) {
+	s := newTestServer(t)
+	user, token := s.createUser("alice@example.com")
+	_, otherToken := s.createUser("bob@example.com")
+	chirp := s.createChirp(user.ID, "hello world")
+	target := "/api/chirps/" + chirp.ID.String()
+
+	expectStatus(t, s.do("DELETE", "/api/chirps/not-a-uuid", token, nil), http.StatusBadRequest)
+	expectStatus(t, s.do("DELETE", target, "", nil), http.StatusUnauthorized)
+	expectStatus(t, s.do("DELETE", target, otherToken, nil), http.StatusForbidden)
+	expectStatus(t, s.do("DELETE", target, token, nil), http.StatusNoContent)
+	expectStatus(t, s.do("DELETE", target, token, nil), http.StatusNotFound)
+	expectStatus(t, s.do("GET", target, "", nil), http.StatusNotFound)
+}
+
+func TestUpgradeUser(t *testing.T) {
+	s := newTestServer(t)
+	user, _ := s.createUser("alice@example.com")
+
+	upgrade := func(apiKey string, params upgradeUserParams) *httptest.ResponseRecorder {
+		req := s.request("POST", "/api/polka/webhooks", params)
+		if apiKey != "" {
+			req.Header.Set("Authorization", "ApiKey "+apiKey)
+		}
+		return s.serve(req)
+	}
+	upgraded := func() bool {
+		u, err := s.db.GetUserByID(context.Background(), user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u.IsChirpyRed
+	}
+	params := upgradeUserParams{Event: WEBHOOKS_UPGRADE_EVENT, Data: upgradeUserParamsData{UserID: user.ID.String()}}
+
+	expectStatus(t, upgrade("", params), http.StatusUnauthorized)
+	expectStatus(t, upgrade("wrong", params), http.StatusUnauthorized)
+	expectStatus(t, upgrade(testPolkaSecret, upgradeUserParams{Event: "user.payment_failed", Data: params.Data}), http.StatusNoContent)
+	if upgraded() {
+		t.Fatal("user upgraded by an unrelated event")
+	}
+	expectStatus(t, upgrade(testPolkaSecret, upgradeUserParams{Event: WEBHOOKS_UPGRADE_EVENT, Data: upgradeUserParamsData{UserID: "nope"}}), http.StatusBadRequest)
+	expectStatus(t, upgrade(testPolkaSecret, upgradeUserParams{Event: WEBHOOKS_UPGRADE_EVENT, Data: upgradeUserParamsData{UserID: uuid.NewString()}}), http.StatusNotFound)
+	expectStatus(t, upgrade(testPolkaSecret, params), http.StatusNoContent)
+	if !upgraded() {
+		t.Error("user not upgraded")
+	}
+	expectStatus(t, upgrade(testPolkaSecret, params), http.StatusNoContent)
+}
+
+func TestSignMediaURL(t *testing.T) {
+	s := newTestServer(t)
+	user, token := s.createUser("alice@example.com")
+	own := "/" + MEDIA_PRIVATE_DIR + "/" + user.ID.String() + "/avatar.png"
+	if err := os.MkdirAll(filepath.Join(s.assets, filepath.Dir(own)), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(s.assets, own), []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		path  string
+		want  int
+	}{
+		{name: "own media", token: token, path: own, want: http.StatusOK},
+		{name: "someone else's media", token: token, path: "/" + MEDIA_PRIVATE_DIR + "/" + uuid.NewString() + "/avatar.png", want: http.StatusForbidden},
+		{name: "public media", token: token, path: "/" + MEDIA_PUBLIC_DIR + "/logo.png", want: http.StatusBadRequest},
+		{name: "unauthenticated", path: own, want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do("GET", "/api/media/signed-url?path="+tt.path, tt.token, nil)
+			expectStatus(t, rec, tt.want)
+			if tt.want != http.StatusOK {
+				return
+			}
+
+			signed := decode[signedURLResponse](t, rec)
+			expectStatus(t, s.do("GET", signed.URL, "", nil), http.StatusOK)
+			expectStatus(t, s.do("GET", "/app"+own, "", nil), http.StatusForbidden)
+		})
+	}
+}
+
+func TestMiddlewareScope(t *testing.T) {
+	s := newTestServer(t)
+	user, _ := s.createUser("alice@example.com")
+	readOnly, tokenID := s.oauthToken(user.ID, SCOPE_READ)
+
+	expectStatus(t, s.do("GET", "/api/drafts", readOnly, nil), http.StatusOK)
+
+	rec := s.do("POST", "/api/chirps", readOnly, createChirpParameters{Body: "hello"})
+	expectStatus(t, rec, http.StatusForbidden)
+	if got := rec.Header().Get("WWW-Authenticate"); !strings.Contains(got, `scope="write"`) {
+		t.Errorf("WWW-Authenticate = %q, want the missing scope", got)
+	}
+
+	// Credential management is off limits whatever the scopes.
+	expectStatus(t, s.do("GET", "/api/keys", readOnly, nil), http.StatusForbidden)
+
+	// Scoped routes need credentials; public ones take any caller alike and
+	// only use credentials with the scope to read the caller's own data.
+	expectStatus(t, s.do("GET", "/api/drafts", "", nil), http.StatusUnauthorized)
+	expectStatus(t, s.do("GET", "/admin/metrics", readOnly, nil), http.StatusOK)
+	s.createChirp(user.ID, "hello")
+	writeOnly, _ := s.oauthToken(user.ID, SCOPE_WRITE)
+	rec = s.do("GET", "/api/chirps", writeOnly, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if got := decode[[]Chirp](t, rec); len(got) != 1 || got[0].BookmarkedByMe != nil {
+		t.Errorf("chirps = %+v, want them without bookmarks for a token that cannot read them", got)
+	}
+
+	// The token is checked once however many places need the caller.
+	counted := &countingStore{Store: s.cfg.db}
+	s.cfg.db = counted
+	rec = s.do("GET", "/api/chirps", readOnly, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if got := decode[[]Chirp](t, rec); len(got) != 1 || got[0].BookmarkedByMe == nil {
+		t.Errorf("chirps = %+v, want them marked with the caller's bookmarks", got)
+	}
+	expectStatus(t, s.do("GET", "/api/drafts", readOnly, nil), http.StatusOK)
+	if got := counted.oauthTokenLookups.Load(); got != 2 {
+		t.Errorf("access token looked up %d times for 2 requests, want once each", got)
+	}
+
+	if err := s.db.RevokeOAuthAccessToken(context.Background(), tokenID); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, s.do("GET", "/api/drafts", readOnly, nil), http.StatusUnauthorized)
+}
+
+// countingStore counts the lookups made to check credentials.
+type countingStore struct {
+	store.Store
+	oauthTokenLookups atomic.Int32
+	apiKeyLookups     atomic.Int32
+	apiKeyTouches     atomic.Int32
+}
+
+func (s *countingStore) GetAPIKeyByHash(ctx context.Context, keyHash string) (database.ApiKey, error) {
+	s.apiKeyLookups.Add(1)
+	return s.Store.GetAPIKeyByHash(ctx, keyHash)
+}
+
+func (s *countingStore) TouchAPIKey(ctx context.Context, id uuid.UUID) error {
+	s.apiKeyTouches.Add(1)
+	return s.Store.TouchAPIKey(ctx, id)
+}
+
+func (s *countingStore) GetOAuthAccessToken(ctx context.Context, id string) (database.OauthAccessToken, error) {
+	s.oauthTokenLookups.Add(1)
+	return s.Store.GetOAuthAccessToken(ctx, id)
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handleCreateWebhook(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	params, err := extractParams(createWebhookParameters{}, req)
+	if err != nil {
+		sendParamsError(w, err)
+		return
+	}
+
+	// validate has checked the URL parses.
+	target, _ := url.Parse(params.URL)
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	subscription, err := cfg.db.CreateWebhookSubscription(
+		req.Context(),
+		database.CreateWebhookSubscriptionParams{
+			UserID: userID,
+			Url:    target.String(),
+			Secret: secret,
+			Events: params.Events,
+		},
+	)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	// The signing secret is only ever returned here.
+	response := toWebhookSubscription(subscription)
+	response.Secret = subscription.Secret
+
+	sendJSONResponse(w, http.StatusCreated, response)
+
+}
+
+func (cfg *apiConfig) handleGetWebhooks(w http.ResponseWriter, req *http.Request) {
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	subscriptions, err := cfg.db.GetWebhookSubscriptionsByUser(req.Context(), userID)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	data := []WebhookSubscription{}
+	for _, s := range subscriptions {
+		data = append(data, toWebhookSubscription(s))
+	}
+
+	sendJSONResponse(w, http.StatusOK, data)
+
+}
+
+func (cfg *apiConfig) handleDeleteWebhook(w http.ResponseWriter, req *http.Request) {
+
+	subscription, ok := cfg.ownedWebhook(w, req)
+	if !ok {
+		return
+	}
+
+	if err := cfg.db.DeleteWebhookSubscriptionByID(req.Context(), subscription.ID); err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handleGetWebhookDeliveries(w http.ResponseWriter, req *http.Request) {
+
+	subscription, ok := cfg.ownedWebhook(w, req)
+	if !ok {
+		return
+	}
+
+	limit, offset, err := parsePagination(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	params := database.GetWebhookDeliveriesParams{
+		SubscriptionID: subscription.ID,
+		RowLimit:       limit,
+		RowOffset:      offset,
+	}
+	if status := req.URL.Query().Get("status"); status != "" {
+		params.Status = sql.NullString{String: status, Valid: true}
+	}
+
+	deliveries, err := cfg.db.GetWebhookDeliveries(req.Context(), params)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	data := []WebhookDelivery{}
+	for _, d := range deliveries {
+		data = append(data, toWebhookDelivery(d))
+	}
+
+	sendJSONResponse(w, http.StatusOK, data)
+
+}
+
+func (cfg *apiConfig) handleGetWebhookDeliveryAttempts(w http.ResponseWriter, req *http.Request) {
+
+	delivery, ok := cfg.ownedWebhookDelivery(w, req)
+	if !ok {
+		return
+	}
+
+	attempts, err := cfg.db.GetWebhookDeliveryAttempts(req.Context(), delivery.ID)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	data := []WebhookDeliveryAttempt{}
+	for _, a := range attempts {
+		data = append(data, toWebhookDeliveryAttempt(a))
+	}
+
+	sendJSONResponse(w, http.StatusOK, data)
+
+}
+
+func (cfg *apiConfig) handleRedeliverWebhook(w http.ResponseWriter, req *http.Request) {
+
+	delivery, ok := cfg.ownedWebhookDelivery(w, req)
+	if !ok {
+		return
+	}
+
+	delivery, err := cfg.db.RedeliverWebhookDelivery(req.Context(), delivery.ID)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+
+	sendJSONResponse(w, http.StatusAccepted, toWebhookDelivery(delivery))
+
+}
+
+func (cfg *apiConfig) ownedWebhook(w http.ResponseWriter, req *http.Request) (database.WebhookSubscription, bool) {
+
+	webhookUUID, err := uuid.Parse(req.PathValue("webhookID"))
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: fmt.Sprintf("Invalid WebhookID: %v", err)})
+		return database.WebhookSubscription{}, false
+	}
+
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return database.WebhookSubscription{}, false
+	}
+
+	subscription, err := cfg.db.GetWebhookSubscriptionByID(req.Context(), webhookUUID)
+	if err != nil {
+		sendJSONResponse(w, http.StatusNotFound, jsonErr{Error: fmt.Sprintf("Webhook not found: %v", err)})
+		return database.WebhookSubscription{}, false
+	}
+
+	if subscription.UserID != userID {
+		w.WriteHeader(http.StatusForbidden)
+		return database.WebhookSubscription{}, false
+	}
+
+	return subscription, true
+}
+
+func (cfg *apiConfig) ownedWebhookDelivery(w http.ResponseWriter, req *http.Request) (database.WebhookDelivery, bool) {
+
+	subscription, ok := cfg.ownedWebhook(w, req)
+	if !ok {
+		return database.WebhookDelivery{}, false
+	}
+
+	deliveryUUID, err := uuid.Parse(req.PathValue("deliveryID"))
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: fmt.Sprintf("Invalid DeliveryID: %v", err)})
+		return database.WebhookDelivery{}, false
+	}
+
+	delivery, err := cfg.db.GetWebhookDeliveryByID(req.Context(), deliveryUUID)
+	if err != nil || delivery.SubscriptionID != subscription.ID {
+		sendJSONResponse(w, http.StatusNotFound, jsonErr{Error: "Delivery not found"})
+		return database.WebhookDelivery{}, false
+	}
+
+	return delivery, true
+}
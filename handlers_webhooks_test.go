@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+func TestWebhookSubscriptions(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.createUser("alice@example.com")
+	_, otherToken := s.createUser("bob@example.com")
+
+	tests := []struct {
+		name   string
+		params createWebhookParameters
+		want   int
+	}{
+		{name: "valid", params: createWebhookParameters{URL: "https://example.com/hook", Events: []string{webhooks.EventChirpCreated}}, want: http.StatusCreated},
+		{name: "relative url", params: createWebhookParameters{URL: "/hook", Events: []string{webhooks.EventChirpCreated}}, want: http.StatusBadRequest},
+		{name: "unsupported scheme", params: createWebhookParameters{URL: "ftp://example.com/hook", Events: []string{webhooks.EventChirpCreated}}, want: http.StatusBadRequest},
+		{name: "loopback", params: createWebhookParameters{URL: "http://127.0.0.1:8080/hook", Events: []string{webhooks.EventChirpCreated}}, want: http.StatusBadRequest},
+		{name: "localhost", params: createWebhookParameters{URL: "http://localhost/hook", Events: []string{webhooks.EventChirpCreated}}, want: http.StatusBadRequest},
+		{name: "private network", params: createWebhookParameters{URL: "https://10.1.2.3/hook", Events: []string{webhooks.EventChirpCreated}}, want: http.StatusBadRequest},
+		{name: "metadata service", params: createWebhookParameters{URL: "http://169.254.169.254/latest/meta-data/", Events: []string{webhooks.EventChirpCreated}}, want: http.StatusBadRequest},
+		{name: "no events", params: createWebhookParameters{URL: "https://example.com/hook"}, want: http.StatusBadRequest},
+		{name: "unknown event", params: createWebhookParameters{URL: "https://example.com/hook", Events: []string{"chirp.liked"}}, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do("POST", "/api/webhooks", token, tt.params)
+			expectStatus(t, rec, tt.want)
+			if tt.want != http.StatusCreated {
+				return
+			}
+			if got := decode[WebhookSubscription](t, rec); got.Secret == "" {
+				t.Error("created webhook has no signing secret")
+			}
+		})
+	}
+	expectStatus(t, s.do("POST", "/api/webhooks", "", tests[0].params), http.StatusUnauthorized)
+
+	rec := s.do("GET", "/api/webhooks", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	subscriptions := decode[[]WebhookSubscription](t, rec)
+	if len(subscriptions) != 1 || subscriptions[0].Secret != "" {
+		t.Fatalf("webhooks = %+v, want one without its secret", subscriptions)
+	}
+	expectStatus(t, s.do("GET", "/api/webhooks", "", nil), http.StatusUnauthorized)
+
+	target := "/api/webhooks/" + subscriptions[0].ID.String()
+	expectStatus(t, s.do("DELETE", "/api/webhooks/not-a-uuid", token, nil), http.StatusBadRequest)
+	expectStatus(t, s.do("DELETE", "/api/webhooks/"+uuid.NewString(), token, nil), http.StatusNotFound)
+	expectStatus(t, s.do("DELETE", target, otherToken, nil), http.StatusForbidden)
+	expectStatus(t, s.do("DELETE", target, token, nil), http.StatusNoContent)
+	expectStatus(t, s.do("DELETE", target, token, nil), http.StatusNotFound)
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	s := newTestServer(t)
+	alice, token := s.createUser("alice@example.com")
+	_, otherToken := s.createUser("bob@example.com")
+
+	var received []string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		received = append(received, req.Header.Get(webhooks.EventHeader))
+		if len(received) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	// The API refuses the receiver's loopback address, so it is subscribed
+	// directly.
+	subscription, err := s.db.CreateWebhookSubscription(
+		context.Background(),
+		database.CreateWebhookSubscriptionParams{
+			UserID: alice.ID,
+			Url:    receiver.URL,
+			Secret: "whsec_test",
+			Events: []string{webhooks.EventChirpCreated},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deliveries := "/api/webhooks/" + subscription.ID.String() + "/deliveries"
+
+	expectStatus(t, s.do("POST", "/api/chirps", token, createChirpParameters{Body: "hello hooks"}), http.StatusCreated)
+
+	rec := s.do("GET", deliveries, token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	pending := decode[[]WebhookDelivery](t, rec)
+	if len(pending) != 1 || pending[0].Event != webhooks.EventChirpCreated || pending[0].Status != webhooks.StatusPending {
+		t.Fatalf("deliveries = %+v, want one pending chirp.created", pending)
+	}
+	delivery := deliveries + "/" + pending[0].ID.String()
+
+	// The first attempt fails; redelivering makes it due again right away.
+	dispatcher := webhooks.NewDispatcher(s.db, time.Minute)
+	dispatcher.AllowPrivateNetworks = true
+	if err := dispatcher.DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, s.do("POST", delivery+"/redeliver", token, nil), http.StatusAccepted)
+	if err := dispatcher.DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 2 {
+		t.Fatalf("receiver got %d requests, want 2", len(received))
+	}
+
+	rec = s.do("GET", deliveries+"?status="+webhooks.StatusSucceeded, token, nil)
+	if got := decode[[]WebhookDelivery](t, rec); len(got) != 1 || got[0].DeliveredAt == nil {
+		t.Errorf("succeeded deliveries = %+v, want the delivery", got)
+	}
+	rec = s.do("GET", deliveries+"?status="+webhooks.StatusPending, token, nil)
+	if got := decode[[]WebhookDelivery](t, rec); len(got) != 0 {
+		t.Errorf("pending deliveries = %+v, want none", got)
+	}
+
+	rec = s.do("GET", delivery+"/attempts", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	attempts := decode[[]WebhookDeliveryAttempt](t, rec)
+	if len(attempts) != 2 {
+		t.Fatalf("attempts = %+v, want 2", attempts)
+	}
+	for i, want := range []int32{http.StatusServiceUnavailable, http.StatusOK} {
+		if got := attempts[i].StatusCode; got == nil || *got != want {
+			t.Errorf("attempt %d status = %v, want %d", i, got, want)
+		}
+	}
+
+	expectStatus(t, s.do("GET", deliveries+"?limit=0", token, nil), http.StatusBadRequest)
+	expectStatus(t, s.do("GET", deliveries, otherToken, nil), http.StatusForbidden)
+	expectStatus(t, s.do("GET", deliveries+"/not-a-uuid/attempts", token, nil), http.StatusBadRequest)
+	expectStatus(t, s.do("GET", deliveries+"/"+uuid.NewString()+"/attempts", token, nil), http.StatusNotFound)
+	expectStatus(t, s.do("POST", delivery+"/redeliver", otherToken, nil), http.StatusForbidden)
+}
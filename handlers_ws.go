@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/ghis9917/chirpy/internal/auth"
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	wsStatusTokenExpired   websocket.StatusCode = 4001
+	wsStatusSessionRevoked websocket.StatusCode = 4003
+)
+
+type wsSession struct {
+	cfg          *apiConfig
+	conn         *websocket.Conn
+	userID       uuid.UUID
+	clientID     string
+	tokenID      string
+	expiresAt    time.Time
+	refreshToken string
+
+	timeline      bool
+	authors       map[uuid.UUID]bool
+	threads       map[uuid.UUID]bool
+	notifications bool
+}
+
+func (cfg *apiConfig) handleWebSocket(w http.ResponseWriter, req *http.Request) {
+
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		token = req.URL.Query().Get("access_token")
+	}
+	if token == "" {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: "Could not find bearer token"})
+		return
+	}
+
+	claims, err := cfg.parseAccessToken(req.Context(), token)
+	if err != nil {
+		sendJSONResponse(w, http.StatusUnauthorized, jsonErr{Error: fmt.Sprintf("%s", err)})
+		return
+	}
+	if !claims.HasScope(SCOPE_READ) {
+		sendJSONResponse(w, http.StatusForbidden, jsonErr{Error: fmt.Sprintf("Token is missing the %s scope", SCOPE_READ)})
+		return
+	}
+
+	// The server's read and write timeouts stay on the connection after it
+	// is hijacked, so clear them; the session enforces its own.
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+
+	conn, err := websocket.Accept(w, req, nil)
+	if err != nil {
+		return
+	}
+	defer conn.CloseNow()
+	conn.SetReadLimit(WS_READ_LIMIT)
+
+	session := &wsSession{
+		cfg:       cfg,
+		conn:      conn,
+		userID:    claims.UserID,
+		clientID:  claims.ClientID,
+		tokenID:   claims.TokenID,
+		expiresAt: claims.ExpiresAt,
+		authors:   map[uuid.UUID]bool{},
+		threads:   map[uuid.UUID]bool{},
+	}
+
+	code, reason := session.run(req.Context())
+	conn.Close(code, reason)
+
+}
+
+func (s *wsSession) run(ctx context.Context) (websocket.StatusCode, string) {
+
+	chirps := s.cfg.stream.Chirps.Subscribe(STREAM_BUFFER_SIZE)
+	defer s.cfg.stream.Chirps.Unsubscribe(chirps)
+	notifications := s.cfg.stream.Notifications.Subscribe(STREAM_BUFFER_SIZE)
+	defer s.cfg.stream.Notifications.Unsubscribe(notifications)
+
+	// The reader stops once the caller closes the connection; done only
+	// unblocks it if we stop consuming messages first.
+	done := make(chan struct{})
+	defer close(done)
+	messages := make(chan wsClientMessage)
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			var msg wsClientMessage
+			if err := wsjson.Read(ctx, s.conn, &msg); err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case messages <- msg:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	expiry := time.NewTimer(time.Until(s.expiresAt))
+	defer expiry.Stop()
+	sessionCheck := time.NewTicker(WS_SESSION_CHECK)
+	defer sessionCheck.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return websocket.StatusGoingAway, "server shutting down"
+		case <-s.cfg.closing:
+			return websocket.StatusGoingAway, "server shutting down"
+		case err := <-readErr:
+			if status := websocket.CloseStatus(err); status != -1 {
+				return status, ""
+			}
+			return websocket.StatusUnsupportedData, "invalid message"
+		case msg := <-messages:
+			// Revoking the access token or refresh token the socket was
+			// opened with ends it by the next message, or the next check.
+			if !s.sessionValid(ctx) {
+				return wsStatusSessionRevoked, "session revoked"
+			}
+			if err := s.handleMessage(ctx, msg, expiry); err != nil {
+				s.write(ctx, wsServerMessage{Type: "error", Error: err.Error()})
+			}
+		case <-expiry.C:
+			return wsStatusTokenExpired, "token expired"
+		case <-sessionCheck.C:
+			if !s.sessionValid(ctx) {
+				return wsStatusSessionRevoked, "session revoked"
+			}
+		case event, ok := <-chirps.C:
+			if !ok {
+				return websocket.StatusTryAgainLater, "client fell behind"
+			}
+			if topic, id, match := s.matchChirp(event); match {
+				s.write(ctx, wsServerMessage{
+					Type:  "event",
+					Topic: topic,
+					ID:    id,
+					Event: event.Type,
+					Data: Chirp{
+						ID:        event.ChirpID,
+						CreatedAt: event.ChirpCreatedAt,
+						UpdatedAt: event.ChirpUpdatedAt,
+						Body:      event.Body,
+						UserID:    event.UserID,
+					},
+				})
+			}
+		case notification, ok := <-notifications.C:
+			if !ok {
+				return websocket.StatusTryAgainLater, "client fell behind"
+			}
+			if s.notifications && notification.UserID == s.userID {
+				s.write(ctx, wsServerMessage{
+					Type:  "event",
+					Topic: WS_TOPIC_NOTIFICATIONS,
+					Event: "notification.created",
+					Data:  toNotification(notification),
+				})
+			}
+		}
+	}
+
+}
+
+func (s *wsSession) handleMessage(ctx context.Context, msg wsClientMessage, expiry *time.Timer) error {
+
+	switch msg.Type {
+	case "ping":
+		s.write(ctx, wsServerMessage{Type: "pong"})
+		return nil
+	case "subscribe", "unsubscribe":
+		if err := s.setSubscription(msg.Topic, msg.ID, msg.Type == "subscribe"); err != nil {
+			return err
+		}
+		s.write(ctx, wsServerMessage{Type: msg.Type + "d", Topic: msg.Topic, ID: msg.ID})
+		return nil
+	case "refresh":
+		if err := s.refresh(ctx, msg); err != nil {
+			return err
+		}
+		expiry.Reset(time.Until(s.expiresAt))
+		return nil
+	}
+
+	return fmt.Errorf("unknown message type %q", msg.Type)
+}
+
+func (s *wsSession) setSubscription(topic, id string, on bool) error {
+
+	switch topic {
+	case WS_TOPIC_TIMELINE:
+		if id == "" {
+			s.timeline = on
+			return nil
+		}
+		authorUUID, err := uuid.Parse(id)
+		if err != nil {
+			return fmt.Errorf("Invalid UserID: %v", err)
+		}
+		setMember(s.authors, authorUUID, on)
+		return nil
+	case WS_TOPIC_THREAD:
+		chirpUUID, err := uuid.Parse(id)
+		if err != nil {
+			return fmt.Errorf("Invalid ChirpID: %v", err)
+		}
+		setMember(s.threads, chirpUUID, on)
+		return nil
+	case WS_TOPIC_NOTIFICATIONS:
+		s.notifications = on
+		return nil
+	}
+
+	return fmt.Errorf("unknown topic %q", topic)
+}
+
+// refresh accepts either a new access token or a refresh token. A refresh
+// token also binds the socket to that session so revoking it disconnects us.
+// Sockets opened by an OAuth client can only move to another of its access
+// tokens, which is held to the same scope as the handshake.
+func (s *wsSession) refresh(ctx context.Context, msg wsClientMessage) error {
+
+	if msg.RefreshToken != "" {
+		if s.clientID != "" {
+			return errors.New("token belongs to a different client")
+		}
+		token, err := s.cfg.db.GetRefreshToken(ctx, msg.RefreshToken)
+		if err != nil || token.UserID != s.userID || token.ClientID.Valid {
+			return errors.New("Token not found")
+		}
+		if token.RevokedAt.Valid || time.Now().After(token.ExpiresAt) {
+			return errors.New("Token has been revoked")
+		}
+
+		accessToken, err := auth.MakeJWT(s.userID, s.cfg.serverSecret, time.Hour)
+		if err != nil {
+			return err
+		}
+		msg.Token = accessToken
+		s.refreshToken = token.Token
+	}
+
+	claims, err := s.cfg.parseAccessToken(ctx, msg.Token)
+	if err != nil {
+		return err
+	}
+	if claims.UserID != s.userID {
+		return errors.New("token belongs to a different user")
+	}
+	if msg.RefreshToken == "" && claims.ClientID != s.clientID {
+		return errors.New("token belongs to a different client")
+	}
+	if !claims.HasScope(SCOPE_READ) {
+		return fmt.Errorf("Token is missing the %s scope", SCOPE_READ)
+	}
+	s.clientID = claims.ClientID
+	s.tokenID = claims.TokenID
+	expiresAt := claims.ExpiresAt
+	s.expiresAt = expiresAt
+
+	s.write(ctx, wsServerMessage{Type: "token", Token: msg.Token, ExpiresAt: &expiresAt})
+
+	return nil
+}
+
+func (s *wsSession) sessionValid(ctx context.Context) bool {
+
+	if s.clientID != "" {
+		grant, err := s.cfg.db.GetOAuthAccessToken(ctx, s.tokenID)
+		if err != nil || grant.RevokedAt.Valid {
+			return false
+		}
+	}
+
+	if s.refreshToken == "" {
+		return true
+	}
+
+	token, err := s.cfg.db.GetRefreshToken(ctx, s.refreshToken)
+	if err != nil {
+		return false
+	}
+
+	return !token.RevokedAt.Valid && time.Now().Before(token.ExpiresAt)
+}
+
+func (s *wsSession) matchChirp(event database.ChirpEvent) (string, string, bool) {
+
+	if s.threads[event.ChirpID] {
+		return WS_TOPIC_THREAD, event.ChirpID.String(), true
+	}
+	if s.authors[event.UserID] {
+		return WS_TOPIC_TIMELINE, event.UserID.String(), true
+	}
+	if s.timeline {
+		return WS_TOPIC_TIMELINE, "", true
+	}
+
+	return "", "", false
+}
+
+func (s *wsSession) write(ctx context.Context, msg wsServerMessage) {
+
+	ctx, cancel := context.WithTimeout(ctx, WS_WRITE_TIMEOUT)
+	defer cancel()
+
+	wsjson.Write(ctx, s.conn, msg)
+}
+
+func setMember(set map[uuid.UUID]bool, id uuid.UUID, on bool) {
+	if on {
+		set[id] = true
+	} else {
+		delete(set, id)
+	}
+}
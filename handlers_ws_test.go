@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/ghis9917/chirpy/internal/auth"
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// dialWebSocket opens /api/ws with token and returns the connection. It is
+// closed when the test ends.
+func (s *testServer) dialWebSocket(ctx context.Context, token string) *websocket.Conn {
+	s.t.Helper()
+
+	srv := httptest.NewServer(s.handler)
+	s.t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http")+"/api/ws?access_token="+token, nil)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	s.t.Cleanup(func() { conn.CloseNow() })
+
+	return conn
+}
+
+// exchange sends msg and returns the next message from the server.
+func exchange(t *testing.T, ctx context.Context, conn *websocket.Conn, msg wsClientMessage) wsServerMessage {
+	t.Helper()
+
+	if msg.Type != "" {
+		if err := wsjson.Write(ctx, conn, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var reply wsServerMessage
+	if err := wsjson.Read(ctx, conn, &reply); err != nil {
+		t.Fatal(err)
+	}
+
+	return reply
+}
+
+func TestWebSocketAuth(t *testing.T) {
+	s := newTestServer(t)
+	user, _ := s.createUser("alice@example.com")
+	followOnly, _ := s.oauthToken(user.ID, SCOPE_FOLLOW)
+
+	expectStatus(t, s.do("GET", "/api/ws", "", nil), http.StatusUnauthorized)
+	expectStatus(t, s.do("GET", "/api/ws?access_token=nope", "", nil), http.StatusUnauthorized)
+	expectStatus(t, s.do("GET", "/api/ws?access_token="+followOnly, "", nil), http.StatusForbidden)
+}
+
+func TestWebSocket(t *testing.T) {
+	s := newTestServer(t)
+	alice, token := s.createUser("alice@example.com")
+	bob, _ := s.createUser("bob@example.com")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn := s.dialWebSocket(ctx, token)
+
+	if got := exchange(t, ctx, conn, wsClientMessage{Type: "ping"}); got.Type != "pong" {
+		t.Errorf("ping reply = %+v, want pong", got)
+	}
+	if got := exchange(t, ctx, conn, wsClientMessage{Type: "dance"}); got.Type != "error" {
+		t.Errorf("unknown message reply = %+v, want error", got)
+	}
+	if got := exchange(t, ctx, conn, wsClientMessage{Type: "subscribe", Topic: WS_TOPIC_THREAD, ID: "nope"}); got.Type != "error" {
+		t.Errorf("invalid thread reply = %+v, want error", got)
+	}
+	if got := exchange(t, ctx, conn, wsClientMessage{Type: "subscribe", Topic: WS_TOPIC_TIMELINE, ID: bob.ID.String()}); got.Type != "subscribed" {
+		t.Fatalf("subscribe reply = %+v, want subscribed", got)
+	}
+
+	// Only chirps by followed authors are forwarded.
+	s.createChirp(alice.ID, "not followed")
+	s.createChirp(bob.ID, "followed")
+	for _, event := range s.chirpEvents() {
+		s.cfg.stream.Chirps.Publish(event)
+	}
+	got := exchange(t, ctx, conn, wsClientMessage{})
+	if got.Type != "event" || got.Topic != WS_TOPIC_TIMELINE || got.ID != bob.ID.String() || got.Event != "chirp.created" {
+		t.Fatalf("event = %+v, want bob's chirp on the timeline", got)
+	}
+
+	if got := exchange(t, ctx, conn, wsClientMessage{Type: "subscribe", Topic: WS_TOPIC_NOTIFICATIONS}); got.Type != "subscribed" {
+		t.Fatalf("subscribe reply = %+v, want subscribed", got)
+	}
+	for _, userID := range []uuid.UUID{bob.ID, alice.ID} {
+		s.cfg.stream.Notifications.Publish(database.Notification{ID: uuid.New(), UserID: userID, ActorID: bob.ID, Type: "mention"})
+	}
+	got = exchange(t, ctx, conn, wsClientMessage{})
+	if got.Topic != WS_TOPIC_NOTIFICATIONS || got.Event != "notification.created" {
+		t.Fatalf("event = %+v, want alice's notification", got)
+	}
+
+	refreshToken, err := s.db.CreateRefreshRoken(ctx, database.CreateRefreshRokenParams{Token: "ws-refresh-token", UserID: alice.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := exchange(t, ctx, conn, wsClientMessage{Type: "refresh", RefreshToken: "unknown"}); got.Type != "error" {
+		t.Errorf("refresh with unknown token reply = %+v, want error", got)
+	}
+	got = exchange(t, ctx, conn, wsClientMessage{Type: "refresh", RefreshToken: refreshToken.Token})
+	if got.Type != "token" || got.Token == "" || got.ExpiresAt == nil {
+		t.Errorf("refresh reply = %+v, want a new token", got)
+	}
+}
+
+func TestWebSocketShutdown(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.createUser("alice@example.com")
+	closing := make(chan struct{})
+	s.cfg.closing = closing
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn := s.dialWebSocket(ctx, token)
+
+	if got := exchange(t, ctx, conn, wsClientMessage{Type: "ping"}); got.Type != "pong" {
+		t.Fatalf("ping reply = %+v, want pong", got)
+	}
+	close(closing)
+
+	_, _, err := conn.Read(ctx)
+	if got := websocket.CloseStatus(err); got != websocket.StatusGoingAway {
+		t.Errorf("close status = %v, want %v", got, websocket.StatusGoingAway)
+	}
+}
+
+func TestWebSocketOAuthSession(t *testing.T) {
+	s := newTestServer(t)
+	user, _ := s.createUser("alice@example.com")
+	token, tokenID := s.oauthToken(user.ID, SCOPE_READ)
+	claims, err := auth.ParseJWT(token, testServerSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn := s.dialWebSocket(ctx, token)
+
+	followOnly, followOnlyID, err := auth.MakeScopedJWT(user.ID, testServerSecret, time.Hour, claims.ClientID, []string{SCOPE_FOLLOW})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.db.CreateOAuthAccessToken(
+		ctx,
+		database.CreateOAuthAccessTokenParams{
+			ID:        followOnlyID,
+			ClientID:  claims.ClientID,
+			UserID:    user.ID,
+			Scopes:    []string{SCOPE_FOLLOW},
+			ExpiresAt: time.Now().UTC().Add(time.Hour),
+		},
+	); err != nil {
+		t.Fatal(err)
+	}
+	if got := exchange(t, ctx, conn, wsClientMessage{Type: "refresh", Token: followOnly}); got.Type != "error" || !strings.Contains(got.Error, SCOPE_READ) {
+		t.Errorf("refresh without the read scope reply = %+v, want error", got)
+	}
+
+	refreshToken, err := s.db.CreateRefreshRoken(ctx, database.CreateRefreshRokenParams{Token: "ws-refresh-token", UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := exchange(t, ctx, conn, wsClientMessage{Type: "refresh", RefreshToken: refreshToken.Token}); got.Type != "error" {
+		t.Errorf("refresh with a first-party refresh token reply = %+v, want error", got)
+	}
+
+	if err := s.db.RevokeOAuthAccessToken(ctx, tokenID); err != nil {
+		t.Fatal(err)
+	}
+	if err := wsjson.Write(ctx, conn, wsClientMessage{Type: "ping"}); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = conn.Read(ctx)
+	if got := websocket.CloseStatus(err); got != wsStatusSessionRevoked {
+		t.Errorf("close status = %v, want %v after the access token was revoked", got, wsStatusSessionRevoked)
+	}
+}
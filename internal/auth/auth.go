@@ -1,16 +1,23 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/alexedwards/argon2id"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 )
 
 type TokenType string
@@ -19,20 +26,32 @@ const (
 	TokenTypeAccess TokenType = "chirpy-access"
 )
 
-func HashPassword(password string) (string, error) {
+var tracer = otel.Tracer("github.com/ghis9917/chirpy/internal/auth")
+
+// HashPassword and CheckPassword record a span each: argon2id is
+// deliberately slow and often dominates a login or sign-up.
+func HashPassword(ctx context.Context, password string) (string, error) {
+
+	_, span := tracer.Start(ctx, "auth.HashPassword")
+	defer span.End()
 
 	hash, err := argon2id.CreateHash(password, argon2id.DefaultParams)
 	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
 		return "", err
 	}
 
 	return hash, nil
 }
 
-func CheckPassword(password, hash string) (bool, error) {
+func CheckPassword(ctx context.Context, password, hash string) (bool, error) {
+
+	_, span := tracer.Start(ctx, "auth.CheckPassword")
+	defer span.End()
 
 	check, err := argon2id.ComparePasswordAndHash(password, hash)
 	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
 		return false, err
 	}
 
@@ -54,6 +73,99 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 	return token.SignedString(signingKey)
 }
 
+type scopedClaims struct {
+	jwt.RegisteredClaims
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+}
+
+// Claims describes a validated access token or personal API key.
+// First-party tokens from MakeJWT carry every scope.
+type Claims struct {
+	UserID    uuid.UUID
+	ClientID  string
+	APIKeyID  uuid.UUID
+	Scopes    []string
+	TokenID   string
+	ExpiresAt time.Time
+}
+
+// FirstParty reports whether the credential came from a password login
+// rather than an OAuth client or an API key.
+func (c Claims) FirstParty() bool {
+	return c.ClientID == "" && c.APIKeyID == uuid.Nil
+}
+
+func (c Claims) HasScope(scope string) bool {
+	return c.FirstParty() || slices.Contains(c.Scopes, scope)
+}
+
+func MakeScopedJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration, clientID string, scopes []string) (string, string, error) {
+
+	tokenID, err := MakeRefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	signingKey := []byte(tokenSecret)
+	token := jwt.NewWithClaims(
+		jwt.SigningMethodHS256,
+		scopedClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    string(TokenTypeAccess),
+				IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn).UTC()),
+				Subject:   userID.String(),
+				ID:        tokenID,
+			},
+			ClientID: clientID,
+			Scope:    strings.Join(scopes, " "),
+		},
+	)
+
+	signed, err := token.SignedString(signingKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	return signed, tokenID, nil
+}
+
+func ParseJWT(tokenString, tokenSecret string) (Claims, error) {
+
+	claimsStruct := scopedClaims{}
+	_, err := jwt.ParseWithClaims(
+		tokenString,
+		&claimsStruct,
+		func(token *jwt.Token) (interface{}, error) { return []byte(tokenSecret), nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+	)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	if claimsStruct.Issuer != string(TokenTypeAccess) {
+		return Claims{}, errors.New("invalid issuer")
+	}
+
+	userID, err := uuid.Parse(claimsStruct.Subject)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	claims := Claims{
+		UserID:   userID,
+		ClientID: claimsStruct.ClientID,
+		Scopes:   strings.Fields(claimsStruct.Scope),
+		TokenID:  claimsStruct.ID,
+	}
+	if claimsStruct.ExpiresAt != nil {
+		claims.ExpiresAt = claimsStruct.ExpiresAt.Time
+	}
+
+	return claims, nil
+}
+
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 
 	claimsStruct := jwt.RegisteredClaims{}
@@ -106,6 +218,28 @@ func MakeRefreshToken() (string, error) {
 	return hex.EncodeToString(randomData), nil
 }
 
+// HashToken returns a SHA-256 digest suitable for looking up high-entropy
+// tokens such as authorization codes without storing them in plain text.
+func HashToken(token string) string {
+
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyPKCE checks an RFC 7636 code verifier against its S256 challenge.
+func VerifyPKCE(verifier, challenge string) bool {
+
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
 func GetAPIKey(headers http.Header) (string, error) {
 
 	apiKey, hadApiKey := strings.CutPrefix(
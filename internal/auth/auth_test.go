@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -11,8 +12,8 @@ func TestCheckPasswordHash(t *testing.T) {
 	// First, we need to create some hashed passwords for testing
 	password1 := "correctPassword123!"
 	password2 := "anotherPassword456!"
-	hash1, _ := HashPassword(password1)
-	hash2, _ := HashPassword(password2)
+	hash1, _ := HashPassword(context.Background(), password1)
+	hash2, _ := HashPassword(context.Background(), password2)
 
 	tests := []struct {
 		name          string
@@ -60,7 +61,7 @@ func TestCheckPasswordHash(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			match, err := CheckPassword(tt.password, tt.hash)
+			match, err := CheckPassword(context.Background(), tt.password, tt.hash)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CheckPasswordHash() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -118,3 +119,59 @@ func TestValidateJWT(t *testing.T) {
 		})
 	}
 }
+
+func TestParseJWT(t *testing.T) {
+	userID := uuid.New()
+	firstParty, _ := MakeJWT(userID, "secret", time.Hour)
+	scoped, tokenID, _ := MakeScopedJWT(userID, "secret", time.Hour, "client", []string{"read"})
+
+	claims, err := ParseJWT(firstParty, "secret")
+	if err != nil {
+		t.Fatalf("ParseJWT() error = %v", err)
+	}
+	if claims.UserID != userID || !claims.HasScope("admin") {
+		t.Errorf("ParseJWT() first-party claims = %+v, want every scope", claims)
+	}
+
+	claims, err = ParseJWT(scoped, "secret")
+	if err != nil {
+		t.Fatalf("ParseJWT() error = %v", err)
+	}
+	if claims.ClientID != "client" || claims.TokenID != tokenID {
+		t.Errorf("ParseJWT() scoped claims = %+v", claims)
+	}
+	if !claims.HasScope("read") || claims.HasScope("write") {
+		t.Errorf("ParseJWT() scopes = %v, want only read", claims.Scopes)
+	}
+
+	if gotUserID, err := ValidateJWT(scoped, "secret"); err != nil || gotUserID != userID {
+		t.Errorf("ValidateJWT() on scoped token = %v, %v", gotUserID, err)
+	}
+}
+
+func TestClaimsHasScope(t *testing.T) {
+	apiKey := Claims{UserID: uuid.New(), APIKeyID: uuid.New(), Scopes: []string{"write"}}
+
+	if apiKey.FirstParty() {
+		t.Errorf("FirstParty() = true for an API key")
+	}
+	if !apiKey.HasScope("write") || apiKey.HasScope("read") {
+		t.Errorf("HasScope() on API key scopes %v", apiKey.Scopes)
+	}
+	if !(Claims{UserID: uuid.New()}).HasScope("admin") {
+		t.Errorf("HasScope() = false for first-party claims")
+	}
+}
+
+func TestVerifyPKCE(t *testing.T) {
+	// Example from RFC 7636 appendix B.
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	if !VerifyPKCE(verifier, challenge) {
+		t.Error("VerifyPKCE() rejected the RFC 7636 example")
+	}
+	if VerifyPKCE(verifier+"x", challenge) {
+		t.Error("VerifyPKCE() accepted a wrong verifier")
+	}
+}
@@ -0,0 +1,19 @@
+// Package backoff spaces out retries of work that keeps failing.
+package backoff
+
+import "time"
+
+// Exponential doubles the wait after each failed attempt, starting at base
+// after the first and capped at max.
+func Exponential(attempts int32, base, max time.Duration) time.Duration {
+
+	wait := base
+	for i := int32(1); i < attempts; i++ {
+		wait *= 2
+		if wait >= max {
+			return max
+		}
+	}
+
+	return wait
+}
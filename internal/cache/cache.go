@@ -0,0 +1,20 @@
+// Package cache stores short-lived copies of values that are expensive to
+// load, either in process or in a Redis-compatible server shared by every
+// instance.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache holds byte values under string keys until they expire or are
+// deleted. Implementations are safe for concurrent use.
+type Cache interface {
+	// Get reports whether key holds a value that has not expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	// Clear deletes every key this cache holds.
+	Clear(ctx context.Context) error
+}
@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := NewLRU(2)
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "a", []byte("1"), time.Minute)
+	c.Set(ctx, "b", []byte("2"), time.Minute)
+	// Reading a makes b the least recently used.
+	if got, ok, _ := c.Get(ctx, "a"); !ok || string(got) != "1" {
+		t.Fatalf("Get(a) = %q, %v, want 1", got, ok)
+	}
+	c.Set(ctx, "c", []byte("3"), time.Minute)
+
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Error("Get(b) found it, want it evicted")
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+
+	c.Set(ctx, "a", []byte("updated"), time.Second)
+	if got, _, _ := c.Get(ctx, "a"); string(got) != "updated" {
+		t.Errorf("Get(a) = %q, want updated", got)
+	}
+	now = now.Add(time.Second)
+	if _, ok, _ := c.Get(ctx, "a"); ok {
+		t.Error("Get(a) found it after its TTL, want it expired")
+	}
+
+	c.Delete(ctx, "c", "missing")
+	if _, ok, _ := c.Get(ctx, "c"); ok {
+		t.Error("Get(c) found it after Delete")
+	}
+
+	c.Set(ctx, "d", []byte("4"), time.Minute)
+	c.Clear(ctx)
+	if c.Len() != 0 {
+		t.Errorf("Len() after Clear = %d, want 0", c.Len())
+	}
+}
@@ -0,0 +1,267 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// RedisTimeout bounds each command when the context has no earlier
+// deadline, so a hung server slows requests down rather than stalling them.
+const RedisTimeout = time.Second
+
+// redisIdleConns is how many connections are kept open between commands.
+const redisIdleConns = 8
+
+// Redis is a cache in a server speaking the Redis protocol, so every
+// instance sees the same entries and invalidations. Only the handful of
+// commands the cache needs are implemented. Keys are namespaced with prefix,
+// which is also what Clear deletes.
+type Redis struct {
+	addr   string
+	prefix string
+	idle   chan *redisConn
+}
+
+var _ Cache = (*Redis)(nil)
+
+func NewRedis(addr, prefix string) *Redis {
+	return &Redis{
+		addr:   addr,
+		prefix: prefix,
+		idle:   make(chan *redisConn, redisIdleConns),
+	}
+}
+
+// RedisError is an error reply from the server.
+type RedisError string
+
+func (e RedisError) Error() string {
+	return "redis: " + string(e)
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+
+	reply, err := r.do(ctx, "GET", r.prefix+key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis: GET replied %T", reply)
+	}
+
+	return value, true, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := r.do(ctx, "SET", r.prefix+key, string(value), "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	return err
+}
+
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	args := []string{"DEL"}
+	for _, key := range keys {
+		args = append(args, r.prefix+key)
+	}
+	_, err := r.do(ctx, args...)
+
+	return err
+}
+
+// Clear walks the keyspace with SCAN rather than using FLUSHDB, so that a
+// server shared with other data keeps it.
+func (r *Redis) Clear(ctx context.Context) error {
+
+	cursor := "0"
+	for {
+		reply, err := r.do(ctx, "SCAN", cursor, "MATCH", r.prefix+"*", "COUNT", "100")
+		if err != nil {
+			return err
+		}
+		page, ok := reply.([]any)
+		if !ok || len(page) != 2 {
+			return fmt.Errorf("redis: SCAN replied %v", reply)
+		}
+		next, _ := page[0].([]byte)
+		found, _ := page[1].([]any)
+
+		if len(found) > 0 {
+			args := []string{"DEL"}
+			for _, key := range found {
+				k, _ := key.([]byte)
+				args = append(args, string(k))
+			}
+			if _, err := r.do(ctx, args...); err != nil {
+				return err
+			}
+		}
+
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return nil
+		}
+	}
+}
+
+// Ping checks that the server answers.
+func (r *Redis) Ping(ctx context.Context) error {
+	_, err := r.do(ctx, "PING")
+	return err
+}
+
+// Close closes the idle connections. Commands still running close theirs
+// when they finish.
+func (r *Redis) Close() error {
+
+	for {
+		select {
+		case conn := <-r.idle:
+			conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do sends one command and returns its reply: nil, a string for a status,
+// an int64, a []byte for a bulk string or a []any for an array.
+func (r *Redis) do(ctx context.Context, args ...string) (any, error) {
+
+	conn, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok || time.Until(deadline) > RedisTimeout {
+		deadline = time.Now().Add(RedisTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	reply, err := conn.roundTrip(args)
+	var redisErr RedisError
+	if err != nil && !errors.As(err, &redisErr) {
+		// The connection may be halfway through a reply.
+		conn.Close()
+		return nil, err
+	}
+
+	select {
+	case r.idle <- conn:
+	default:
+		conn.Close()
+	}
+
+	return reply, err
+}
+
+func (r *Redis) conn(ctx context.Context) (*redisConn, error) {
+
+	select {
+	case conn := <-r.idle:
+		return conn, nil
+	default:
+	}
+
+	var d net.Dialer
+	ctx, cancel := context.WithTimeout(ctx, RedisTimeout)
+	defer cancel()
+	c, err := d.DialContext(ctx, "tcp", r.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &redisConn{Conn: c, r: bufio.NewReader(c)}, nil
+}
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *redisConn) roundTrip(args []string) (any, error) {
+
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(arg))+"\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	if _, err := c.Write(buf); err != nil {
+		return nil, err
+	}
+
+	return readReply(c.r)
+}
+
+func readReply(r *bufio.Reader) (any, error) {
+
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, rest := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return rest, nil
+	case '-':
+		return nil, RedisError(rest)
+	case ':':
+		return strconv.ParseInt(rest, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(rest)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", rest)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(rest)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed array length %q", rest)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			// An error inside an array is a value, not a failed command.
+			item, err := readReply(r)
+			var redisErr RedisError
+			if errors.As(err, &redisErr) {
+				item = redisErr
+			} else if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
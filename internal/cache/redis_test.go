@@ -0,0 +1,198 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is a stand-in server implementing the commands Redis sends.
+type fakeRedis struct {
+	mu      sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+}
+
+func startFakeRedis(t *testing.T) (*fakeRedis, string) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	f := &fakeRedis{values: map[string]string{}, expires: map[string]time.Time{}}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+
+	return f, l.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, arg := range reply.([]any) {
+			args = append(args, string(arg.([]byte)))
+		}
+		if _, err := io.WriteString(conn, f.run(args)); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) run(args []string) string {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for key, at := range f.expires {
+		if !time.Now().Before(at) {
+			delete(f.values, key)
+			delete(f.expires, key)
+		}
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "GET":
+		v, ok := f.values[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(v)
+	case "SET":
+		f.values[args[1]] = args[2]
+		delete(f.expires, args[1])
+		if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+			ms, err := strconv.Atoi(args[4])
+			if err != nil || ms <= 0 {
+				return "-ERR invalid expire time in 'set' command\r\n"
+			}
+			f.expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		return "+OK\r\n"
+	case "DEL":
+		n := 0
+		for _, key := range args[1:] {
+			if _, ok := f.values[key]; ok {
+				delete(f.values, key)
+				delete(f.expires, key)
+				n++
+			}
+		}
+		return ":" + strconv.Itoa(n) + "\r\n"
+	case "SCAN":
+		// One page holds every match; the cursor is always finished.
+		var keys []string
+		for key := range f.values {
+			if ok, _ := path.Match(args[3], key); ok {
+				keys = append(keys, bulk(key))
+			}
+		}
+		return "*2\r\n" + bulk("0") + "*" + strconv.Itoa(len(keys)) + "\r\n" + strings.Join(keys, "")
+	}
+
+	return "-ERR unknown command '" + args[0] + "'\r\n"
+}
+
+func bulk(s string) string {
+	return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+}
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+	f, addr := startFakeRedis(t)
+	c := NewRedis(addr, "chirpy:")
+	defer c.Close()
+
+	if err := c.Ping(ctx); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	if _, ok, err := c.Get(ctx, "a"); ok || err != nil {
+		t.Errorf("Get(a) = %v, %v, want a miss", ok, err)
+	}
+
+	if err := c.Set(ctx, "a", []byte("line\r\nbreak"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok, err := c.Get(ctx, "a"); err != nil || !ok || string(got) != "line\r\nbreak" {
+		t.Errorf("Get(a) = %q, %v, %v, want the value set", got, ok, err)
+	}
+	if _, ok := f.values["chirpy:a"]; !ok {
+		t.Error("server has no chirpy:a, want keys prefixed")
+	}
+
+	if err := c.Set(ctx, "short", []byte("x"), 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, ok, _ := c.Get(ctx, "short"); ok {
+		t.Error("Get(short) found it after its TTL, want it expired")
+	}
+
+	if err := c.Delete(ctx, "a", "missing"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := c.Get(ctx, "a"); ok {
+		t.Error("Get(a) found it after Delete")
+	}
+
+	c.Set(ctx, "b", []byte("2"), time.Minute)
+	c.Set(ctx, "c", []byte("3"), time.Minute)
+	f.mu.Lock()
+	f.values["other:d"] = "kept"
+	f.mu.Unlock()
+	if err := c.Clear(ctx); err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	if len(f.values) != 1 || f.values["other:d"] != "kept" {
+		t.Errorf("server holds %v after Clear, want only keys outside the prefix", f.values)
+	}
+	f.mu.Unlock()
+
+	var redisErr RedisError
+	if err := c.Set(ctx, "a", []byte("x"), 0); !errors.As(err, &redisErr) {
+		t.Errorf("Set() with no TTL error = %v, want a RedisError", err)
+	}
+	if err := c.Ping(ctx); err != nil {
+		t.Errorf("Ping() after an error reply = %v, want the connection still usable", err)
+	}
+}
+
+func TestRedisUnreachable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	if _, _, err := NewRedis(addr, "").Get(context.Background(), "a"); err == nil {
+		t.Error("Get() from a closed port error = nil, want an error")
+	}
+}
@@ -0,0 +1,322 @@
+// Package compress encodes HTTP responses with zstd or gzip, whichever the
+// client prefers in Accept-Encoding.
+package compress
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Encodings lists the supported content codings, preferred first when the
+// client weighs them equally.
+var Encodings = []string{"zstd", "gzip"}
+
+// etagSeparator joins an entity tag and the coding applied to it. The file
+// server tags its precompressed files with "-", so a distinct separator
+// keeps the two from being confused.
+const etagSeparator = "+"
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var pools = map[string]*sync.Pool{
+	"zstd": {New: func() any {
+		// Only invalid options make NewWriter fail.
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return enc
+	}},
+	"gzip": {New: func() any {
+		return gzip.NewWriter(nil)
+	}},
+}
+
+// Middleware compresses responses of a compressible content type once they
+// reach minSize bytes, or as soon as the handler flushes, since a streamed
+// response is assumed to be large. Responses that are already encoded,
+// partial or bodiless pass through, as do protocol upgrades.
+//
+// Entity tags are per representation, so a compressed response's strong
+// ETag gets the coding appended, and the suffix is stripped from
+// conditional request headers before the handler compares them.
+func Middleware(minSize int, next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		// Byte ranges refer to the identity encoding.
+		encoding := ""
+		if req.Header.Get("Range") == "" {
+			encoding = Negotiate(req.Header.Get("Accept-Encoding"))
+		}
+		stripped := stripETags(req.Header, "If-None-Match")
+		stripped = stripETags(req.Header, "If-Match") || stripped
+
+		cw := &responseWriter{
+			ResponseWriter: w,
+			encoding:       encoding,
+			minSize:        minSize,
+			tagNotModified: stripped,
+		}
+		defer cw.close()
+
+		next.ServeHTTP(cw, req)
+	})
+
+}
+
+// Negotiate returns the coding of Encodings with the highest weight in an
+// Accept-Encoding header, or "" if the client accepts none of them.
+func Negotiate(header string) string {
+
+	weights := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		weights[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range Encodings {
+		q, ok := weights[encoding]
+		if !ok {
+			q = weights["*"]
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+
+	return best
+}
+
+// Compressible reports whether a content type is worth compressing: text,
+// JSON and XML, but not server-sent events, whose proxies and clients
+// expect each event as soon as it is flushed.
+func Compressible(contentType string) bool {
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+
+	switch mediaType {
+	case "application/json", "application/x-ndjson", "application/javascript", "application/xml", "image/svg+xml":
+		return true
+	}
+
+	return false
+}
+
+// stripETags removes the coding this package appends from every tag in a
+// conditional header and reports whether there were any.
+func stripETags(h http.Header, name string) bool {
+
+	value := h.Get(name)
+	if value == "" {
+		return false
+	}
+
+	stripped := false
+	tags := strings.Split(value, ",")
+	for i, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		for _, encoding := range Encodings {
+			if base, ok := strings.CutSuffix(tag, etagSeparator+encoding+`"`); ok {
+				tag = base + `"`
+				stripped = true
+				break
+			}
+		}
+		tags[i] = tag
+	}
+	h.Set(name, strings.Join(tags, ", "))
+
+	return stripped
+}
+
+// tagETag appends the coding to a strong entity tag. Weak tags already
+// allow for different encodings of the same content.
+func tagETag(h http.Header, encoding string) {
+
+	tag := h.Get("ETag")
+	if !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) || len(tag) < 2 {
+		return
+	}
+
+	h.Set("ETag", strings.TrimSuffix(tag, `"`)+etagSeparator+encoding+`"`)
+}
+
+// responseWriter holds the body back until it knows whether to compress
+// it: once minSize bytes are buffered, or the handler flushes or returns.
+type responseWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	// tagNotModified tags a 304 for a client that sent a compressed
+	// response's ETag, so it keeps matching its cached copy.
+	tagNotModified bool
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     encoder
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+
+	if w.status != 0 {
+		return
+	}
+	if status < http.StatusOK {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	w.status = status
+	if status == http.StatusNoContent || status == http.StatusNotModified {
+		w.decide(false)
+	}
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if !w.decided {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) < w.minSize {
+			return len(p), nil
+		}
+		w.decide(true)
+		return len(p), w.flushBuffer()
+	}
+
+	if w.enc != nil {
+		return w.enc.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush commits to compressing whatever the response's size, so streamed
+// responses are compressed as they go.
+func (w *responseWriter) Flush() {
+
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		w.decide(true)
+		w.flushBuffer()
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide writes the headers, encoding the body if big is set and the
+// response qualifies.
+func (w *responseWriter) decide(big bool) {
+
+	w.decided = true
+	h := w.Header()
+
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		// Sniff now: the server would otherwise sniff compressed bytes.
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if Compressible(h.Get("Content-Type")) && !strings.Contains(h.Get("Vary"), "Accept-Encoding") {
+		h.Add("Vary", "Accept-Encoding")
+	}
+
+	if w.status == http.StatusNotModified && w.tagNotModified && w.encoding != "" {
+		tagETag(h, w.encoding)
+	}
+
+	if big && w.encoding != "" && w.status != http.StatusPartialContent && h.Get("Content-Encoding") == "" && Compressible(h.Get("Content-Type")) {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", w.encoding)
+		tagETag(h, w.encoding)
+		w.enc = pools[w.encoding].Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+func (w *responseWriter) flushBuffer() error {
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+
+	return err
+}
+
+func (w *responseWriter) close() {
+
+	if w.status == 0 {
+		// The handler wrote nothing; let the server send its default.
+		return
+	}
+	if !w.decided {
+		w.decide(len(w.buf) >= w.minSize)
+		w.flushBuffer()
+	}
+	if w.enc != nil {
+		w.enc.Close()
+		w.enc.Reset(nil)
+		pools[w.encoding].Put(w.enc)
+		w.enc = nil
+	}
+
+}
@@ -0,0 +1,202 @@
+package compress
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: ""},
+		{header: "gzip", want: "gzip"},
+		{header: "gzip, zstd", want: "zstd"},
+		{header: "zstd;q=0.5, gzip", want: "gzip"},
+		{header: "zstd;q=0, gzip;q=0", want: ""},
+		{header: "*", want: "zstd"},
+		{header: "*;q=0.1, zstd;q=0", want: "gzip"},
+		{header: "br, deflate", want: ""},
+		{header: "GZIP;q=0.8", want: "gzip"},
+	}
+
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCompressible(t *testing.T) {
+	for contentType, want := range map[string]bool{
+		"application/json":         true,
+		"application/x-ndjson":     true,
+		"text/csv; charset=utf-8":  true,
+		"application/problem+json": true,
+		"text/event-stream":        false,
+		"image/png":                false,
+		"application/octet-stream": false,
+		"":                         false,
+	} {
+		if got := Compressible(contentType); got != want {
+			t.Errorf("Compressible(%q) = %v, want %v", contentType, got, want)
+		}
+	}
+}
+
+func serve(h http.Handler, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for i := 0; i < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	var r io.Reader = rec.Body
+	switch rec.Header().Get("Content-Encoding") {
+	case "gzip":
+		gr, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gr
+	case "zstd":
+		zr, err := zstd.NewReader(rec.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	body, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestMiddleware(t *testing.T) {
+	large := strings.Repeat(`{"body":"hello world"}`, 100)
+
+	handler := func(contentType, body string) http.Handler {
+		return Middleware(1024, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if contentType != "" {
+				w.Header().Set("Content-Type", contentType)
+			}
+			w.Header().Set("ETag", `"abc"`)
+			w.Header().Set("Content-Length", "1")
+			// Written in pieces to cross the threshold mid-body.
+			io.WriteString(w, body[:len(body)/2])
+			io.WriteString(w, body[len(body)/2:])
+		}))
+	}
+
+	tests := []struct {
+		name         string
+		contentType  string
+		body         string
+		accept       string
+		wantEncoding string
+		wantETag     string
+	}{
+		{name: "zstd preferred", contentType: "application/json", body: large, accept: "gzip, zstd", wantEncoding: "zstd", wantETag: `"abc+zstd"`},
+		{name: "gzip", contentType: "application/json", body: large, accept: "gzip", wantEncoding: "gzip", wantETag: `"abc+gzip"`},
+		{name: "not accepted", contentType: "application/json", body: large, wantETag: `"abc"`},
+		{name: "below threshold", contentType: "application/json", body: `{"a":1}`, accept: "gzip", wantETag: `"abc"`},
+		{name: "not compressible", contentType: "image/png", body: large, accept: "gzip", wantETag: `"abc"`},
+		{name: "sniffed", body: "<html>" + large, accept: "gzip", wantEncoding: "gzip", wantETag: `"abc+gzip"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(handler(tt.contentType, tt.body), "Accept-Encoding", tt.accept)
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got := rec.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("ETag = %q, want %q", got, tt.wantETag)
+			}
+			if tt.wantEncoding != "" && rec.Header().Get("Content-Length") != "" {
+				t.Error("Content-Length kept on a compressed response")
+			}
+			if got := decodeBody(t, rec); got != tt.body {
+				t.Errorf("body = %q, want %q", got, tt.body)
+			}
+		})
+	}
+}
+
+func TestMiddlewarePassThrough(t *testing.T) {
+	large := strings.Repeat("a", 2048)
+
+	precompressed := Middleware(0, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Encoding", "br")
+		io.WriteString(w, large)
+	}))
+	if rec := serve(precompressed, "Accept-Encoding", "gzip"); rec.Header().Get("Content-Encoding") != "br" || rec.Body.String() != large {
+		t.Errorf("already encoded response = %q, want it untouched", rec.Header().Get("Content-Encoding"))
+	}
+
+	ranged := Middleware(0, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, large)
+	}))
+	if rec := serve(ranged, "Accept-Encoding", "gzip", "Range", "bytes=0-1"); rec.Header().Get("Content-Encoding") != "" {
+		t.Error("range request compressed, want the identity encoding")
+	}
+
+	notModified := Middleware(0, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if got := req.Header.Get("If-None-Match"); got != `"old", "abc"` {
+			t.Errorf("handler saw If-None-Match %q, want the coding stripped", got)
+		}
+		w.Header().Set("ETag", `"abc"`)
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	rec := serve(notModified, "Accept-Encoding", "gzip", "If-None-Match", `"old", "abc+gzip"`)
+	if rec.Code != http.StatusNotModified || rec.Header().Get("ETag") != `"abc+gzip"` || rec.Body.Len() != 0 {
+		t.Errorf("304 = %d, ETag %q, body %q, want the compressed tag and no body", rec.Code, rec.Header().Get("ETag"), rec.Body.String())
+	}
+}
+
+func TestMiddlewareFlush(t *testing.T) {
+	h := Middleware(1024, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		io.WriteString(w, "{\"n\":1}\n")
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush() error = %v", err)
+		}
+		io.WriteString(w, "{\"n\":2}\n")
+	}))
+
+	rec := serve(h, "Accept-Encoding", "gzip")
+	if !rec.Flushed || rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("flushed = %v, Content-Encoding = %q, want a flushed gzip stream", rec.Flushed, rec.Header().Get("Content-Encoding"))
+	}
+	if got := decodeBody(t, rec); got != "{\"n\":1}\n{\"n\":2}\n" {
+		t.Errorf("body = %q", got)
+	}
+
+	events := Middleware(0, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: hi\n\n")
+		http.NewResponseController(w).Flush()
+	}))
+	if rec := serve(events, "Accept-Encoding", "gzip"); rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != "data: hi\n\n" {
+		t.Error("event stream compressed, want it passed through")
+	}
+}
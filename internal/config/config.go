@@ -0,0 +1,453 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[REDACTED]"
+
+type Config struct {
+	Server   Server         `yaml:"server"`
+	Database Database       `yaml:"database"`
+	Cache    Cache          `yaml:"cache"`
+	Secrets  Secrets        `yaml:"secrets"`
+	Chirps   Chirps         `yaml:"chirps"`
+	Workers  Workers        `yaml:"workers"`
+	Log      Log            `yaml:"log"`
+	Tracing  Tracing        `yaml:"tracing"`
+	OIDC     []OIDCProvider `yaml:"oidc"`
+
+	// PrintConfig is only ever set by the --print-config flag.
+	PrintConfig bool `yaml:"-"`
+}
+
+type Server struct {
+	Port              int           `yaml:"port"`
+	FilepathRoot      string        `yaml:"filepath_root"`
+	Platform          string        `yaml:"platform"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes"`
+	// ShutdownDrain is how long we keep serving after reporting not ready,
+	// giving load balancers time to stop routing to us.
+	ShutdownDrain   time.Duration `yaml:"shutdown_drain"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+type Database struct {
+	URL             string        `yaml:"url"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+	// AutoMigrate applies pending migrations before the server starts.
+	AutoMigrate bool `yaml:"auto_migrate"`
+}
+
+type Cache struct {
+	// Backend is none, memory or redis. The memory cache is per instance,
+	// so with several instances a write is only seen by the others once
+	// their copy expires; use redis to share one.
+	Backend string `yaml:"backend"`
+	// RedisAddr is the host:port of a server speaking the Redis protocol.
+	RedisAddr string `yaml:"redis_addr"`
+	// Size and TTL are ignored, and not validated, when Backend is none.
+	Size int           `yaml:"size"`
+	TTL  time.Duration `yaml:"ttl"`
+}
+
+type Secrets struct {
+	ServerSecret string `yaml:"server_secret"`
+	PolkaKey     string `yaml:"polka_key"`
+}
+
+type Chirps struct {
+	MaxLength    int      `yaml:"max_length"`
+	ProfaneWords []string `yaml:"profane_words"`
+}
+
+type Workers struct {
+	SchedulerInterval       time.Duration `yaml:"scheduler_interval"`
+	WebhookDispatchInterval time.Duration `yaml:"webhook_dispatch_interval"`
+	StreamRetention         time.Duration `yaml:"stream_retention"`
+}
+
+type Log struct {
+	// Level is one of debug, info, warn or error.
+	Level string `yaml:"level"`
+}
+
+// SlogLevel parses Level; Validate rejects values it cannot parse.
+func (l Log) SlogLevel() (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(l.Level))
+	return level, err
+}
+
+type Tracing struct {
+	// Exporter is none, stdout or otlp.
+	Exporter string `yaml:"exporter"`
+	// Endpoint is the OTLP/HTTP collector URL; OTEL_EXPORTER_OTLP_ENDPOINT
+	// is used when it is empty.
+	Endpoint    string  `yaml:"endpoint"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+type OIDCProvider struct {
+	Name         string `yaml:"name"`
+	Issuer       string `yaml:"issuer"`
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+	RedirectURL  string `yaml:"redirect_url"`
+}
+
+func Default() Config {
+	return Config{
+		Server: Server{
+			Port:              8080,
+			FilepathRoot:      ".",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			MaxHeaderBytes:    1 << 20,
+			ShutdownDrain:     5 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Database: Database{
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		Cache: Cache{
+			Backend: "memory",
+			Size:    10000,
+			TTL:     time.Minute,
+		},
+		Chirps: Chirps{
+			MaxLength:    140,
+			ProfaneWords: []string{"kerfuffle", "sharbert", "fornax"},
+		},
+		Workers: Workers{
+			SchedulerInterval:       10 * time.Second,
+			WebhookDispatchInterval: 5 * time.Second,
+			StreamRetention:         24 * time.Hour,
+		},
+		Log: Log{Level: "info"},
+		Tracing: Tracing{
+			Exporter:    "none",
+			ServiceName: "chirpy",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// Load parses the configuration and validates it unless it is only going
+// to be printed.
+func Load(args []string, getenv func(string) string) (Config, error) {
+
+	cfg, err := Parse(args, getenv)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if cfg.PrintConfig {
+		return cfg, nil
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// Parse builds the configuration from defaults, then the YAML file named by
+// --config or CHIRPY_CONFIG, then the environment, then command line flags,
+// each overriding the one before. The file must be YAML with a .yaml or .yml
+// extension; anything else is rejected rather than misread. Commands that only need part of it, like
+// migrate, check what they use themselves.
+func Parse(args []string, getenv func(string) string) (Config, error) {
+
+	cfg := Default()
+
+	fs := flag.NewFlagSet("chirpy", flag.ContinueOnError)
+	path := fs.String("config", getenv("CHIRPY_CONFIG"), "path to a YAML (.yaml or .yml) config file")
+	port := fs.Int("port", 0, "port to listen on")
+	root := fs.String("root", "", "directory to serve /app from")
+	platform := fs.String("platform", "", "platform name; dev enables /admin/reset")
+	fs.BoolVar(&cfg.PrintConfig, "print-config", false, "print the effective config with secrets redacted and exit")
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	if *path != "" {
+		if ext := strings.ToLower(filepath.Ext(*path)); ext != ".yaml" && ext != ".yml" {
+			return Config{}, fmt.Errorf("config file %s: only YAML (.yaml, .yml) is supported", *path)
+		}
+		data, err := os.ReadFile(*path)
+		if err != nil {
+			return Config{}, err
+		}
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return Config{}, fmt.Errorf("parsing %s: %w", *path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(getenv); err != nil {
+		return Config{}, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			cfg.Server.Port = *port
+		case "root":
+			cfg.Server.FilepathRoot = *root
+		case "platform":
+			cfg.Server.Platform = *platform
+		}
+	})
+
+	return cfg, nil
+}
+
+func (c *Config) applyEnv(getenv func(string) string) error {
+
+	setString(&c.Server.FilepathRoot, getenv("FILEPATH_ROOT"))
+	setString(&c.Server.Platform, getenv("PLATFORM"))
+	setString(&c.Database.URL, getenv("DB_URL"))
+	setString(&c.Secrets.ServerSecret, getenv("SERVER_SECRET"))
+	setString(&c.Secrets.PolkaKey, getenv("POLKA_KEY"))
+	setString(&c.Cache.Backend, getenv("CACHE_BACKEND"))
+	setString(&c.Cache.RedisAddr, getenv("CACHE_REDIS_ADDR"))
+	setString(&c.Log.Level, getenv("LOG_LEVEL"))
+	setString(&c.Tracing.Exporter, getenv("TRACING_EXPORTER"))
+	setString(&c.Tracing.Endpoint, getenv("TRACING_ENDPOINT"))
+
+	if v := getenv("PORT"); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("PORT: %w", err)
+		}
+		c.Server.Port = port
+	}
+
+	for name, n := range map[string]*int{
+		"CHIRP_MAX_LENGTH":  &c.Chirps.MaxLength,
+		"DB_MAX_OPEN_CONNS": &c.Database.MaxOpenConns,
+		"DB_MAX_IDLE_CONNS": &c.Database.MaxIdleConns,
+		"CACHE_SIZE":        &c.Cache.Size,
+	} {
+		if v := getenv(name); v != "" {
+			parsed, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			*n = parsed
+		}
+	}
+
+	if v := getenv("TRACING_SAMPLE_RATIO"); v != "" {
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("TRACING_SAMPLE_RATIO: %w", err)
+		}
+		c.Tracing.SampleRatio = ratio
+	}
+
+	if v := getenv("DB_AUTO_MIGRATE"); v != "" {
+		auto, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("DB_AUTO_MIGRATE: %w", err)
+		}
+		c.Database.AutoMigrate = auto
+	}
+
+	if v := getenv("CHIRP_PROFANE_WORDS"); v != "" {
+		c.Chirps.ProfaneWords = splitList(v)
+	}
+
+	for name, d := range map[string]*time.Duration{
+		"SCHEDULER_INTERVAL":        &c.Workers.SchedulerInterval,
+		"WEBHOOK_DISPATCH_INTERVAL": &c.Workers.WebhookDispatchInterval,
+		"STREAM_RETENTION":          &c.Workers.StreamRetention,
+		"CACHE_TTL":                 &c.Cache.TTL,
+		"SHUTDOWN_DRAIN":            &c.Server.ShutdownDrain,
+		"SHUTDOWN_TIMEOUT":          &c.Server.ShutdownTimeout,
+	} {
+		if v := getenv(name); v != "" {
+			parsed, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			*d = parsed
+		}
+	}
+
+	// OIDC_PROVIDERS=google reads OIDC_GOOGLE_ISSUER, OIDC_GOOGLE_CLIENT_ID,
+	// OIDC_GOOGLE_CLIENT_SECRET and OIDC_GOOGLE_REDIRECT_URL, replacing any
+	// providers from the file.
+	if v := getenv("OIDC_PROVIDERS"); v != "" {
+		c.OIDC = nil
+		for _, name := range splitList(v) {
+			prefix := "OIDC_" + strings.ToUpper(name) + "_"
+			c.OIDC = append(c.OIDC, OIDCProvider{
+				Name:         name,
+				Issuer:       getenv(prefix + "ISSUER"),
+				ClientID:     getenv(prefix + "CLIENT_ID"),
+				ClientSecret: getenv(prefix + "CLIENT_SECRET"),
+				RedirectURL:  getenv(prefix + "REDIRECT_URL"),
+			})
+		}
+	}
+
+	return nil
+}
+
+// Validate reports every problem at once so a bad deploy can be fixed in
+// one go.
+func (c Config) Validate() error {
+
+	var errs []error
+	if c.Database.URL == "" {
+		errs = append(errs, errors.New("database url is required (DB_URL)"))
+	}
+	if c.Secrets.ServerSecret == "" {
+		errs = append(errs, errors.New("server secret is required (SERVER_SECRET)"))
+	}
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port %d is out of range", c.Server.Port))
+	}
+	if c.Server.FilepathRoot == "" {
+		errs = append(errs, errors.New("filepath root is required"))
+	}
+	if c.Server.ReadTimeout <= 0 || c.Server.ReadHeaderTimeout <= 0 || c.Server.WriteTimeout <= 0 || c.Server.IdleTimeout <= 0 {
+		errs = append(errs, errors.New("server timeouts must be positive"))
+	}
+	if c.Server.MaxHeaderBytes < 1 {
+		errs = append(errs, errors.New("max header bytes must be positive"))
+	}
+	if c.Server.ShutdownDrain < 0 || c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdown drain must not be negative and shutdown timeout must be positive"))
+	}
+	if c.Database.MaxOpenConns < 1 || c.Database.MaxIdleConns < 0 || c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		errs = append(errs, errors.New("database pool needs max_open_conns >= 1 and 0 <= max_idle_conns <= max_open_conns"))
+	}
+	if !slices.Contains([]string{"none", "memory", "redis"}, c.Cache.Backend) {
+		errs = append(errs, fmt.Errorf("cache backend %q must be none, memory or redis", c.Cache.Backend))
+	}
+	if c.Cache.Backend == "redis" && c.Cache.RedisAddr == "" {
+		errs = append(errs, errors.New("cache redis addr is required for the redis backend (CACHE_REDIS_ADDR)"))
+	}
+	if c.Cache.Backend != "none" && (c.Cache.Size < 1 || c.Cache.TTL < time.Millisecond) {
+		errs = append(errs, errors.New("cache size must be positive and cache ttl at least 1ms"))
+	}
+	if c.Chirps.MaxLength < 1 {
+		errs = append(errs, errors.New("chirp max length must be positive"))
+	}
+	if c.Workers.SchedulerInterval <= 0 || c.Workers.WebhookDispatchInterval <= 0 || c.Workers.StreamRetention <= 0 {
+		errs = append(errs, errors.New("worker intervals must be positive"))
+	}
+	if _, err := c.Log.SlogLevel(); err != nil {
+		errs = append(errs, fmt.Errorf("log level: %w", err))
+	}
+	if !slices.Contains([]string{"none", "stdout", "otlp"}, c.Tracing.Exporter) {
+		errs = append(errs, fmt.Errorf("tracing exporter %q must be none, stdout or otlp", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing sample ratio must be between 0 and 1"))
+	}
+	for _, p := range c.OIDC {
+		if p.Name == "" || p.Issuer == "" || p.ClientID == "" || p.RedirectURL == "" {
+			errs = append(errs, fmt.Errorf("oidc provider %q needs a name, issuer, client id and redirect url", p.Name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Redacted returns a copy that is safe to log or print.
+func (c Config) Redacted() Config {
+
+	c.Database.URL = redactURL(c.Database.URL)
+	c.Secrets.ServerSecret = redact(c.Secrets.ServerSecret)
+	c.Secrets.PolkaKey = redact(c.Secrets.PolkaKey)
+
+	providers := make([]OIDCProvider, len(c.OIDC))
+	for i, p := range c.OIDC {
+		p.ClientSecret = redact(p.ClientSecret)
+		providers[i] = p
+	}
+	c.OIDC = providers
+
+	return c
+}
+
+// String renders the redacted config as YAML, so printing a Config by
+// accident never leaks secrets.
+func (c Config) String() string {
+
+	out, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return fmt.Sprintf("config: %s", err)
+	}
+
+	return string(out)
+}
+
+func (c Config) Addr() string {
+	return ":" + strconv.Itoa(c.Server.Port)
+}
+
+func redact(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// redactURL keeps a database URL readable but hides its password.
+func redactURL(s string) string {
+
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" {
+		return redact(s)
+	}
+
+	return u.Redacted()
+}
+
+func setString(dst *string, v string) {
+	if v != "" {
+		*dst = v
+	}
+}
+
+func splitList(v string) []string {
+
+	items := []string{}
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
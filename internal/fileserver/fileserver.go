@@ -0,0 +1,289 @@
+package fileserver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Options struct {
+	Allow      []string
+	Private    []string
+	SigningKey []byte
+	MaxAge     time.Duration
+}
+
+type Server struct {
+	root       *os.Root
+	allow      []string
+	private    []string
+	signingKey []byte
+	maxAge     time.Duration
+}
+
+var encodings = []struct {
+	name string
+	ext  string
+}{
+	{name: "br", ext: ".br"},
+	{name: "gzip", ext: ".gz"},
+}
+
+var ErrInvalidSignature = errors.New("invalid or expired signature")
+
+func New(dir string, opts Options) (*Server, error) {
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	maxAge := opts.MaxAge
+	if maxAge == 0 {
+		maxAge = 24 * time.Hour
+	}
+
+	return &Server{
+		root:       root,
+		allow:      cleanPrefixes(opts.Allow),
+		private:    cleanPrefixes(opts.Private),
+		signingKey: opts.SigningKey,
+		maxAge:     maxAge,
+	}, nil
+}
+
+func (s *Server) Close() error {
+	return s.root.Close()
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name, ok := s.resolve(req.URL.Path)
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	private := hasPrefix(name, s.private)
+	if private {
+		if err := s.Verify(name, req.URL.Query()); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
+	info, err := s.root.Stat(name)
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+	if info.IsDir() {
+		name = path.Join(name, "index.html")
+		if info, err = s.root.Stat(name); err != nil || info.IsDir() {
+			http.NotFound(w, req)
+			return
+		}
+	}
+
+	servedName, encoding := s.negotiate(name, req)
+	f, err := s.root.Open(servedName)
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+	defer f.Close()
+
+	servedInfo, err := f.Stat()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	h := w.Header()
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		h.Set("Content-Type", ctype)
+	}
+	h.Set("Vary", "Accept-Encoding")
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("ETag", etag(servedInfo, encoding))
+	h.Set("Cache-Control", s.cacheControl(name, private))
+	if encoding != "" {
+		h.Set("Content-Encoding", encoding)
+	}
+
+	http.ServeContent(w, req, name, servedInfo.ModTime(), f)
+}
+
+// resolve maps a request path onto a file name relative to the root, refusing
+// anything outside the allow-list or that touches a dotfile.
+func (s *Server) resolve(urlPath string) (string, bool) {
+
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if name == "" {
+		name = "index.html"
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") {
+			return "", false
+		}
+	}
+
+	if !hasPrefix(name, s.allow) {
+		return "", false
+	}
+
+	return name, true
+}
+
+func (s *Server) negotiate(name string, req *http.Request) (string, string) {
+
+	// Byte ranges refer to the identity encoding, so media seeking always
+	// gets the original file.
+	if req.Header.Get("Range") != "" {
+		return name, ""
+	}
+
+	accept := req.Header.Get("Accept-Encoding")
+	for _, enc := range encodings {
+		if !acceptsEncoding(accept, enc.name) {
+			continue
+		}
+		if info, err := s.root.Stat(name + enc.ext); err == nil && !info.IsDir() {
+			return name + enc.ext, enc.name
+		}
+	}
+
+	return name, ""
+}
+
+func (s *Server) cacheControl(name string, private bool) string {
+
+	if private {
+		return "private, no-store"
+	}
+
+	switch path.Ext(name) {
+	case ".html", "":
+		return "no-cache"
+	}
+
+	return fmt.Sprintf("public, max-age=%d", int(s.maxAge.Seconds()))
+}
+
+func (s *Server) Sign(name string, expiresAt time.Time) string {
+
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	values := url.Values{}
+	values.Set("expires", expires)
+	values.Set("sig", s.signature(name, expires))
+
+	return "/" + name + "?" + values.Encode()
+}
+
+func (s *Server) Verify(name string, query url.Values) error {
+
+	if len(s.signingKey) == 0 {
+		return ErrInvalidSignature
+	}
+
+	expires := query.Get("expires")
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().After(time.Unix(unix, 0)) {
+		return ErrInvalidSignature
+	}
+
+	got, err := hex.DecodeString(query.Get("sig"))
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	want, _ := hex.DecodeString(s.signature(name, expires))
+	if !hmac.Equal(got, want) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func (s *Server) IsPrivate(name string) bool {
+	return hasPrefix(strings.TrimPrefix(path.Clean("/"+name), "/"), s.private)
+}
+
+func (s *Server) signature(name, expires string) string {
+
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(name))
+	mac.Write([]byte{0})
+	mac.Write([]byte(expires))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func etag(info fs.FileInfo, encoding string) string {
+
+	tag := strconv.FormatInt(info.ModTime().UnixNano(), 36) + "-" + strconv.FormatInt(info.Size(), 36)
+	if encoding != "" {
+		tag += "-" + encoding
+	}
+
+	return `"` + tag + `"`
+}
+
+func acceptsEncoding(header, encoding string) bool {
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+func cleanPrefixes(prefixes []string) []string {
+
+	cleaned := make([]string, 0, len(prefixes))
+	for _, p := range prefixes {
+		cleaned = append(cleaned, strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(p)), "/"))
+	}
+
+	return cleaned
+}
+
+func hasPrefix(name string, prefixes []string) bool {
+
+	for _, p := range prefixes {
+		if p == "" || name == p || strings.HasPrefix(name, p+"/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,154 @@
+package fileserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T) *Server {
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.html":                  "<html></html>",
+		".env":                        "SERVER_SECRET=oops",
+		"sql/schema/001_users.sql":    "CREATE TABLE users;",
+		"assets/app.js":               "console.log('hello')",
+		"assets/app.js.gz":            "gzipped",
+		"media/public/clip.mp4":       "0123456789",
+		"media/private/user/note.txt": "secret",
+	}
+	for name, content := range files {
+		full := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := New(dir, Options{
+		Allow:      []string{"index.html", "assets", "media/public", "media/private"},
+		Private:    []string{"media/private"},
+		SigningKey: []byte("key"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func TestServeHTTP(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name       string
+		path       string
+		headers    map[string]string
+		wantStatus int
+		wantBody   string
+		wantHeader map[string]string
+	}{
+		{
+			name:       "Index",
+			path:       "/",
+			wantStatus: http.StatusOK,
+			wantBody:   "<html></html>",
+			wantHeader: map[string]string{"Cache-Control": "no-cache"},
+		},
+		{
+			name:       "Dotfile",
+			path:       "/.env",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Outside allow-list",
+			path:       "/sql/schema/001_users.sql",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Traversal",
+			path:       "/assets/../.env",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Precompressed",
+			path:       "/assets/app.js",
+			headers:    map[string]string{"Accept-Encoding": "br, gzip"},
+			wantStatus: http.StatusOK,
+			wantBody:   "gzipped",
+			wantHeader: map[string]string{"Content-Encoding": "gzip", "Cache-Control": "public, max-age=86400"},
+		},
+		{
+			name:       "Range",
+			path:       "/media/public/clip.mp4",
+			headers:    map[string]string{"Range": "bytes=2-4"},
+			wantStatus: http.StatusPartialContent,
+			wantBody:   "234",
+		},
+		{
+			name:       "Unsigned private media",
+			path:       "/media/private/user/note.txt",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Signed private media",
+			path:       s.Sign("media/private/user/note.txt", time.Now().Add(time.Minute)),
+			wantStatus: http.StatusOK,
+			wantBody:   "secret",
+			wantHeader: map[string]string{"Cache-Control": "private, no-store"},
+		},
+		{
+			name:       "Expired private media",
+			path:       s.Sign("media/private/user/note.txt", time.Now().Add(-time.Minute)),
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("ServeHTTP() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("ServeHTTP() body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+			for k, v := range tt.wantHeader {
+				if got := rec.Header().Get(k); got != v {
+					t.Errorf("ServeHTTP() header %s = %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestConditionalRequest(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/assets/app.js", nil))
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("ServeHTTP() missing ETag")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/assets/app.js", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("ServeHTTP() status = %v, want %v", rec.Code, http.StatusNotModified)
+	}
+}
@@ -0,0 +1,158 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Checker is implemented by anything readiness depends on. Check should
+// return promptly once ctx is done.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type checkFunc struct {
+	name  string
+	check func(context.Context) error
+}
+
+func (c checkFunc) Name() string                    { return c.name }
+func (c checkFunc) Check(ctx context.Context) error { return c.check(ctx) }
+
+// CheckFunc adapts a function to a Checker.
+func CheckFunc(name string, check func(context.Context) error) Checker {
+	return checkFunc{name: name, check: check}
+}
+
+type Component struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components"`
+}
+
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+type Registry struct {
+	timeout time.Duration
+
+	mu       sync.RWMutex
+	checkers []Checker
+}
+
+// NewRegistry returns a registry that gives each check at most timeout to
+// finish.
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{timeout: timeout}
+}
+
+func (r *Registry) Register(c Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkers = append(r.checkers, c)
+}
+
+// Run executes every check concurrently. The report is only OK when all of
+// them pass.
+func (r *Registry) Run(ctx context.Context) Report {
+
+	r.mu.RLock()
+	checkers := append([]Checker(nil), r.checkers...)
+	r.mu.RUnlock()
+
+	components := make([]Component, len(checkers))
+	var wg sync.WaitGroup
+	for i, c := range checkers {
+		wg.Go(func() {
+			components[i] = r.run(ctx, c)
+		})
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Components: map[string]Component{}}
+	for i, c := range checkers {
+		report.Components[c.Name()] = components[i]
+		if components[i].Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, c Checker) Component {
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", r.timeout)
+	}
+
+	component := Component{Status: StatusOK, DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		component.Status = StatusUnavailable
+		component.Error = err.Error()
+	}
+
+	return component
+}
+
+// Database checks that a connection can be made and answers a ping.
+func Database(db *sql.DB) Checker {
+	return CheckFunc("database", db.PingContext)
+}
+
+// Migrations checks that the schema has been migrated to at least want, as
+// recorded by goose.
+func Migrations(db *sql.DB, want int64) Checker {
+	return CheckFunc("migrations", func(ctx context.Context) error {
+		var version int64
+		err := db.QueryRowContext(
+			ctx,
+			"SELECT COALESCE(MAX(version_id), 0) FROM goose_db_version WHERE is_applied",
+		).Scan(&version)
+		if err != nil {
+			return err
+		}
+		if version < want {
+			return fmt.Errorf("schema is at version %d, want %d", version, want)
+		}
+		return nil
+	})
+}
+
+// Flag reports unavailable with reason whenever ready returns false.
+func Flag(name string, ready func() bool, reason string) Checker {
+	return CheckFunc(name, func(context.Context) error {
+		if !ready() {
+			return errors.New(reason)
+		}
+		return nil
+	})
+}
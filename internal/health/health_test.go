@@ -0,0 +1,60 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegistryRun(t *testing.T) {
+	ready := true
+	r := NewRegistry(50 * time.Millisecond)
+	r.Register(Flag("server", func() bool { return ready }, "shutting down"))
+	r.Register(CheckFunc("storage", func(context.Context) error { return nil }))
+
+	report := r.Run(context.Background())
+	if !report.OK() {
+		t.Fatalf("Run() = %+v, want ok", report)
+	}
+	if len(report.Components) != 2 {
+		t.Errorf("Run() reported %d components, want 2", len(report.Components))
+	}
+
+	ready = false
+	report = r.Run(context.Background())
+	if report.OK() {
+		t.Fatal("Run() ok while a check fails")
+	}
+	if got := report.Components["server"]; got.Status != StatusUnavailable || got.Error != "shutting down" {
+		t.Errorf("server component = %+v, want unavailable: shutting down", got)
+	}
+	if got := report.Components["storage"]; got.Status != StatusOK {
+		t.Errorf("storage component = %+v, want ok", got)
+	}
+}
+
+func TestRegistryRunTimeout(t *testing.T) {
+	r := NewRegistry(10 * time.Millisecond)
+	r.Register(CheckFunc("queue", func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(time.Second)
+		return nil
+	}))
+	r.Register(CheckFunc("cache", func(context.Context) error { return errors.New("connection refused") }))
+
+	start := time.Now()
+	report := r.Run(context.Background())
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Run() took %s, want it bounded by the timeout", elapsed)
+	}
+	if report.OK() {
+		t.Fatal("Run() ok with a hung check")
+	}
+	if got := report.Components["queue"]; got.Status != StatusUnavailable {
+		t.Errorf("queue component = %+v, want unavailable", got)
+	}
+	if got := report.Components["cache"]; got.Error != "connection refused" {
+		t.Errorf("cache component = %+v, want connection refused", got)
+	}
+}
@@ -0,0 +1,162 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values are never written out,
+// whatever group they appear in.
+var sensitiveKeys = map[string]bool{
+	"authorization": true,
+	"cookie":        true,
+	"set-cookie":    true,
+	"password":      true,
+	"secret":        true,
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"api_key":       true,
+}
+
+// validRequestID keeps client-supplied IDs short and free of characters
+// that could forge log lines.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// New returns a JSON logger that redacts sensitive attributes.
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redact,
+	}))
+}
+
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redacted)
+	}
+	return a
+}
+
+// Headers renders request headers as a group. Credentials are redacted by
+// the handler like any other sensitive key.
+func Headers(h http.Header) slog.Attr {
+
+	attrs := make([]any, 0, len(h))
+	for name, values := range h {
+		attrs = append(attrs, slog.String(name, strings.Join(values, ", ")))
+	}
+
+	return slog.Group("headers", attrs...)
+}
+
+type contextKey struct{}
+
+// requestState is shared between the middleware and the handlers below it
+// so that the access log can report who the request was made by.
+type requestState struct {
+	logger *slog.Logger
+	userID string
+}
+
+// FromContext returns the request-scoped logger, or the default logger
+// outside of a request.
+func FromContext(ctx context.Context) *slog.Logger {
+	if s, ok := ctx.Value(contextKey{}).(*requestState); ok {
+		return s.logger
+	}
+	return slog.Default()
+}
+
+// SetUserID records the authenticated user for the rest of the request's
+// log lines, including the access log.
+func SetUserID(ctx context.Context, userID string) {
+	s, ok := ctx.Value(contextKey{}).(*requestState)
+	if !ok || s.userID == userID {
+		return
+	}
+	s.userID = userID
+	s.logger = s.logger.With("user_id", userID)
+}
+
+// Middleware assigns every request an ID, or keeps a valid one sent by the
+// client or a proxy, echoes it in the response and logs one line per
+// request once it completes. Inside a traced request the trace ID is
+// logged too, and the request ID is added to the span.
+func Middleware(logger *slog.Logger, next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requestID := req.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		requestLogger := logger.With("request_id", requestID)
+		if span := trace.SpanFromContext(req.Context()); span.SpanContext().IsValid() {
+			span.SetAttributes(attribute.String("request_id", requestID))
+			requestLogger = requestLogger.With("trace_id", span.SpanContext().TraceID().String())
+		}
+
+		state := &requestState{logger: requestLogger}
+		req = req.WithContext(context.WithValue(req.Context(), contextKey{}, state))
+		state.logger.Debug("Request started", Headers(req.Header))
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, req)
+
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		state.logger.LogAttrs(
+			req.Context(),
+			level,
+			"Request completed",
+			slog.String("method", req.Method),
+			slog.String("path", req.URL.Path),
+			slog.String("route", req.Pattern),
+			slog.Int("status", rec.status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("remote_addr", req.RemoteAddr),
+		)
+	})
+
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelDebug)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/chirps", func(w http.ResponseWriter, r *http.Request) {
+		SetUserID(r.Context(), "user-1")
+		FromContext(r.Context()).Info("Creating chirp", "password", "hunter2")
+		w.WriteHeader(http.StatusCreated)
+	})
+	handler := Middleware(logger, mux)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/chirps", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	req.Header.Set("Authorization", "Bearer secret-token")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(RequestIDHeader); got != "abc-123" {
+		t.Errorf("response %s = %q, want the one sent", RequestIDHeader, got)
+	}
+	if strings.Contains(buf.String(), "hunter2") || strings.Contains(buf.String(), "secret-token") {
+		t.Errorf("log leaks credentials:\n%s", buf.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d log lines, want started, handler and completed:\n%s", len(lines), buf.String())
+	}
+
+	var handlerLine, accessLine map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &handlerLine); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(lines[2]), &accessLine); err != nil {
+		t.Fatal(err)
+	}
+
+	if handlerLine["request_id"] != "abc-123" || handlerLine["user_id"] != "user-1" {
+		t.Errorf("handler log = %v, want request and user IDs", handlerLine)
+	}
+	for key, want := range map[string]any{
+		"request_id": "abc-123",
+		"user_id":    "user-1",
+		"method":     "POST",
+		"path":       "/api/chirps",
+		"route":      "POST /api/chirps",
+		"status":     float64(http.StatusCreated),
+	} {
+		if accessLine[key] != want {
+			t.Errorf("access log %s = %v, want %v", key, accessLine[key], want)
+		}
+	}
+	if _, ok := accessLine["latency"]; !ok {
+		t.Error("access log has no latency")
+	}
+}
+
+func TestMiddlewareRequestID(t *testing.T) {
+	handler := Middleware(New(&bytes.Buffer{}, slog.LevelInfo), http.NotFoundHandler())
+
+	for _, sent := range []string{"", "has spaces", strings.Repeat("a", 200), "line\nbreak"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(RequestIDHeader, sent)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		got := rec.Header().Get(RequestIDHeader)
+		if got == "" || got == sent {
+			t.Errorf("request ID %q was not replaced, got %q", sent, got)
+		}
+	}
+}
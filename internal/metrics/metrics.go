@@ -0,0 +1,255 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const namespace = "chirpy"
+
+// UnmatchedRoute labels requests that no route pattern matched, so unknown
+// paths cannot blow up the number of series.
+const UnmatchedRoute = "unmatched"
+
+// OtherMethod labels requests whose method is not a standard one, which a
+// client can otherwise make up freely.
+const OtherMethod = "OTHER"
+
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+
+	chirpsCreated     prometheus.Counter
+	logins            *prometheus.CounterVec
+	webhookDeliveries *prometheus.CounterVec
+	cacheLookups      *prometheus.CounterVec
+
+	// fileserverHits backs a counter func rather than a counter so that the
+	// dev-only reset endpoint can zero it.
+	fileserverHits atomic.Int64
+}
+
+// New registers the HTTP, business, Go runtime and process metrics. Pool
+// statistics are collected from db when it is not nil.
+func New(db *sql.DB) *Metrics {
+
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route pattern, method and status code.",
+		}, []string{"route", "method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route pattern and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests currently being served, including open streams.",
+		}),
+		chirpsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "chirps_created_total",
+			Help:      "Chirps published, whether posted directly, from a draft or on schedule.",
+		}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Sign-in attempts by method and result.",
+		}, []string{"method", "result"}),
+		webhookDeliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_deliveries_total",
+			Help:      "Webhook delivery attempts by event and result.",
+		}, []string{"event", "result"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_lookups_total",
+			Help:      "Read-through cache lookups by cache and result.",
+		}, []string{"cache", "result"}),
+	}
+
+	m.Registry.MustRegister(
+		m.requests,
+		m.duration,
+		m.inFlight,
+		m.chirpsCreated,
+		m.logins,
+		m.webhookDeliveries,
+		m.cacheLookups,
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "fileserver_hits_total",
+			Help:      "Requests served from /app since start or the last reset.",
+		}, func() float64 { return float64(m.fileserverHits.Load()) }),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	if db != nil {
+		m.Registry.MustRegister(collectors.NewDBStatsCollector(db, namespace))
+	}
+
+	return m
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
+
+// Middleware records every request against the ServeMux pattern that
+// handled it. It must wrap the mux itself: the pattern is only known once
+// the mux has routed the request.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, req)
+
+		route := req.Pattern
+		if route == "" {
+			route = UnmatchedRoute
+		}
+		method := methodLabel(req.Method)
+		m.requests.WithLabelValues(route, method, strconv.Itoa(rec.status)).Inc()
+		m.duration.WithLabelValues(route, method).Observe(time.Since(start).Seconds())
+	})
+
+}
+
+func (m *Metrics) FileserverHit() {
+	m.fileserverHits.Add(1)
+}
+
+func (m *Metrics) ResetFileserverHits() {
+	m.fileserverHits.Store(0)
+}
+
+func (m *Metrics) ChirpCreated() {
+	m.chirpsCreated.Inc()
+}
+
+func (m *Metrics) Login(method string, succeeded bool) {
+	m.logins.WithLabelValues(method, result(succeeded)).Inc()
+}
+
+// WebhookAttempt matches webhooks.Dispatcher.OnAttempt.
+func (m *Metrics) WebhookAttempt(event string, succeeded bool) {
+	m.webhookDeliveries.WithLabelValues(event, result(succeeded)).Inc()
+}
+
+// CacheLookup matches cached.Store.OnLookup.
+func (m *Metrics) CacheLookup(cache string, hit bool) {
+
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cacheLookups.WithLabelValues(cache, result).Inc()
+
+}
+
+// Total sums the series of the named metric that carry every label in
+// match, so a labelled counter can be shown as a single number. It returns
+// 0 for unknown names.
+func (m *Metrics) Total(name string, match prometheus.Labels) (float64, error) {
+
+	families, err := m.Registry.Gather()
+	if err != nil {
+		return 0, err
+	}
+
+	var total float64
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			if !hasLabels(metric, match) {
+				continue
+			}
+			switch {
+			case metric.Counter != nil:
+				total += metric.Counter.GetValue()
+			case metric.Gauge != nil:
+				total += metric.Gauge.GetValue()
+			}
+		}
+	}
+
+	return total, nil
+}
+
+func hasLabels(metric *dto.Metric, match prometheus.Labels) bool {
+
+	found := 0
+	for _, pair := range metric.GetLabel() {
+		if want, ok := match[pair.GetName()]; ok && want == pair.GetValue() {
+			found++
+		}
+	}
+
+	return found == len(match)
+}
+
+func methodLabel(method string) string {
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+
+	return OtherMethod
+}
+
+func result(succeeded bool) string {
+	if succeeded {
+		return "success"
+	}
+	return "failure"
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController and the WebSocket upgrader reach the
+// underlying writer to flush and hijack.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
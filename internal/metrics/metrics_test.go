@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMiddleware(t *testing.T) {
+	m := New(nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/chirps/{chirpID}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("POST /api/chirps", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	handler := m.Middleware(mux)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/chirps/1", nil),
+		httptest.NewRequest(http.MethodGet, "/api/chirps/2", nil),
+		httptest.NewRequest(http.MethodPost, "/api/chirps", nil),
+		httptest.NewRequest(http.MethodGet, "/nope", nil),
+		httptest.NewRequest("XYZZY", "/nope", nil),
+		httptest.NewRequest("get", "/nope", nil),
+	} {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	m.Login("password", false)
+	m.WebhookAttempt("chirp.created", true)
+	m.CacheLookup("chirp", true)
+	m.CacheLookup("chirp", false)
+	m.FileserverHit()
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+
+	for _, want := range []string{
+		`chirpy_http_requests_total{code="404",method="GET",route="GET /api/chirps/{chirpID}"} 2`,
+		`chirpy_http_requests_total{code="200",method="POST",route="POST /api/chirps"} 1`,
+		`chirpy_http_requests_total{code="404",method="GET",route="unmatched"} 1`,
+		`chirpy_http_requests_total{code="404",method="OTHER",route="unmatched"} 2`,
+		`chirpy_http_request_duration_seconds_count{method="GET",route="GET /api/chirps/{chirpID}"} 2`,
+		`chirpy_http_requests_in_flight 0`,
+		`chirpy_logins_total{method="password",result="failure"} 1`,
+		`chirpy_webhook_deliveries_total{event="chirp.created",result="success"} 1`,
+		`chirpy_cache_lookups_total{cache="chirp",result="hit"} 1`,
+		`chirpy_cache_lookups_total{cache="chirp",result="miss"} 1`,
+		`chirpy_fileserver_hits_total 1`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("exposition is missing %s", want)
+		}
+	}
+	if strings.Contains(string(body), `method="XYZZY"`) {
+		t.Error("exposition has a series for a made-up method")
+	}
+}
+
+func TestTotal(t *testing.T) {
+	m := New(nil)
+	m.Login("password", true)
+	m.Login("oidc", true)
+	m.Login("password", false)
+
+	if got, err := m.Total("chirpy_logins_total", nil); err != nil || got != 3 {
+		t.Errorf("Total(logins) = %v, %v; want 3", got, err)
+	}
+	if got, _ := m.Total("chirpy_logins_total", prometheus.Labels{"result": "success"}); got != 2 {
+		t.Errorf("Total(successful logins) = %v, want 2", got)
+	}
+
+	m.FileserverHit()
+	m.ResetFileserverHits()
+	if got, _ := m.Total("chirpy_fileserver_hits_total", nil); got != 0 {
+		t.Errorf("Total(fileserver hits) = %v after reset, want 0", got)
+	}
+}
@@ -0,0 +1,119 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+
+	"github.com/ghis9917/chirpy/sql/schema"
+	sqliteschema "github.com/ghis9917/chirpy/sql/sqlite/schema"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// The database drivers New accepts. They are also the database/sql driver
+// names.
+const (
+	Postgres = "postgres"
+	SQLite   = "sqlite"
+)
+
+type Migrator struct {
+	provider *goose.Provider
+}
+
+// New returns a migrator for the embedded schema of driver. On Postgres
+// every run holds an advisory lock, so instances started together apply
+// each migration exactly once; a SQLite file has a single instance.
+func New(db *sql.DB, driver string) (*Migrator, error) {
+
+	var provider *goose.Provider
+	var err error
+	switch driver {
+	case Postgres:
+		var locker lock.SessionLocker
+		locker, err = lock.NewPostgresSessionLocker()
+		if err != nil {
+			return nil, err
+		}
+		provider, err = goose.NewProvider(
+			goose.DialectPostgres,
+			db,
+			schema.FS,
+			goose.WithSessionLocker(locker),
+		)
+	case SQLite:
+		provider, err = goose.NewProvider(goose.DialectSQLite3, db, sqliteschema.FS)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{provider: provider}, nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) ([]*goose.MigrationResult, error) {
+	return m.provider.Up(ctx)
+}
+
+// Down rolls back the most recently applied migration.
+func (m *Migrator) Down(ctx context.Context) (*goose.MigrationResult, error) {
+	return m.provider.Down(ctx)
+}
+
+// Redo rolls back the most recent migration and applies it again.
+func (m *Migrator) Redo(ctx context.Context) ([]*goose.MigrationResult, error) {
+
+	down, err := m.provider.Down(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	up, err := m.provider.UpByOne(ctx)
+	if err != nil {
+		return []*goose.MigrationResult{down}, err
+	}
+
+	return []*goose.MigrationResult{down, up}, nil
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]*goose.MigrationStatus, error) {
+	return m.provider.Status(ctx)
+}
+
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	return m.provider.GetDBVersion(ctx)
+}
+
+// LatestVersion is the newest migration embedded in the binary. The SQLite
+// migrations mirror the Postgres ones, so it holds for both.
+func LatestVersion() int64 {
+
+	names, err := fs.Glob(schema.FS, "*.sql")
+	if err != nil {
+		panic(err)
+	}
+
+	var latest int64
+	for _, name := range names {
+		version, err := goose.NumericComponent(path.Base(name))
+		if err != nil {
+			panic(fmt.Sprintf("migration %s: %s", name, err))
+		}
+		latest = max(latest, version)
+	}
+
+	return latest
+}
+
+// IsNoChange reports whether err only means there was nothing to roll
+// back or reapply.
+func IsNoChange(err error) bool {
+	return errors.Is(err, goose.ErrNoNextVersion)
+}
@@ -0,0 +1,105 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ghis9917/chirpy/internal/store/sqlite"
+	"github.com/ghis9917/chirpy/sql/schema"
+	sqliteschema "github.com/ghis9917/chirpy/sql/sqlite/schema"
+	_ "github.com/lib/pq"
+)
+
+func TestMigrationFiles(t *testing.T) {
+	names, err := fs.Glob(schema.FS, "*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(names)) != LatestVersion() {
+		t.Errorf("found %d migrations but the latest is version %d; versions should have no gaps", len(names), LatestVersion())
+	}
+
+	for _, name := range names {
+		data, err := fs.ReadFile(schema.FS, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), "-- +goose Down") {
+			t.Errorf("%s has no Down section", name)
+		}
+	}
+
+	sqliteNames, err := fs.Glob(sqliteschema.FS, "*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(sqliteNames, names) {
+		t.Errorf("SQLite migrations = %v, want %v", sqliteNames, names)
+	}
+}
+
+// TestUpDown applies each migration, rolls it back and applies it again,
+// so every Down is exercised against the schema it has to undo. It needs
+// an empty Postgres database in TEST_DB_URL.
+func TestUpDown(t *testing.T) {
+	dbURL := os.Getenv("TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testUpDown(t, db, Postgres)
+}
+
+func TestUpDownSQLite(t *testing.T) {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "chirpy.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testUpDown(t, db, SQLite)
+}
+
+func testUpDown(t *testing.T, db *sql.DB, driver string) {
+	t.Helper()
+
+	m, err := New(db, driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for version := int64(1); version <= LatestVersion(); version++ {
+		if _, err := m.provider.UpByOne(ctx); err != nil {
+			t.Fatalf("up %d: %v", version, err)
+		}
+		if _, err := m.Redo(ctx); err != nil {
+			t.Fatalf("redo %d: %v", version, err)
+		}
+	}
+
+	if got, err := m.Version(ctx); err != nil || got != LatestVersion() {
+		t.Fatalf("Version() = %d, %v; want %d", got, err, LatestVersion())
+	}
+
+	for version := LatestVersion(); version > 0; version-- {
+		if _, err := m.Down(ctx); err != nil {
+			t.Fatalf("down %d: %v", version, err)
+		}
+	}
+	if _, err := m.Down(ctx); !IsNoChange(err) {
+		t.Errorf("Down() on an empty schema = %v, want no change", err)
+	}
+}
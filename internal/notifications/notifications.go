@@ -0,0 +1,169 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/logging"
+	"github.com/ghis9917/chirpy/internal/store"
+	"github.com/google/uuid"
+)
+
+type Type string
+
+const (
+	TypeFollow  Type = "follow"
+	TypeMention Type = "mention"
+	TypeReply   Type = "reply"
+	TypeLike    Type = "like"
+)
+
+var Types = []Type{TypeFollow, TypeMention, TypeReply, TypeLike}
+
+func (t Type) Valid() bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+type Event struct {
+	UserID  uuid.UUID
+	ActorID uuid.UUID
+	Type    Type
+	ChirpID uuid.UUID
+}
+
+// Store is what the service needs from persistence.
+type Store interface {
+	store.UserStore
+	store.NotificationStore
+}
+
+type Service struct {
+	db Store
+
+	// OnNotify, if set, is called with every notification recorded. Postgres
+	// announces them from a trigger; other backends rely on this instead.
+	OnNotify func(context.Context, database.Notification)
+}
+
+func New(db Store) *Service {
+	return &Service{db: db}
+}
+
+// Notify records an event for its recipient unless the recipient caused it
+// or has muted that notification type.
+func (s *Service) Notify(ctx context.Context, e Event) error {
+
+	if e.UserID == e.ActorID {
+		return nil
+	}
+
+	muted, err := s.db.IsNotificationTypeMuted(
+		ctx,
+		database.IsNotificationTypeMutedParams{
+			UserID: e.UserID,
+			Type:   string(e.Type),
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if muted {
+		return nil
+	}
+
+	notification, err := s.db.CreateNotification(
+		ctx,
+		database.CreateNotificationParams{
+			UserID:  e.UserID,
+			ActorID: e.ActorID,
+			Type:    string(e.Type),
+			ChirpID: uuid.NullUUID{UUID: e.ChirpID, Valid: e.ChirpID != uuid.Nil},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if s.OnNotify != nil {
+		s.OnNotify(ctx, notification)
+	}
+
+	return nil
+}
+
+// ChirpCreated notifies every user mentioned in the chirp body. Failures are
+// logged rather than returned so that publishing a chirp never fails because
+// of a notification.
+func (s *Service) ChirpCreated(ctx context.Context, chirp database.Chirp) {
+
+	for _, email := range Mentions(chirp.Body) {
+		user, err := s.db.GetUserByEmail(ctx, email)
+		if err != nil {
+			continue
+		}
+		if err := s.Notify(ctx, Event{
+			UserID:  user.ID,
+			ActorID: chirp.UserID,
+			Type:    TypeMention,
+			ChirpID: chirp.ID,
+		}); err != nil {
+			logging.FromContext(ctx).Error("Error recording mention notification", "chirp_id", chirp.ID, "err", err)
+		}
+	}
+
+}
+
+// Mentions returns the distinct email addresses mentioned as "@user@host" in
+// a chirp body.
+func Mentions(body string) []string {
+
+	seen := map[string]bool{}
+	mentions := []string{}
+	for _, word := range strings.Fields(body) {
+		handle, ok := strings.CutPrefix(word, "@")
+		if !ok {
+			continue
+		}
+		handle = strings.TrimRightFunc(handle, func(r rune) bool {
+			return unicode.IsPunct(r) && r != '_' && r != '-'
+		})
+		if !strings.Contains(handle, "@") || seen[strings.ToLower(handle)] {
+			continue
+		}
+		seen[strings.ToLower(handle)] = true
+		mentions = append(mentions, handle)
+	}
+
+	return mentions
+}
+
+// Summary renders a grouped notification as a sentence such as
+// "5 people liked your chirp".
+func Summary(t Type, count int32) string {
+
+	actor := "Someone"
+	if count > 1 {
+		actor = fmt.Sprintf("%d people", count)
+	}
+
+	switch t {
+	case TypeFollow:
+		return actor + " followed you"
+	case TypeMention:
+		return actor + " mentioned you in a chirp"
+	case TypeReply:
+		return actor + " replied to your chirp"
+	case TypeLike:
+		return actor + " liked your chirp"
+	}
+
+	return fmt.Sprintf("%s sent you a %s notification", actor, t)
+}
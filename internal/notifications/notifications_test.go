@@ -0,0 +1,53 @@
+package notifications
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "No mentions",
+			body: "I had something interesting for breakfast",
+			want: []string{},
+		},
+		{
+			name: "Single mention with punctuation",
+			body: "Thanks @walt@breakingbad.com!",
+			want: []string{"walt@breakingbad.com"},
+		},
+		{
+			name: "Duplicates are collapsed",
+			body: "@saul@bettercall.com and @Saul@bettercall.com, call me",
+			want: []string{"saul@bettercall.com"},
+		},
+		{
+			name: "Bare handles are ignored",
+			body: "hello @everyone",
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Mentions(tt.body)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Mentions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummary(t *testing.T) {
+	if got := Summary(TypeLike, 5); got != "5 people liked your chirp" {
+		t.Errorf("Summary() = %q", got)
+	}
+	if got := Summary(TypeFollow, 1); got != "Someone followed you" {
+		t.Errorf("Summary() = %q", got)
+	}
+}
@@ -0,0 +1,53 @@
+package oidc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ghis9917/chirpy/internal/oidc/oidctest"
+)
+
+func TestProviderExchange(t *testing.T) {
+	mock := oidctest.NewProvider(t)
+	ctx := context.Background()
+
+	provider, err := NewProvider(ctx, Config{
+		Name:        "mock",
+		Issuer:      mock.URL,
+		ClientID:    "chirpy",
+		RedirectURL: "http://localhost:8080/api/auth/mock/callback",
+	})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+
+	verifier := NewVerifier()
+	mock.Authorize(t, provider.AuthCodeURL("state", "nonce", verifier))
+
+	identity, err := provider.Exchange(ctx, oidctest.Code, verifier, "nonce")
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	want := Identity{Issuer: mock.URL, Subject: "user-123", Email: "alice@example.com", EmailVerified: true}
+	if identity != want {
+		t.Errorf("Exchange() = %+v, want %+v", identity, want)
+	}
+
+	if _, err := provider.Exchange(ctx, oidctest.Code, verifier, "other-nonce"); err == nil {
+		t.Error("Exchange() accepted a mismatched nonce")
+	}
+	if _, err := provider.Exchange(ctx, oidctest.Code, NewVerifier(), "nonce"); err == nil {
+		t.Error("Exchange() accepted a wrong code verifier")
+	}
+
+	mock.SetAudience("someone-else")
+	if _, err := provider.Exchange(ctx, oidctest.Code, verifier, "nonce"); err == nil {
+		t.Error("Exchange() accepted an ID token for another audience")
+	}
+}
+
+func TestNewProviderRequiresIssuer(t *testing.T) {
+	if _, err := NewProvider(context.Background(), Config{Name: "mock", ClientID: "chirpy"}); err == nil {
+		t.Error("NewProvider() accepted a config without an issuer")
+	}
+}
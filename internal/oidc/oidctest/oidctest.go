@@ -0,0 +1,158 @@
+// Package oidctest provides a minimal OpenID provider for tests.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Code is the only authorization code the provider accepts.
+const Code = "mock-code"
+
+// Provider issues ID tokens, for Code, to the last request passed to
+// Authorize. By default they are for user-123, alice@example.com; the
+// identity can be changed between sign-ins.
+type Provider struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu            sync.Mutex
+	audience      string
+	subject       string
+	email         string
+	emailVerified bool
+	nonce         string
+	challenge     string
+}
+
+// NewProvider starts a provider for the client ID "chirpy". It is closed
+// when the test ends.
+func NewProvider(t *testing.T) *Provider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Provider{
+		key:           key,
+		audience:      "chirpy",
+		subject:       "user-123",
+		email:         "alice@example.com",
+		emailVerified: true,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                p.URL,
+			"authorization_endpoint":                p.URL + "/authorize",
+			"token_endpoint":                        p.URL + "/token",
+			"jwks_uri":                              p.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"kid": "test",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("POST /token", p.handleToken)
+
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+
+	return p
+}
+
+func (p *Provider) handleToken(w http.ResponseWriter, r *http.Request) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if r.PostFormValue("code") != Code || base64.RawURLEncoding.EncodeToString(sum[:]) != p.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            p.URL,
+		"sub":            p.subject,
+		"aud":            p.audience,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          p.nonce,
+		"email":          p.email,
+		"email_verified": p.emailVerified,
+	})
+	token.Header["kid"] = "test"
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// Authorize plays the user approving the request at the provider and
+// returns the state the client will get back.
+func (p *Provider) Authorize(t *testing.T, authURL string) string {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Query().Get("code_challenge_method") != "S256" {
+		t.Fatalf("authorization URL %s has no S256 code challenge", authURL)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nonce = u.Query().Get("nonce")
+	p.challenge = u.Query().Get("code_challenge")
+
+	return u.Query().Get("state")
+}
+
+// SetIdentity changes who the ID tokens issued from now on are for.
+func (p *Provider) SetIdentity(subject, email string, emailVerified bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.subject, p.email, p.emailVerified = subject, email, emailVerified
+}
+
+// SetAudience changes the audience of the ID tokens issued from now on.
+func (p *Provider) SetAudience(audience string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.audience = audience
+}
@@ -0,0 +1,128 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/backoff"
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/store"
+	"github.com/ghis9917/chirpy/internal/tracing"
+	"github.com/google/uuid"
+)
+
+const MaxAttempts = 5
+
+type Scheduler struct {
+	db          store.Store
+	interval    time.Duration
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+	OnPublish   func(context.Context, database.Chirp)
+}
+
+func New(db store.Store, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		db:          db,
+		interval:    interval,
+		baseBackoff: 30 * time.Second,
+		maxBackoff:  time.Hour,
+	}
+}
+
+func (s *Scheduler) Run(ctx context.Context) {
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.PublishDue(ctx); err != nil {
+				slog.Error("Error publishing scheduled chirps", "err", err)
+			}
+		}
+	}
+
+}
+
+// PublishDue publishes every scheduled chirp whose time has come. Each row is
+// claimed and removed in the same transaction that creates the chirp, so
+// concurrent instances never publish a row twice. A row that fails is not
+// claimed again until its backoff, doubling with each attempt, has passed.
+func (s *Scheduler) PublishDue(ctx context.Context) (int, error) {
+
+	ctx, span := tracing.Tracer().Start(ctx, "scheduler.PublishDue")
+	defer span.End()
+
+	published := 0
+	for {
+		scheduled, err := s.publishNext(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return published, nil
+		}
+		if err != nil {
+			if scheduled.ID == uuid.Nil {
+				return published, err
+			}
+			slog.Error("Error publishing scheduled chirp", "scheduled_id", scheduled.ID, "err", err)
+			if err := s.db.RecordScheduledChirpFailure(
+				ctx,
+				database.RecordScheduledChirpFailureParams{
+					LastError:     sql.NullString{String: err.Error(), Valid: true},
+					NextAttemptAt: sql.NullTime{Time: time.Now().UTC().Add(backoff.Exponential(scheduled.Attempts+1, s.baseBackoff, s.maxBackoff)), Valid: true},
+					ID:            scheduled.ID,
+				},
+			); err != nil {
+				return published, err
+			}
+			continue
+		}
+		published++
+	}
+
+}
+
+func (s *Scheduler) publishNext(ctx context.Context) (database.ScheduledChirp, error) {
+
+	chirp, scheduled, err := s.claimAndPublish(ctx)
+	if err == nil && s.OnPublish != nil {
+		s.OnPublish(ctx, chirp)
+	}
+
+	return scheduled, err
+}
+
+func (s *Scheduler) claimAndPublish(ctx context.Context) (database.Chirp, database.ScheduledChirp, error) {
+
+	var chirp database.Chirp
+	var scheduled database.ScheduledChirp
+	err := s.db.InTx(ctx, func(q store.Queries) error {
+
+		var err error
+		scheduled, err = q.ClaimDueScheduledChirp(ctx, MaxAttempts)
+		if err != nil {
+			return err
+		}
+
+		chirp, err = q.CreateChirp(
+			ctx,
+			database.CreateChirpParams{
+				Body:   scheduled.Body,
+				UserID: scheduled.UserID,
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		return q.DeleteScheduledChirpByID(ctx, scheduled.ID)
+	})
+
+	return chirp, scheduled, err
+}
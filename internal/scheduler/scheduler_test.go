@@ -0,0 +1,180 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/store"
+	"github.com/ghis9917/chirpy/internal/store/memory"
+	"github.com/google/uuid"
+)
+
+func schedule(t *testing.T, s store.Store, userID uuid.UUID, body string, at time.Time) database.ScheduledChirp {
+	t.Helper()
+
+	scheduled, err := s.CreateScheduledChirp(
+		context.Background(),
+		database.CreateScheduledChirpParams{Body: body, UserID: userID, ScheduledAt: at.UTC()},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scheduled
+}
+
+func newUser(t *testing.T, s store.Store) database.User {
+	t.Helper()
+
+	user, err := s.CreateUser(context.Background(), database.CreateUserParams{Email: "alice@example.com", HashedPassword: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestPublishDue(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	user := newUser(t, db)
+	due := schedule(t, db, user.ID, "due", time.Now().Add(-time.Minute))
+	later := schedule(t, db, user.ID, "later", time.Now().Add(time.Hour))
+
+	s := New(db, time.Minute)
+	var published []database.Chirp
+	s.OnPublish = func(_ context.Context, chirp database.Chirp) { published = append(published, chirp) }
+
+	if n, err := s.PublishDue(ctx); err != nil || n != 1 {
+		t.Fatalf("PublishDue() = %d, %v, want 1", n, err)
+	}
+	if len(published) != 1 || published[0].Body != "due" || published[0].UserID != user.ID {
+		t.Errorf("published = %+v, want the due chirp", published)
+	}
+	if _, err := db.GetScheduledChirpByID(ctx, due.ID); err == nil {
+		t.Error("GetScheduledChirpByID(published) error = nil, want it removed")
+	}
+	if _, err := db.GetScheduledChirpByID(ctx, later.ID); err != nil {
+		t.Errorf("GetScheduledChirpByID(later) error = %v, want it kept", err)
+	}
+
+	if n, err := s.PublishDue(ctx); err != nil || n != 0 {
+		t.Errorf("PublishDue() again = %d, %v, want nothing left to publish", n, err)
+	}
+	if chirps, _ := db.GetAllChirps(ctx); len(chirps) != 1 {
+		t.Errorf("chirps = %d, want the row published once", len(chirps))
+	}
+}
+
+func TestPublishDueConcurrently(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	user := newUser(t, db)
+	const rows = 20
+	for i := range rows {
+		schedule(t, db, user.ID, fmt.Sprintf("chirp %d", i), time.Now().Add(-time.Duration(i+1)*time.Second))
+	}
+
+	var mu sync.Mutex
+	seen := map[uuid.UUID]int{}
+	total := 0
+	var wg sync.WaitGroup
+	for range 4 {
+		s := New(db, time.Minute)
+		s.OnPublish = func(_ context.Context, chirp database.Chirp) {
+			mu.Lock()
+			defer mu.Unlock()
+			seen[chirp.ID]++
+		}
+		wg.Go(func() {
+			n, err := s.PublishDue(ctx)
+			if err != nil {
+				t.Errorf("PublishDue() error = %v", err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			total += n
+		})
+	}
+	wg.Wait()
+
+	if total != rows || len(seen) != rows {
+		t.Errorf("published %d chirps, %d distinct, want %d", total, len(seen), rows)
+	}
+	if chirps, _ := db.GetAllChirps(ctx); len(chirps) != rows {
+		t.Errorf("chirps = %d, want each row published once", len(chirps))
+	}
+}
+
+var errPublish = errors.New("insert failed")
+
+// failingStore fails every chirp insert made inside a transaction.
+type failingStore struct {
+	store.Store
+}
+
+func (s failingStore) InTx(ctx context.Context, fn func(q store.Queries) error) error {
+	return s.Store.InTx(ctx, func(q store.Queries) error {
+		return fn(failingQueries{q})
+	})
+}
+
+type failingQueries struct {
+	store.Queries
+}
+
+func (failingQueries) CreateChirp(context.Context, database.CreateChirpParams) (database.Chirp, error) {
+	return database.Chirp{}, errPublish
+}
+
+func TestPublishDueFailures(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	user := newUser(t, db)
+	scheduled := schedule(t, db, user.ID, "doomed", time.Now().Add(-time.Minute))
+
+	s := New(failingStore{db}, time.Minute)
+	for range 3 {
+		if n, err := s.PublishDue(ctx); err != nil || n != 0 {
+			t.Fatalf("PublishDue() = %d, %v, want the failure recorded", n, err)
+		}
+	}
+	got, err := db.GetScheduledChirpByID(ctx, scheduled.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Attempts != 1 || got.LastError.String != errPublish.Error() {
+		t.Errorf("after failing = %d attempts, last error %q, want 1 attempt until the backoff passes", got.Attempts, got.LastError.String)
+	}
+	if wait := got.NextAttemptAt.Time.Sub(got.UpdatedAt); !got.NextAttemptAt.Valid || wait < 29*time.Second || wait > 31*time.Second {
+		t.Errorf("next attempt in %v, want the 30s base backoff", wait)
+	}
+}
+
+func TestPublishDueAttemptLimit(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	user := newUser(t, db)
+	scheduled := schedule(t, db, user.ID, "doomed", time.Now().Add(-time.Minute))
+
+	// Without a backoff the row is claimed again at once, so one run uses
+	// up every attempt.
+	s := New(failingStore{db}, time.Minute)
+	s.baseBackoff, s.maxBackoff = 0, 0
+	for range 2 {
+		if n, err := s.PublishDue(ctx); err != nil || n != 0 {
+			t.Fatalf("PublishDue() = %d, %v, want nothing published", n, err)
+		}
+	}
+
+	got, err := db.GetScheduledChirpByID(ctx, scheduled.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Attempts != MaxAttempts {
+		t.Errorf("attempts = %d, want %d and no more", got.Attempts, MaxAttempts)
+	}
+}
@@ -0,0 +1,287 @@
+// Package cached puts a read-through cache in front of a store.Store for the
+// lookups made on almost every request: chirps by ID and the signed-in user
+// by ID.
+//
+// Writes through the store invalidate what they change once they have been
+// made, or once the transaction they were made in ends. A read racing a
+// write can still cache the old row, so entries also expire after a TTL.
+//
+// Password hashes are never cached. Passwords are checked against
+// GetUserCredentialsByEmail, which is left uncached, and reads inside a
+// transaction always go to the database.
+package cached
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/cache"
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/logging"
+	"github.com/ghis9917/chirpy/internal/store"
+	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
+)
+
+// Names passed to OnLookup.
+const (
+	Chirps = "chirp"
+	Users  = "user"
+)
+
+type Store struct {
+	store.Store
+	cache cache.Cache
+	ttl   time.Duration
+	group singleflight.Group
+
+	// OnLookup, if set, is called with the cache name and whether the value
+	// was found for every cached lookup.
+	OnLookup func(name string, hit bool)
+}
+
+var _ store.Store = (*Store)(nil)
+
+func New(s store.Store, c cache.Cache, ttl time.Duration) *Store {
+	return &Store{
+		Store: s,
+		cache: c,
+		ttl:   ttl,
+	}
+}
+
+func chirpKey(id uuid.UUID) string {
+	return Chirps + ":" + id.String()
+}
+
+func userKey(id uuid.UUID) string {
+	return Users + ":" + id.String()
+}
+
+func (s *Store) GetChirpByID(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	return get(ctx, s, Chirps, chirpKey(id), func(ctx context.Context) (database.Chirp, error) {
+		return s.Store.GetChirpByID(ctx, id)
+	})
+}
+
+// GetUserByID leaves HashedPassword empty whether or not the user was
+// cached.
+func (s *Store) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+
+	u, err := get(ctx, s, Users, userKey(id), func(ctx context.Context) (user, error) {
+		u, err := s.Store.GetUserByID(ctx, id)
+		return toUser(u), err
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+
+	return database.User{
+		ID:          u.ID,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+		Email:       u.Email,
+		IsChirpyRed: u.IsChirpyRed,
+	}, nil
+}
+
+// user is what is cached of a database.User: everything but the password
+// hash, which would otherwise sit in a cache that may be shared.
+type user struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Email       string
+	IsChirpyRed bool
+}
+
+func toUser(u database.User) user {
+	return user{
+		ID:          u.ID,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+		Email:       u.Email,
+		IsChirpyRed: u.IsChirpyRed,
+	}
+}
+
+// get reads key from the cache, falling back to load on a miss. Concurrent
+// misses for the same key share one load, which runs without the first
+// caller's cancellation so one client giving up does not fail the others.
+// The cache failing only costs the lookup a trip to the database.
+func get[T any](ctx context.Context, s *Store, name, key string, load func(context.Context) (T, error)) (T, error) {
+
+	data, ok, err := s.cache.Get(ctx, key)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Error reading cache", "key", key, "err", err)
+	}
+	if ok {
+		var v T
+		if err := json.Unmarshal(data, &v); err == nil {
+			s.lookup(name, true)
+			return v, nil
+		}
+	}
+	s.lookup(name, false)
+
+	v, err, _ := s.group.Do(key, func() (any, error) {
+		ctx := context.WithoutCancel(ctx)
+		v, err := load(ctx)
+		if err != nil {
+			return v, err
+		}
+		data, err := json.Marshal(v)
+		if err == nil {
+			err = s.cache.Set(ctx, key, data, s.ttl)
+		}
+		if err != nil {
+			logging.FromContext(ctx).Warn("Error writing cache", "key", key, "err", err)
+		}
+		return v, nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return v.(T), nil
+}
+
+func (s *Store) lookup(name string, hit bool) {
+	if s.OnLookup != nil {
+		s.OnLookup(name, hit)
+	}
+}
+
+func (s *Store) UpdateUser(ctx context.Context, arg database.UpdateUserParams) (database.User, error) {
+	return s.writes(ctx).UpdateUser(ctx, arg)
+}
+
+func (s *Store) UpgradeUser(ctx context.Context, id uuid.UUID) error {
+	return s.writes(ctx).UpgradeUser(ctx, id)
+}
+
+func (s *Store) DeleteUserByID(ctx context.Context, id uuid.UUID) error {
+	return s.writes(ctx).DeleteUserByID(ctx, id)
+}
+
+func (s *Store) DeleteAllUsers(ctx context.Context) error {
+	return s.writes(ctx).DeleteAllUsers(ctx)
+}
+
+func (s *Store) DeleteChirpByID(ctx context.Context, id uuid.UUID) error {
+	return s.writes(ctx).DeleteChirpByID(ctx, id)
+}
+
+// InTx reads straight from the database inside the transaction, so fn sees
+// its own writes and full users, and invalidates what fn changed once the
+// transaction ends. Lookups in fn are deliberately left uncached.
+func (s *Store) InTx(ctx context.Context, fn func(q store.Queries) error) error {
+
+	var stale []string
+	cleared := false
+	err := s.Store.InTx(ctx, func(q store.Queries) error {
+		return fn(writes{
+			Queries: q,
+			stale:   func(keys ...string) { stale = append(stale, keys...) },
+			clear:   func() { cleared = true },
+		})
+	})
+
+	if cleared {
+		s.clear(ctx)
+	} else {
+		s.invalidate(ctx, stale...)
+	}
+
+	return err
+}
+
+func (s *Store) writes(ctx context.Context) writes {
+	return writes{
+		Queries: s.Store,
+		stale:   func(keys ...string) { s.invalidate(ctx, keys...) },
+		clear:   func() { s.clear(ctx) },
+	}
+}
+
+// invalidate deletes keys even if the request that changed them has been
+// cancelled; failing to leaves them stale until they expire.
+func (s *Store) invalidate(ctx context.Context, keys ...string) {
+
+	if len(keys) == 0 {
+		return
+	}
+	for _, key := range keys {
+		s.group.Forget(key)
+	}
+	if err := s.cache.Delete(context.WithoutCancel(ctx), keys...); err != nil {
+		logging.FromContext(ctx).Error("Error invalidating cache", "keys", keys, "err", err)
+	}
+
+}
+
+func (s *Store) clear(ctx context.Context) {
+	if err := s.cache.Clear(context.WithoutCancel(ctx)); err != nil {
+		logging.FromContext(ctx).Error("Error clearing cache", "err", err)
+	}
+}
+
+// writes wraps the queries that change cached rows, reporting the keys they
+// make stale.
+type writes struct {
+	store.Queries
+	stale func(keys ...string)
+	clear func()
+}
+
+func (w writes) UpdateUser(ctx context.Context, arg database.UpdateUserParams) (database.User, error) {
+
+	user, err := w.Queries.UpdateUser(ctx, arg)
+	w.stale(userKey(arg.ID))
+
+	return user, err
+}
+
+func (w writes) UpgradeUser(ctx context.Context, id uuid.UUID) error {
+
+	err := w.Queries.UpgradeUser(ctx, id)
+	w.stale(userKey(id))
+
+	return err
+}
+
+// DeleteUserByID also invalidates the user's chirps, which the delete
+// cascades to.
+func (w writes) DeleteUserByID(ctx context.Context, id uuid.UUID) error {
+
+	chirps, err := w.Queries.GetAllChirpsByAuthor(ctx, id)
+	if err != nil {
+		return err
+	}
+	keys := []string{userKey(id)}
+	for _, chirp := range chirps {
+		keys = append(keys, chirpKey(chirp.ID))
+	}
+	err = w.Queries.DeleteUserByID(ctx, id)
+	w.stale(keys...)
+
+	return err
+}
+
+func (w writes) DeleteAllUsers(ctx context.Context) error {
+
+	err := w.Queries.DeleteAllUsers(ctx)
+	w.clear()
+
+	return err
+}
+
+func (w writes) DeleteChirpByID(ctx context.Context, id uuid.UUID) error {
+
+	err := w.Queries.DeleteChirpByID(ctx, id)
+	w.stale(chirpKey(id))
+
+	return err
+}
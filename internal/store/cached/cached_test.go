@@ -0,0 +1,212 @@
+package cached
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/cache"
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/store"
+	"github.com/ghis9917/chirpy/internal/store/memory"
+	"github.com/ghis9917/chirpy/internal/store/storetest"
+	"github.com/google/uuid"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return New(memory.New(), cache.NewLRU(100), time.Minute)
+	})
+}
+
+type lookups struct {
+	mu   sync.Mutex
+	hits map[string]int
+	miss map[string]int
+}
+
+func countLookups(s *Store) *lookups {
+	l := &lookups{hits: map[string]int{}, miss: map[string]int{}}
+	s.OnLookup = func(name string, hit bool) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if hit {
+			l.hits[name]++
+		} else {
+			l.miss[name]++
+		}
+	}
+	return l
+}
+
+func TestInvalidation(t *testing.T) {
+	ctx := context.Background()
+	s := New(memory.New(), cache.NewLRU(100), time.Minute)
+	l := countLookups(s)
+
+	user, err := s.CreateUser(ctx, database.CreateUserParams{Email: "alice@example.com", HashedPassword: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chirp, err := s.CreateChirp(ctx, database.CreateChirpParams{Body: "hello", UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if _, err := s.GetUserByID(ctx, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.GetChirpByID(ctx, chirp.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if l.hits[Users] != 1 || l.miss[Users] != 1 || l.hits[Chirps] != 1 || l.miss[Chirps] != 1 {
+		t.Errorf("hits = %v, misses = %v, want one of each per cache", l.hits, l.miss)
+	}
+
+	if err := s.UpgradeUser(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.GetUserByID(ctx, user.ID); err != nil || !got.IsChirpyRed {
+		t.Errorf("GetUserByID() after UpgradeUser = %+v, %v, want Chirpy Red", got, err)
+	}
+
+	if _, err := s.UpdateUser(ctx, database.UpdateUserParams{Email: "alice@example.org", HashedPassword: "y", ID: user.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.GetUserByID(ctx, user.ID); err != nil || got.Email != "alice@example.org" {
+		t.Errorf("GetUserByID() after UpdateUser = %+v, %v, want the new email", got, err)
+	}
+
+	if err := s.InTx(ctx, func(q store.Queries) error { return q.DeleteUserByID(ctx, user.ID) }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetUserByID(ctx, user.ID); err == nil {
+		t.Error("GetUserByID() after deleting the user in a transaction error = nil, want the cached row invalidated")
+	}
+	if _, err := s.GetChirpByID(ctx, chirp.ID); err == nil {
+		t.Error("GetChirpByID() after deleting its author in a transaction error = nil, want the cached row invalidated")
+	}
+}
+
+// slowStore blocks GetChirpByID until release is closed and counts calls.
+type slowStore struct {
+	store.Store
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (s *slowStore) GetChirpByID(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	s.calls.Add(1)
+	<-s.release
+	return s.Store.GetChirpByID(ctx, id)
+}
+
+func TestSingleflight(t *testing.T) {
+	ctx := context.Background()
+	inner := memory.New()
+	user, _ := inner.CreateUser(ctx, database.CreateUserParams{Email: "alice@example.com", HashedPassword: "x"})
+	chirp, _ := inner.CreateChirp(ctx, database.CreateChirpParams{Body: "hello", UserID: user.ID})
+
+	slow := &slowStore{Store: inner, release: make(chan struct{})}
+	s := New(slow, cache.NewLRU(100), time.Minute)
+	l := countLookups(s)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			if got, err := s.GetChirpByID(ctx, chirp.ID); err != nil || got.ID != chirp.ID {
+				t.Errorf("GetChirpByID() = %v, %v, want %v", got.ID, err, chirp.ID)
+			}
+		})
+	}
+	// Wait until every caller has missed before letting the load finish.
+	for {
+		l.mu.Lock()
+		missed := l.miss[Chirps]
+		l.mu.Unlock()
+		if missed == 10 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(slow.release)
+	wg.Wait()
+
+	if calls := slow.calls.Load(); calls != 1 {
+		t.Errorf("store called %d times, want concurrent misses to share 1 load", calls)
+	}
+}
+
+// brokenCache fails every operation.
+type brokenCache struct{}
+
+var errBroken = errors.New("cache unavailable")
+
+func (brokenCache) Get(context.Context, string) ([]byte, bool, error) { return nil, false, errBroken }
+func (brokenCache) Set(context.Context, string, []byte, time.Duration) error {
+	return errBroken
+}
+func (brokenCache) Delete(context.Context, ...string) error { return errBroken }
+func (brokenCache) Clear(context.Context) error             { return errBroken }
+
+func TestCacheFailure(t *testing.T) {
+	ctx := context.Background()
+	s := New(memory.New(), brokenCache{}, time.Minute)
+
+	user, err := s.CreateUser(ctx, database.CreateUserParams{Email: "alice@example.com", HashedPassword: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.GetUserByID(ctx, user.ID); err != nil || got.ID != user.ID {
+		t.Errorf("GetUserByID() = %v, %v, want the user from the store", got.ID, err)
+	}
+	if err := s.UpgradeUser(ctx, user.ID); err != nil {
+		t.Errorf("UpgradeUser() error = %v, want a failed invalidation not to fail the write", err)
+	}
+}
+
+func TestPasswordsNotCached(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewLRU(100)
+	s := New(memory.New(), c, time.Minute)
+	const hash = "$argon2id$v=19$m=65536,t=1,p=4$c2FsdA$aGFzaA"
+
+	user, err := s.CreateUser(ctx, database.CreateUserParams{Email: "alice@example.com", HashedPassword: hash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		got, err := s.GetUserByID(ctx, user.ID)
+		if err != nil || got.ID != user.ID || got.HashedPassword != "" {
+			t.Errorf("GetUserByID() = %+v, %v, want the user without a password hash", got, err)
+		}
+	}
+
+	data, ok, err := c.Get(ctx, userKey(user.ID))
+	if err != nil || !ok {
+		t.Fatalf("cached user = %v, %v, want it cached", ok, err)
+	}
+	if stored := strings.ToLower(string(data)); strings.Contains(stored, "hashed_password") || strings.Contains(stored, "hashedpassword") || strings.Contains(string(data), hash) {
+		t.Errorf("cached user = %s, want no password hash", data)
+	}
+
+	if got, err := s.GetUserCredentialsByEmail(ctx, user.Email); err != nil || got.HashedPassword != hash {
+		t.Errorf("GetUserCredentialsByEmail() = %+v, %v, want hash %q", got, err, hash)
+	}
+	err = s.InTx(ctx, func(q store.Queries) error {
+		got, err := q.GetUserByID(ctx, user.ID)
+		if got.HashedPassword != hash {
+			t.Errorf("GetUserByID() in a transaction hash = %q, want %q", got.HashedPassword, hash)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,230 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// recordChirpEvent does what the chirps_record_event trigger does in
+// Postgres. Callers must hold s.mu.
+func (s *Store) recordChirpEvent(kind string, chirp database.Chirp) {
+
+	var id int64 = 1
+	if n := len(s.chirpEvents); n > 0 {
+		id = s.chirpEvents[n-1].ID + 1
+	}
+
+	s.chirpEvents = append(s.chirpEvents, database.ChirpEvent{
+		ID:             id,
+		CreatedAt:      s.now(),
+		Type:           kind,
+		ChirpID:        chirp.ID,
+		UserID:         chirp.UserID,
+		Body:           chirp.Body,
+		ChirpCreatedAt: chirp.CreatedAt,
+		ChirpUpdatedAt: chirp.UpdatedAt,
+	})
+}
+
+func (s *Store) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return database.Chirp{}, foreignKey("chirps_user_id_fkey")
+	}
+	if slices.ContainsFunc(s.chirps, func(c database.Chirp) bool { return c.Body == arg.Body }) {
+		return database.Chirp{}, unique("chirps_body_key")
+	}
+
+	now := s.now()
+	chirp := database.Chirp{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Body:      arg.Body,
+		UserID:    arg.UserID,
+	}
+	s.chirps = append(s.chirps, chirp)
+	s.recordChirpEvent("chirp.created", chirp)
+
+	return chirp, nil
+}
+
+func (s *Store) GetChirpByID(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chirp, ok := find(s.chirps, func(c database.Chirp) bool { return c.ID == id })
+	if !ok {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+
+	return *chirp, nil
+}
+
+// Chirps are kept in insertion order, which is also created_at order.
+
+func (s *Store) GetAllChirps(ctx context.Context) ([]database.Chirp, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.chirps), nil
+}
+
+func (s *Store) GetAllChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return filter(s.chirps, func(c database.Chirp) bool { return c.UserID == userID }), nil
+}
+
+func (s *Store) DeleteChirpByID(ctx context.Context, id uuid.UUID) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteChirps(func(c database.Chirp) bool { return c.ID == id })
+
+	return nil
+}
+
+func (s *Store) CreateScheduledChirp(ctx context.Context, arg database.CreateScheduledChirpParams) (database.ScheduledChirp, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return database.ScheduledChirp{}, foreignKey("scheduled_chirps_user_id_fkey")
+	}
+
+	now := s.now()
+	scheduled := database.ScheduledChirp{
+		ID:          uuid.New(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Body:        arg.Body,
+		UserID:      arg.UserID,
+		ScheduledAt: arg.ScheduledAt,
+	}
+	s.scheduledChirps = append(s.scheduledChirps, scheduled)
+
+	return scheduled, nil
+}
+
+func (s *Store) GetScheduledChirpByID(ctx context.Context, id uuid.UUID) (database.ScheduledChirp, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scheduled, ok := find(s.scheduledChirps, func(c database.ScheduledChirp) bool { return c.ID == id })
+	if !ok {
+		return database.ScheduledChirp{}, sql.ErrNoRows
+	}
+
+	return *scheduled, nil
+}
+
+func (s *Store) GetScheduledChirpsByUser(ctx context.Context, userID uuid.UUID) ([]database.ScheduledChirp, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scheduled := filter(s.scheduledChirps, func(c database.ScheduledChirp) bool { return c.UserID == userID })
+	slices.SortStableFunc(scheduled, func(a, b database.ScheduledChirp) int {
+		return a.ScheduledAt.Compare(b.ScheduledAt)
+	})
+
+	return scheduled, nil
+}
+
+func (s *Store) DeleteScheduledChirpByID(ctx context.Context, id uuid.UUID) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scheduledChirps = slices.DeleteFunc(s.scheduledChirps, func(c database.ScheduledChirp) bool { return c.ID == id })
+
+	return nil
+}
+
+// ClaimDueScheduledChirp returns the earliest scheduled chirp that is due,
+// not backing off from a failure and has attempts left. There is nothing to
+// lock: InTx already runs one transaction at a time.
+func (s *Store) ClaimDueScheduledChirp(ctx context.Context, attempts int32) (database.ScheduledChirp, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	due := filter(s.scheduledChirps, func(c database.ScheduledChirp) bool {
+		return !c.ScheduledAt.After(now) && (!c.NextAttemptAt.Valid || !c.NextAttemptAt.Time.After(now)) && c.Attempts < attempts
+	})
+	if len(due) == 0 {
+		return database.ScheduledChirp{}, sql.ErrNoRows
+	}
+	slices.SortStableFunc(due, func(a, b database.ScheduledChirp) int {
+		return a.ScheduledAt.Compare(b.ScheduledAt)
+	})
+
+	return due[0], nil
+}
+
+func (s *Store) RecordScheduledChirpFailure(ctx context.Context, arg database.RecordScheduledChirpFailureParams) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if scheduled, ok := find(s.scheduledChirps, func(c database.ScheduledChirp) bool { return c.ID == arg.ID }); ok {
+		scheduled.Attempts++
+		scheduled.LastError = arg.LastError
+		scheduled.NextAttemptAt = arg.NextAttemptAt
+		scheduled.UpdatedAt = s.now()
+	}
+
+	return nil
+}
+
+func (s *Store) GetChirpEventByID(ctx context.Context, id int64) (database.ChirpEvent, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := find(s.chirpEvents, func(e database.ChirpEvent) bool { return e.ID == id })
+	if !ok {
+		return database.ChirpEvent{}, sql.ErrNoRows
+	}
+
+	return *event, nil
+}
+
+func (s *Store) GetChirpEventsSince(ctx context.Context, arg database.GetChirpEventsSinceParams) ([]database.ChirpEvent, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := filter(s.chirpEvents, func(e database.ChirpEvent) bool { return e.ID > arg.ID })
+
+	return page(events, arg.Limit, 0), nil
+}
+
+func (s *Store) DeleteChirpEventsBefore(ctx context.Context, createdAt time.Time) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.chirpEvents = slices.DeleteFunc(s.chirpEvents, func(e database.ChirpEvent) bool {
+		return e.CreatedAt.Before(createdAt)
+	})
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (s *Store) CreateBookmark(ctx context.Context, arg database.CreateBookmarkParams) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return foreignKey("bookmarks_user_id_fkey")
+	}
+	if !s.chirpExists(arg.ChirpID) {
+		return foreignKey("bookmarks_chirp_id_fkey")
+	}
+	if slices.ContainsFunc(s.bookmarks, func(b database.Bookmark) bool {
+		return b.UserID == arg.UserID && b.ChirpID == arg.ChirpID
+	}) {
+		return nil
+	}
+
+	s.bookmarks = append(s.bookmarks, database.Bookmark{
+		UserID:    arg.UserID,
+		ChirpID:   arg.ChirpID,
+		CreatedAt: s.now(),
+	})
+
+	return nil
+}
+
+func (s *Store) DeleteBookmark(ctx context.Context, arg database.DeleteBookmarkParams) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.bookmarks = slices.DeleteFunc(s.bookmarks, func(b database.Bookmark) bool {
+		return b.UserID == arg.UserID && b.ChirpID == arg.ChirpID
+	})
+
+	return nil
+}
+
+func (s *Store) GetBookmarkedChirps(ctx context.Context, arg database.GetBookmarkedChirpsParams) ([]database.Chirp, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chirps := []database.Chirp{}
+	for _, b := range slices.Backward(s.bookmarks) {
+		if b.UserID != arg.UserID {
+			continue
+		}
+		if chirp, ok := find(s.chirps, func(c database.Chirp) bool { return c.ID == b.ChirpID }); ok {
+			chirps = append(chirps, *chirp)
+		}
+	}
+
+	return page(chirps, arg.Limit, arg.Offset), nil
+}
+
+func (s *Store) GetBookmarkedChirpIDs(ctx context.Context, arg database.GetBookmarkedChirpIDsParams) ([]uuid.UUID, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := []uuid.UUID{}
+	for _, b := range s.bookmarks {
+		if b.UserID == arg.UserID && slices.Contains(arg.ChirpIds, b.ChirpID) {
+			ids = append(ids, b.ChirpID)
+		}
+	}
+
+	return ids, nil
+}
+
+func (s *Store) CreateCollection(ctx context.Context, arg database.CreateCollectionParams) (database.Collection, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return database.Collection{}, foreignKey("collections_user_id_fkey")
+	}
+	if slices.ContainsFunc(s.collections, func(c database.Collection) bool {
+		return c.UserID == arg.UserID && c.Name == arg.Name
+	}) {
+		return database.Collection{}, unique("collections_user_id_name_key")
+	}
+
+	now := s.now()
+	collection := database.Collection{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    arg.UserID,
+		Name:      arg.Name,
+	}
+	s.collections = append(s.collections, collection)
+
+	return collection, nil
+}
+
+func (s *Store) GetCollectionByID(ctx context.Context, id uuid.UUID) (database.Collection, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	collection, ok := find(s.collections, func(c database.Collection) bool { return c.ID == id })
+	if !ok {
+		return database.Collection{}, sql.ErrNoRows
+	}
+
+	return *collection, nil
+}
+
+func (s *Store) GetCollectionsByUser(ctx context.Context, arg database.GetCollectionsByUserParams) ([]database.Collection, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	collections := filter(s.collections, func(c database.Collection) bool { return c.UserID == arg.UserID })
+	slices.SortStableFunc(collections, func(a, b database.Collection) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return page(collections, arg.Limit, arg.Offset), nil
+}
+
+func (s *Store) RenameCollection(ctx context.Context, arg database.RenameCollectionParams) (database.Collection, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	collection, ok := find(s.collections, func(c database.Collection) bool { return c.ID == arg.ID })
+	if !ok {
+		return database.Collection{}, sql.ErrNoRows
+	}
+	if slices.ContainsFunc(s.collections, func(c database.Collection) bool {
+		return c.UserID == collection.UserID && c.Name == arg.Name && c.ID != arg.ID
+	}) {
+		return database.Collection{}, unique("collections_user_id_name_key")
+	}
+
+	collection.Name = arg.Name
+	collection.UpdatedAt = s.now()
+
+	return *collection, nil
+}
+
+func (s *Store) DeleteCollectionByID(ctx context.Context, id uuid.UUID) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteCollections(func(c database.Collection) bool { return c.ID == id })
+
+	return nil
+}
+
+func (s *Store) AddChirpToCollection(ctx context.Context, arg database.AddChirpToCollectionParams) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !slices.ContainsFunc(s.collections, func(c database.Collection) bool { return c.ID == arg.CollectionID }) {
+		return foreignKey("collection_chirps_collection_id_fkey")
+	}
+	if !s.chirpExists(arg.ChirpID) {
+		return foreignKey("collection_chirps_chirp_id_fkey")
+	}
+	if slices.ContainsFunc(s.collectionChirps, func(c database.CollectionChirp) bool {
+		return c.CollectionID == arg.CollectionID && c.ChirpID == arg.ChirpID
+	}) {
+		return nil
+	}
+
+	s.collectionChirps = append(s.collectionChirps, database.CollectionChirp{
+		CollectionID: arg.CollectionID,
+		ChirpID:      arg.ChirpID,
+		CreatedAt:    s.now(),
+	})
+
+	return nil
+}
+
+func (s *Store) RemoveChirpFromCollection(ctx context.Context, arg database.RemoveChirpFromCollectionParams) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.collectionChirps = slices.DeleteFunc(s.collectionChirps, func(c database.CollectionChirp) bool {
+		return c.CollectionID == arg.CollectionID && c.ChirpID == arg.ChirpID
+	})
+
+	return nil
+}
+
+func (s *Store) GetCollectionChirps(ctx context.Context, arg database.GetCollectionChirpsParams) ([]database.Chirp, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chirps := []database.Chirp{}
+	for _, cc := range slices.Backward(s.collectionChirps) {
+		if cc.CollectionID != arg.CollectionID {
+			continue
+		}
+		if chirp, ok := find(s.chirps, func(c database.Chirp) bool { return c.ID == cc.ChirpID }); ok {
+			chirps = append(chirps, *chirp)
+		}
+	}
+
+	return page(chirps, arg.Limit, arg.Offset), nil
+}
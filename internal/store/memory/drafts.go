@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (s *Store) CreateDraft(ctx context.Context, arg database.CreateDraftParams) (database.Draft, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return database.Draft{}, foreignKey("drafts_user_id_fkey")
+	}
+
+	now := s.now()
+	draft := database.Draft{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Body:      arg.Body,
+		UserID:    arg.UserID,
+	}
+	s.drafts = append(s.drafts, draft)
+
+	return draft, nil
+}
+
+func (s *Store) GetDraftByID(ctx context.Context, id uuid.UUID) (database.Draft, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	draft, ok := find(s.drafts, func(d database.Draft) bool { return d.ID == id })
+	if !ok {
+		return database.Draft{}, sql.ErrNoRows
+	}
+
+	return *draft, nil
+}
+
+func (s *Store) GetDraftsByUser(ctx context.Context, userID uuid.UUID) ([]database.Draft, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	drafts := filter(s.drafts, func(d database.Draft) bool { return d.UserID == userID })
+	slices.SortStableFunc(drafts, func(a, b database.Draft) int {
+		return b.UpdatedAt.Compare(a.UpdatedAt)
+	})
+
+	return drafts, nil
+}
+
+func (s *Store) UpdateDraft(ctx context.Context, arg database.UpdateDraftParams) (database.Draft, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	draft, ok := find(s.drafts, func(d database.Draft) bool { return d.ID == arg.ID })
+	if !ok {
+		return database.Draft{}, sql.ErrNoRows
+	}
+
+	draft.Body = arg.Body
+	draft.UpdatedAt = s.now()
+
+	return *draft, nil
+}
+
+func (s *Store) DeleteDraftByID(ctx context.Context, id uuid.UUID) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.drafts = slices.DeleteFunc(s.drafts, func(d database.Draft) bool { return d.ID == id })
+
+	return nil
+}
+
+func (s *Store) ClaimDraft(ctx context.Context, arg database.ClaimDraftParams) (database.Draft, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.drafts, func(d database.Draft) bool { return d.ID == arg.ID && d.UserID == arg.UserID })
+	if i < 0 {
+		return database.Draft{}, sql.ErrNoRows
+	}
+	draft := s.drafts[i]
+	s.drafts = slices.Delete(s.drafts, i, i+1)
+
+	return draft, nil
+}
@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (s *Store) CreateIdentity(ctx context.Context, arg database.CreateIdentityParams) (database.Identity, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return database.Identity{}, foreignKey("identities_user_id_fkey")
+	}
+	if slices.ContainsFunc(s.identities, func(i database.Identity) bool {
+		return i.Provider == arg.Provider && i.Subject == arg.Subject
+	}) {
+		return database.Identity{}, unique("identities_provider_subject_key")
+	}
+	if slices.ContainsFunc(s.identities, func(i database.Identity) bool {
+		return i.UserID == arg.UserID && i.Provider == arg.Provider
+	}) {
+		return database.Identity{}, unique("identities_user_id_provider_key")
+	}
+
+	now := s.now()
+	identity := database.Identity{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    arg.UserID,
+		Provider:  arg.Provider,
+		Subject:   arg.Subject,
+		Email:     arg.Email,
+	}
+	s.identities = append(s.identities, identity)
+
+	return identity, nil
+}
+
+func (s *Store) GetIdentityByID(ctx context.Context, id uuid.UUID) (database.Identity, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, ok := find(s.identities, func(i database.Identity) bool { return i.ID == id })
+	if !ok {
+		return database.Identity{}, sql.ErrNoRows
+	}
+
+	return *identity, nil
+}
+
+func (s *Store) GetIdentityBySubject(ctx context.Context, arg database.GetIdentityBySubjectParams) (database.Identity, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, ok := find(s.identities, func(i database.Identity) bool {
+		return i.Provider == arg.Provider && i.Subject == arg.Subject
+	})
+	if !ok {
+		return database.Identity{}, sql.ErrNoRows
+	}
+
+	return *identity, nil
+}
+
+func (s *Store) GetIdentitiesByUser(ctx context.Context, userID uuid.UUID) ([]database.Identity, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return filter(s.identities, func(i database.Identity) bool { return i.UserID == userID }), nil
+}
+
+func (s *Store) CountIdentitiesByUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(filter(s.identities, func(i database.Identity) bool { return i.UserID == userID }))), nil
+}
+
+func (s *Store) DeleteIdentityByID(ctx context.Context, id uuid.UUID) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.identities = slices.DeleteFunc(s.identities, func(i database.Identity) bool { return i.ID == id })
+
+	return nil
+}
+
+func (s *Store) CreateOIDCLoginState(ctx context.Context, arg database.CreateOIDCLoginStateParams) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if arg.UserID.Valid && !s.userExists(arg.UserID.UUID) {
+		return foreignKey("oidc_login_states_user_id_fkey")
+	}
+	if slices.ContainsFunc(s.oidcLoginStates, func(l database.OidcLoginState) bool { return l.StateHash == arg.StateHash }) {
+		return unique("oidc_login_states_pkey")
+	}
+
+	s.oidcLoginStates = append(s.oidcLoginStates, database.OidcLoginState{
+		StateHash:    arg.StateHash,
+		CreatedAt:    s.now(),
+		Provider:     arg.Provider,
+		Nonce:        arg.Nonce,
+		CodeVerifier: arg.CodeVerifier,
+		UserID:       arg.UserID,
+		ExpiresAt:    arg.ExpiresAt,
+	})
+
+	return nil
+}
+
+func (s *Store) ConsumeOIDCLoginState(ctx context.Context, stateHash string) (database.OidcLoginState, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	i := slices.IndexFunc(s.oidcLoginStates, func(l database.OidcLoginState) bool {
+		return l.StateHash == stateHash && l.ExpiresAt.After(now)
+	})
+	if i < 0 {
+		return database.OidcLoginState{}, sql.ErrNoRows
+	}
+
+	state := s.oidcLoginStates[i]
+	s.oidcLoginStates = slices.Delete(s.oidcLoginStates, i, i+1)
+
+	return state, nil
+}
+
+func (s *Store) DeleteExpiredOIDCLoginStates(ctx context.Context) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.oidcLoginStates = slices.DeleteFunc(s.oidcLoginStates, func(l database.OidcLoginState) bool {
+		return !l.ExpiresAt.After(now)
+	})
+
+	return nil
+}
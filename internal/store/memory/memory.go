@@ -0,0 +1,301 @@
+// Package memory is an in-process implementation of store.Store for tests.
+// It follows the Postgres schema closely enough for handlers not to notice:
+// missing rows are sql.ErrNoRows, unique and foreign keys are enforced,
+// deletes cascade and chirp changes are written to the event log the way
+// the chirps trigger does.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/store"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUniqueViolation     = errors.New("memory: duplicate key value violates unique constraint")
+	ErrForeignKeyViolation = errors.New("memory: insert violates foreign key constraint")
+)
+
+type Store struct {
+	// txMu serializes InTx calls; mu guards the tables for the duration of
+	// a single query.
+	txMu sync.Mutex
+	mu   sync.Mutex
+	last time.Time
+
+	tables
+}
+
+type tables struct {
+	users                  []database.User
+	chirps                 []database.Chirp
+	chirpEvents            []database.ChirpEvent
+	scheduledChirps        []database.ScheduledChirp
+	drafts                 []database.Draft
+	bookmarks              []database.Bookmark
+	collections            []database.Collection
+	collectionChirps       []database.CollectionChirp
+	notifications          []database.Notification
+	mutedNotificationTypes []database.MutedNotificationType
+	webhookSubscriptions   []database.WebhookSubscription
+	webhookDeliveries      []database.WebhookDelivery
+	webhookAttempts        []database.WebhookDeliveryAttempt
+	refreshTokens          []database.RefreshToken
+	apiKeys                []database.ApiKey
+	oauthClients           []database.OauthClient
+	oauthCodes             []database.OauthAuthorizationCode
+	oauthConsents          []database.OauthConsent
+	oauthAccessTokens      []database.OauthAccessToken
+	identities             []database.Identity
+	oidcLoginStates        []database.OidcLoginState
+}
+
+var _ store.Store = (*Store)(nil)
+
+func New() *Store {
+	return &Store{}
+}
+
+// InTx runs fn against the store itself and, if fn fails, puts back every
+// table as it was before. Transactions run one at a time; queries made
+// outside InTx while one is in progress are rolled back with it.
+func (s *Store) InTx(_ context.Context, fn func(q store.Queries) error) error {
+
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	s.mu.Lock()
+	snapshot := s.tables.clone()
+	s.mu.Unlock()
+
+	if err := fn(s); err != nil {
+		s.mu.Lock()
+		s.tables = snapshot
+		s.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// now stands in for NOW(). Timestamps have the microsecond precision of a
+// Postgres TIMESTAMP and strictly increase, so ordering by creation time is
+// deterministic. Callers must hold s.mu.
+func (s *Store) now() time.Time {
+
+	t := time.Now().UTC().Truncate(time.Microsecond)
+	if !t.After(s.last) {
+		t = s.last.Add(time.Microsecond)
+	}
+	s.last = t
+
+	return t
+}
+
+func unique(constraint string) error {
+	return fmt.Errorf("%w %q", ErrUniqueViolation, constraint)
+}
+
+func foreignKey(constraint string) error {
+	return fmt.Errorf("%w %q", ErrForeignKeyViolation, constraint)
+}
+
+// find returns a pointer to the first row matching, so updates can be made
+// in place.
+func find[T any](rows []T, match func(T) bool) (*T, bool) {
+	i := slices.IndexFunc(rows, match)
+	if i < 0 {
+		return nil, false
+	}
+	return &rows[i], true
+}
+
+func filter[T any](rows []T, match func(T) bool) []T {
+	matched := []T{}
+	for _, row := range rows {
+		if match(row) {
+			matched = append(matched, row)
+		}
+	}
+	return matched
+}
+
+// page applies LIMIT and OFFSET.
+func page[T any](rows []T, limit, offset int32) []T {
+	if int(offset) >= len(rows) {
+		return []T{}
+	}
+	rows = rows[offset:]
+	if int(limit) < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: true}
+}
+
+func (s *Store) userExists(id uuid.UUID) bool {
+	return slices.ContainsFunc(s.users, func(u database.User) bool { return u.ID == id })
+}
+
+func (s *Store) chirpExists(id uuid.UUID) bool {
+	return slices.ContainsFunc(s.chirps, func(c database.Chirp) bool { return c.ID == id })
+}
+
+func (s *Store) oauthClientExists(id string) bool {
+	return slices.ContainsFunc(s.oauthClients, func(c database.OauthClient) bool { return c.ID == id })
+}
+
+// The delete helpers below remove rows together with everything that
+// references them ON DELETE CASCADE. Callers must hold s.mu.
+
+func (s *Store) deleteUsers(match func(database.User) bool) {
+
+	var deleted []uuid.UUID
+	s.users = slices.DeleteFunc(s.users, func(u database.User) bool {
+		if match(u) {
+			deleted = append(deleted, u.ID)
+			return true
+		}
+		return false
+	})
+	owned := func(id uuid.UUID) bool { return slices.Contains(deleted, id) }
+
+	s.deleteChirps(func(c database.Chirp) bool { return owned(c.UserID) })
+	s.deleteCollections(func(c database.Collection) bool { return owned(c.UserID) })
+	s.deleteWebhookSubscriptions(func(w database.WebhookSubscription) bool { return owned(w.UserID) })
+	s.deleteOAuthClients(func(c database.OauthClient) bool { return owned(c.UserID) })
+
+	s.scheduledChirps = slices.DeleteFunc(s.scheduledChirps, func(c database.ScheduledChirp) bool { return owned(c.UserID) })
+	s.drafts = slices.DeleteFunc(s.drafts, func(d database.Draft) bool { return owned(d.UserID) })
+	s.bookmarks = slices.DeleteFunc(s.bookmarks, func(b database.Bookmark) bool { return owned(b.UserID) })
+	s.notifications = slices.DeleteFunc(s.notifications, func(n database.Notification) bool {
+		return owned(n.UserID) || owned(n.ActorID)
+	})
+	s.mutedNotificationTypes = slices.DeleteFunc(s.mutedNotificationTypes, func(m database.MutedNotificationType) bool { return owned(m.UserID) })
+	s.refreshTokens = slices.DeleteFunc(s.refreshTokens, func(t database.RefreshToken) bool { return owned(t.UserID) })
+	s.apiKeys = slices.DeleteFunc(s.apiKeys, func(k database.ApiKey) bool { return owned(k.UserID) })
+	s.oauthCodes = slices.DeleteFunc(s.oauthCodes, func(c database.OauthAuthorizationCode) bool { return owned(c.UserID) })
+	s.oauthConsents = slices.DeleteFunc(s.oauthConsents, func(c database.OauthConsent) bool { return owned(c.UserID) })
+	s.oauthAccessTokens = slices.DeleteFunc(s.oauthAccessTokens, func(t database.OauthAccessToken) bool { return owned(t.UserID) })
+	s.identities = slices.DeleteFunc(s.identities, func(i database.Identity) bool { return owned(i.UserID) })
+	s.oidcLoginStates = slices.DeleteFunc(s.oidcLoginStates, func(l database.OidcLoginState) bool {
+		return l.UserID.Valid && owned(l.UserID.UUID)
+	})
+}
+
+func (s *Store) deleteChirps(match func(database.Chirp) bool) {
+
+	var deleted []uuid.UUID
+	s.chirps = slices.DeleteFunc(s.chirps, func(c database.Chirp) bool {
+		if match(c) {
+			deleted = append(deleted, c.ID)
+			s.recordChirpEvent("chirp.deleted", c)
+			return true
+		}
+		return false
+	})
+	gone := func(id uuid.UUID) bool { return slices.Contains(deleted, id) }
+
+	s.bookmarks = slices.DeleteFunc(s.bookmarks, func(b database.Bookmark) bool { return gone(b.ChirpID) })
+	s.collectionChirps = slices.DeleteFunc(s.collectionChirps, func(c database.CollectionChirp) bool { return gone(c.ChirpID) })
+	s.notifications = slices.DeleteFunc(s.notifications, func(n database.Notification) bool {
+		return n.ChirpID.Valid && gone(n.ChirpID.UUID)
+	})
+}
+
+func (s *Store) deleteCollections(match func(database.Collection) bool) {
+
+	var deleted []uuid.UUID
+	s.collections = slices.DeleteFunc(s.collections, func(c database.Collection) bool {
+		if match(c) {
+			deleted = append(deleted, c.ID)
+			return true
+		}
+		return false
+	})
+
+	s.collectionChirps = slices.DeleteFunc(s.collectionChirps, func(c database.CollectionChirp) bool {
+		return slices.Contains(deleted, c.CollectionID)
+	})
+}
+
+func (s *Store) deleteWebhookSubscriptions(match func(database.WebhookSubscription) bool) {
+
+	var subscriptions, deliveries []uuid.UUID
+	s.webhookSubscriptions = slices.DeleteFunc(s.webhookSubscriptions, func(w database.WebhookSubscription) bool {
+		if match(w) {
+			subscriptions = append(subscriptions, w.ID)
+			return true
+		}
+		return false
+	})
+	s.webhookDeliveries = slices.DeleteFunc(s.webhookDeliveries, func(d database.WebhookDelivery) bool {
+		if slices.Contains(subscriptions, d.SubscriptionID) {
+			deliveries = append(deliveries, d.ID)
+			return true
+		}
+		return false
+	})
+	s.webhookAttempts = slices.DeleteFunc(s.webhookAttempts, func(a database.WebhookDeliveryAttempt) bool {
+		return slices.Contains(deliveries, a.DeliveryID)
+	})
+}
+
+func (s *Store) deleteOAuthClients(match func(database.OauthClient) bool) {
+
+	var deleted []string
+	s.oauthClients = slices.DeleteFunc(s.oauthClients, func(c database.OauthClient) bool {
+		if match(c) {
+			deleted = append(deleted, c.ID)
+			return true
+		}
+		return false
+	})
+	gone := func(id string) bool { return slices.Contains(deleted, id) }
+
+	s.oauthCodes = slices.DeleteFunc(s.oauthCodes, func(c database.OauthAuthorizationCode) bool { return gone(c.ClientID) })
+	s.oauthConsents = slices.DeleteFunc(s.oauthConsents, func(c database.OauthConsent) bool { return gone(c.ClientID) })
+	s.oauthAccessTokens = slices.DeleteFunc(s.oauthAccessTokens, func(t database.OauthAccessToken) bool { return gone(t.ClientID) })
+	s.refreshTokens = slices.DeleteFunc(s.refreshTokens, func(t database.RefreshToken) bool {
+		return t.ClientID.Valid && gone(t.ClientID.String)
+	})
+}
+
+// clone copies every table, so rows updated in place through find do not
+// leak into the copy.
+func (t tables) clone() tables {
+	return tables{
+		users:                  slices.Clone(t.users),
+		chirps:                 slices.Clone(t.chirps),
+		chirpEvents:            slices.Clone(t.chirpEvents),
+		scheduledChirps:        slices.Clone(t.scheduledChirps),
+		drafts:                 slices.Clone(t.drafts),
+		bookmarks:              slices.Clone(t.bookmarks),
+		collections:            slices.Clone(t.collections),
+		collectionChirps:       slices.Clone(t.collectionChirps),
+		notifications:          slices.Clone(t.notifications),
+		mutedNotificationTypes: slices.Clone(t.mutedNotificationTypes),
+		webhookSubscriptions:   slices.Clone(t.webhookSubscriptions),
+		webhookDeliveries:      slices.Clone(t.webhookDeliveries),
+		webhookAttempts:        slices.Clone(t.webhookAttempts),
+		refreshTokens:          slices.Clone(t.refreshTokens),
+		apiKeys:                slices.Clone(t.apiKeys),
+		oauthClients:           slices.Clone(t.oauthClients),
+		oauthCodes:             slices.Clone(t.oauthCodes),
+		oauthConsents:          slices.Clone(t.oauthConsents),
+		oauthAccessTokens:      slices.Clone(t.oauthAccessTokens),
+		identities:             slices.Clone(t.identities),
+		oidcLoginStates:        slices.Clone(t.oidcLoginStates),
+	}
+}
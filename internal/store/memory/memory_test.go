@@ -0,0 +1,12 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/ghis9917/chirpy/internal/store"
+	"github.com/ghis9917/chirpy/internal/store/storetest"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store { return New() })
+}
@@ -0,0 +1,184 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// groupedActors is how many actors GetGroupedNotifications returns per
+// group.
+const groupedActors = 5
+
+func (s *Store) CreateNotification(ctx context.Context, arg database.CreateNotificationParams) (database.Notification, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return database.Notification{}, foreignKey("notifications_user_id_fkey")
+	}
+	if !s.userExists(arg.ActorID) {
+		return database.Notification{}, foreignKey("notifications_actor_id_fkey")
+	}
+	if arg.ChirpID.Valid && !s.chirpExists(arg.ChirpID.UUID) {
+		return database.Notification{}, foreignKey("notifications_chirp_id_fkey")
+	}
+
+	notification := database.Notification{
+		ID:        uuid.New(),
+		CreatedAt: s.now(),
+		UserID:    arg.UserID,
+		ActorID:   arg.ActorID,
+		Type:      arg.Type,
+		ChirpID:   arg.ChirpID,
+	}
+	s.notifications = append(s.notifications, notification)
+
+	return notification, nil
+}
+
+func (s *Store) GetNotificationByID(ctx context.Context, id uuid.UUID) (database.Notification, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notification, ok := find(s.notifications, func(n database.Notification) bool { return n.ID == id })
+	if !ok {
+		return database.Notification{}, sql.ErrNoRows
+	}
+
+	return *notification, nil
+}
+
+func (s *Store) GetGroupedNotifications(ctx context.Context, arg database.GetGroupedNotificationsParams) ([]database.GetGroupedNotificationsRow, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Walking newest first fills in latest_at and the most recent actors
+	// as each group is first seen.
+	groups := []database.GetGroupedNotificationsRow{}
+	for _, n := range slices.Backward(s.notifications) {
+		if n.UserID != arg.UserID {
+			continue
+		}
+
+		group, ok := find(groups, func(g database.GetGroupedNotificationsRow) bool {
+			return g.Type == n.Type && g.ChirpID == n.ChirpID
+		})
+		if !ok {
+			groups = append(groups, database.GetGroupedNotificationsRow{
+				Type:     n.Type,
+				ChirpID:  n.ChirpID,
+				LatestAt: n.CreatedAt,
+				ActorIds: []uuid.UUID{},
+			})
+			group = &groups[len(groups)-1]
+		}
+
+		group.Count++
+		if !n.ReadAt.Valid {
+			group.Unread++
+		}
+		if len(group.ActorIds) < groupedActors {
+			group.ActorIds = append(group.ActorIds, n.ActorID)
+		}
+	}
+
+	return page(groups, arg.Limit, arg.Offset), nil
+}
+
+func (s *Store) CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int64, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unread := filter(s.notifications, func(n database.Notification) bool {
+		return n.UserID == userID && !n.ReadAt.Valid
+	})
+
+	return int64(len(unread)), nil
+}
+
+func (s *Store) MarkNotificationsRead(ctx context.Context, arg database.MarkNotificationsReadParams) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for i := range s.notifications {
+		n := &s.notifications[i]
+		if n.UserID != arg.UserID || n.ReadAt.Valid {
+			continue
+		}
+		if arg.Type.Valid && n.Type != arg.Type.String {
+			continue
+		}
+		if arg.ChirpID.Valid && n.ChirpID != arg.ChirpID {
+			continue
+		}
+		n.ReadAt = nullTime(now)
+	}
+
+	return nil
+}
+
+func (s *Store) IsNotificationTypeMuted(ctx context.Context, arg database.IsNotificationTypeMutedParams) (bool, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Contains(s.mutedNotificationTypes, database.MutedNotificationType{
+		UserID: arg.UserID,
+		Type:   arg.Type,
+	}), nil
+}
+
+func (s *Store) GetMutedNotificationTypes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	types := []string{}
+	for _, m := range s.mutedNotificationTypes {
+		if m.UserID == userID {
+			types = append(types, m.Type)
+		}
+	}
+	slices.Sort(types)
+
+	return types, nil
+}
+
+func (s *Store) MuteNotificationType(ctx context.Context, arg database.MuteNotificationTypeParams) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return foreignKey("muted_notification_types_user_id_fkey")
+	}
+
+	muted := database.MutedNotificationType{UserID: arg.UserID, Type: arg.Type}
+	if !slices.Contains(s.mutedNotificationTypes, muted) {
+		s.mutedNotificationTypes = append(s.mutedNotificationTypes, muted)
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteMutedNotificationTypes(ctx context.Context, userID uuid.UUID) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mutedNotificationTypes = slices.DeleteFunc(s.mutedNotificationTypes, func(m database.MutedNotificationType) bool {
+		return m.UserID == userID
+	})
+
+	return nil
+}
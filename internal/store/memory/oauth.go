@@ -0,0 +1,252 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (s *Store) CreateOAuthClient(ctx context.Context, arg database.CreateOAuthClientParams) (database.OauthClient, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return database.OauthClient{}, foreignKey("oauth_clients_user_id_fkey")
+	}
+	if s.oauthClientExists(arg.ID) {
+		return database.OauthClient{}, unique("oauth_clients_pkey")
+	}
+
+	now := s.now()
+	client := database.OauthClient{
+		ID:           arg.ID,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		UserID:       arg.UserID,
+		Name:         arg.Name,
+		SecretHash:   arg.SecretHash,
+		RedirectUris: slices.Clone(arg.RedirectUris),
+		Scopes:       slices.Clone(arg.Scopes),
+	}
+	s.oauthClients = append(s.oauthClients, client)
+
+	return client, nil
+}
+
+func (s *Store) GetOAuthClientByID(ctx context.Context, id string) (database.OauthClient, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	client, ok := find(s.oauthClients, func(c database.OauthClient) bool { return c.ID == id })
+	if !ok {
+		return database.OauthClient{}, sql.ErrNoRows
+	}
+
+	return *client, nil
+}
+
+func (s *Store) GetOAuthClientsByUser(ctx context.Context, userID uuid.UUID) ([]database.OauthClient, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return filter(s.oauthClients, func(c database.OauthClient) bool { return c.UserID == userID }), nil
+}
+
+func (s *Store) DeleteOAuthClientByID(ctx context.Context, id string) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteOAuthClients(func(c database.OauthClient) bool { return c.ID == id })
+
+	return nil
+}
+
+func (s *Store) CreateAuthorizationCode(ctx context.Context, arg database.CreateAuthorizationCodeParams) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.oauthClientExists(arg.ClientID) {
+		return foreignKey("oauth_authorization_codes_client_id_fkey")
+	}
+	if !s.userExists(arg.UserID) {
+		return foreignKey("oauth_authorization_codes_user_id_fkey")
+	}
+	if slices.ContainsFunc(s.oauthCodes, func(c database.OauthAuthorizationCode) bool { return c.CodeHash == arg.CodeHash }) {
+		return unique("oauth_authorization_codes_pkey")
+	}
+
+	s.oauthCodes = append(s.oauthCodes, database.OauthAuthorizationCode{
+		CodeHash:      arg.CodeHash,
+		CreatedAt:     s.now(),
+		ClientID:      arg.ClientID,
+		UserID:        arg.UserID,
+		RedirectUri:   arg.RedirectUri,
+		Scopes:        slices.Clone(arg.Scopes),
+		CodeChallenge: arg.CodeChallenge,
+		ExpiresAt:     arg.ExpiresAt,
+	})
+
+	return nil
+}
+
+func (s *Store) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (database.OauthAuthorizationCode, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	code, ok := find(s.oauthCodes, func(c database.OauthAuthorizationCode) bool {
+		return c.CodeHash == codeHash && !c.UsedAt.Valid && c.ExpiresAt.After(now)
+	})
+	if !ok {
+		return database.OauthAuthorizationCode{}, sql.ErrNoRows
+	}
+
+	code.UsedAt = nullTime(now)
+
+	return *code, nil
+}
+
+func (s *Store) UpsertOAuthConsent(ctx context.Context, arg database.UpsertOAuthConsentParams) (database.OauthConsent, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if consent, ok := find(s.oauthConsents, func(c database.OauthConsent) bool {
+		return c.UserID == arg.UserID && c.ClientID == arg.ClientID
+	}); ok {
+		consent.Scopes = slices.Clone(arg.Scopes)
+		consent.UpdatedAt = now
+		return *consent, nil
+	}
+
+	if !s.userExists(arg.UserID) {
+		return database.OauthConsent{}, foreignKey("oauth_consents_user_id_fkey")
+	}
+	if !s.oauthClientExists(arg.ClientID) {
+		return database.OauthConsent{}, foreignKey("oauth_consents_client_id_fkey")
+	}
+
+	consent := database.OauthConsent{
+		UserID:    arg.UserID,
+		ClientID:  arg.ClientID,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Scopes:    slices.Clone(arg.Scopes),
+	}
+	s.oauthConsents = append(s.oauthConsents, consent)
+
+	return consent, nil
+}
+
+func (s *Store) GetOAuthConsent(ctx context.Context, arg database.GetOAuthConsentParams) (database.OauthConsent, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	consent, ok := find(s.oauthConsents, func(c database.OauthConsent) bool {
+		return c.UserID == arg.UserID && c.ClientID == arg.ClientID
+	})
+	if !ok {
+		return database.OauthConsent{}, sql.ErrNoRows
+	}
+
+	return *consent, nil
+}
+
+func (s *Store) GetOAuthConsentsByUser(ctx context.Context, userID uuid.UUID) ([]database.OauthConsent, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return filter(s.oauthConsents, func(c database.OauthConsent) bool { return c.UserID == userID }), nil
+}
+
+func (s *Store) DeleteOAuthConsent(ctx context.Context, arg database.DeleteOAuthConsentParams) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.oauthConsents = slices.DeleteFunc(s.oauthConsents, func(c database.OauthConsent) bool {
+		return c.UserID == arg.UserID && c.ClientID == arg.ClientID
+	})
+
+	return nil
+}
+
+func (s *Store) CreateOAuthAccessToken(ctx context.Context, arg database.CreateOAuthAccessTokenParams) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.oauthClientExists(arg.ClientID) {
+		return foreignKey("oauth_access_tokens_client_id_fkey")
+	}
+	if !s.userExists(arg.UserID) {
+		return foreignKey("oauth_access_tokens_user_id_fkey")
+	}
+	if slices.ContainsFunc(s.oauthAccessTokens, func(t database.OauthAccessToken) bool { return t.ID == arg.ID }) {
+		return unique("oauth_access_tokens_pkey")
+	}
+
+	s.oauthAccessTokens = append(s.oauthAccessTokens, database.OauthAccessToken{
+		ID:        arg.ID,
+		CreatedAt: s.now(),
+		ClientID:  arg.ClientID,
+		UserID:    arg.UserID,
+		Scopes:    slices.Clone(arg.Scopes),
+		ExpiresAt: arg.ExpiresAt,
+	})
+
+	return nil
+}
+
+func (s *Store) GetOAuthAccessToken(ctx context.Context, id string) (database.OauthAccessToken, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := find(s.oauthAccessTokens, func(t database.OauthAccessToken) bool { return t.ID == id })
+	if !ok {
+		return database.OauthAccessToken{}, sql.ErrNoRows
+	}
+
+	return *token, nil
+}
+
+func (s *Store) RevokeOAuthAccessToken(ctx context.Context, id string) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if token, ok := find(s.oauthAccessTokens, func(t database.OauthAccessToken) bool { return t.ID == id }); ok && !token.RevokedAt.Valid {
+		token.RevokedAt = nullTime(s.now())
+	}
+
+	return nil
+}
+
+func (s *Store) RevokeOAuthAccessTokensForClient(ctx context.Context, arg database.RevokeOAuthAccessTokensForClientParams) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for i := range s.oauthAccessTokens {
+		t := &s.oauthAccessTokens[i]
+		if t.UserID == arg.UserID && t.ClientID == arg.ClientID && !t.RevokedAt.Valid {
+			t.RevokedAt = nullTime(now)
+		}
+	}
+
+	return nil
+}
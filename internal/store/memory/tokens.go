@@ -0,0 +1,205 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// refreshTokenTTL matches the INTERVAL in the refresh token queries.
+const refreshTokenTTL = 60 * 24 * time.Hour
+
+// apiKeyTouchInterval matches the INTERVAL in TouchAPIKey.
+const apiKeyTouchInterval = time.Minute
+
+func (s *Store) createRefreshToken(token database.RefreshToken) (database.RefreshToken, error) {
+
+	if !s.userExists(token.UserID) {
+		return database.RefreshToken{}, foreignKey("refresh_tokens_user_id_fkey")
+	}
+	if token.ClientID.Valid && !s.oauthClientExists(token.ClientID.String) {
+		return database.RefreshToken{}, foreignKey("refresh_tokens_client_id_fkey")
+	}
+	if slices.ContainsFunc(s.refreshTokens, func(t database.RefreshToken) bool { return t.Token == token.Token }) {
+		return database.RefreshToken{}, unique("refresh_tokens_pkey")
+	}
+
+	now := s.now()
+	token.CreatedAt = now
+	token.UpdatedAt = now
+	token.ExpiresAt = now.Add(refreshTokenTTL)
+	if token.Scopes == nil {
+		token.Scopes = []string{}
+	}
+	s.refreshTokens = append(s.refreshTokens, token)
+
+	return token, nil
+}
+
+func (s *Store) CreateRefreshRoken(ctx context.Context, arg database.CreateRefreshRokenParams) (database.RefreshToken, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.createRefreshToken(database.RefreshToken{
+		Token:  arg.Token,
+		UserID: arg.UserID,
+	})
+}
+
+func (s *Store) CreateOAuthRefreshToken(ctx context.Context, arg database.CreateOAuthRefreshTokenParams) (database.RefreshToken, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.createRefreshToken(database.RefreshToken{
+		Token:    arg.Token,
+		UserID:   arg.UserID,
+		ClientID: arg.ClientID,
+		Scopes:   slices.Clone(arg.Scopes),
+	})
+}
+
+func (s *Store) GetRefreshToken(ctx context.Context, token string) (database.RefreshToken, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := find(s.refreshTokens, func(t database.RefreshToken) bool { return t.Token == token })
+	if !ok {
+		return database.RefreshToken{}, sql.ErrNoRows
+	}
+
+	return *t, nil
+}
+
+func (s *Store) RevokeRefreshToken(ctx context.Context, arg database.RevokeRefreshTokenParams) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := find(s.refreshTokens, func(t database.RefreshToken) bool { return t.Token == arg.Token }); ok {
+		t.RevokedAt = arg.RevokedAt
+		t.UpdatedAt = arg.RevokedAt.Time
+	}
+
+	return nil
+}
+
+func (s *Store) RevokeRefreshTokensForClient(ctx context.Context, arg database.RevokeRefreshTokensForClientParams) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for i := range s.refreshTokens {
+		t := &s.refreshTokens[i]
+		// NULL = NULL is not true in SQL, so first-party tokens never match.
+		if t.UserID == arg.UserID && t.ClientID.Valid && arg.ClientID.Valid &&
+			t.ClientID.String == arg.ClientID.String && !t.RevokedAt.Valid {
+			t.RevokedAt = nullTime(now)
+			t.UpdatedAt = now
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) CreateAPIKey(ctx context.Context, arg database.CreateAPIKeyParams) (database.ApiKey, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return database.ApiKey{}, foreignKey("api_keys_user_id_fkey")
+	}
+	if slices.ContainsFunc(s.apiKeys, func(k database.ApiKey) bool { return k.KeyHash == arg.KeyHash }) {
+		return database.ApiKey{}, unique("api_keys_key_hash_key")
+	}
+
+	now := s.now()
+	key := database.ApiKey{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    arg.UserID,
+		Name:      arg.Name,
+		Prefix:    arg.Prefix,
+		KeyHash:   arg.KeyHash,
+		Scopes:    slices.Clone(arg.Scopes),
+		ExpiresAt: arg.ExpiresAt,
+	}
+	s.apiKeys = append(s.apiKeys, key)
+
+	return key, nil
+}
+
+func (s *Store) GetAPIKeyByID(ctx context.Context, id uuid.UUID) (database.ApiKey, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := find(s.apiKeys, func(k database.ApiKey) bool { return k.ID == id })
+	if !ok {
+		return database.ApiKey{}, sql.ErrNoRows
+	}
+
+	return *key, nil
+}
+
+func (s *Store) GetAPIKeyByHash(ctx context.Context, keyHash string) (database.ApiKey, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := find(s.apiKeys, func(k database.ApiKey) bool { return k.KeyHash == keyHash })
+	if !ok {
+		return database.ApiKey{}, sql.ErrNoRows
+	}
+
+	return *key, nil
+}
+
+func (s *Store) GetAPIKeysByUser(ctx context.Context, userID uuid.UUID) ([]database.ApiKey, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return filter(s.apiKeys, func(k database.ApiKey) bool { return k.UserID == userID }), nil
+}
+
+func (s *Store) RevokeAPIKey(ctx context.Context, id uuid.UUID) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key, ok := find(s.apiKeys, func(k database.ApiKey) bool { return k.ID == id }); ok && !key.RevokedAt.Valid {
+		now := s.now()
+		key.RevokedAt = nullTime(now)
+		key.UpdatedAt = now
+	}
+
+	return nil
+}
+
+func (s *Store) TouchAPIKey(ctx context.Context, id uuid.UUID) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := find(s.apiKeys, func(k database.ApiKey) bool { return k.ID == id })
+	if !ok {
+		return nil
+	}
+
+	now := s.now()
+	if !key.LastUsedAt.Valid || key.LastUsedAt.Time.Before(now.Add(-apiKeyTouchInterval)) {
+		key.LastUsedAt = nullTime(now)
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (s *Store) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slices.ContainsFunc(s.users, func(u database.User) bool { return u.Email == arg.Email }) {
+		return database.User{}, unique("users_email_key")
+	}
+
+	now := s.now()
+	user := database.User{
+		ID:             uuid.New(),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Email:          arg.Email,
+		HashedPassword: arg.HashedPassword,
+	}
+	s.users = append(s.users, user)
+
+	return user, nil
+}
+
+func (s *Store) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := find(s.users, func(u database.User) bool { return u.ID == id })
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+
+	return *user, nil
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (database.User, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := find(s.users, func(u database.User) bool { return u.Email == email })
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+
+	return *user, nil
+}
+
+func (s *Store) GetUserCredentialsByEmail(ctx context.Context, email string) (database.User, error) {
+	return s.GetUserByEmail(ctx, email)
+}
+
+func (s *Store) UpdateUser(ctx context.Context, arg database.UpdateUserParams) (database.User, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := find(s.users, func(u database.User) bool { return u.ID == arg.ID })
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	if slices.ContainsFunc(s.users, func(u database.User) bool { return u.Email == arg.Email && u.ID != arg.ID }) {
+		return database.User{}, unique("users_email_key")
+	}
+
+	user.Email = arg.Email
+	user.HashedPassword = arg.HashedPassword
+
+	return *user, nil
+}
+
+func (s *Store) UpgradeUser(ctx context.Context, id uuid.UUID) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, ok := find(s.users, func(u database.User) bool { return u.ID == id }); ok {
+		user.IsChirpyRed = true
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteAllUsers(ctx context.Context) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteUsers(func(database.User) bool { return true })
+
+	return nil
+}
+
+func (s *Store) DeleteUserByID(ctx context.Context, id uuid.UUID) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteUsers(func(u database.User) bool { return u.ID == id })
+
+	return nil
+}
@@ -0,0 +1,232 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (s *Store) CreateWebhookSubscription(ctx context.Context, arg database.CreateWebhookSubscriptionParams) (database.WebhookSubscription, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return database.WebhookSubscription{}, foreignKey("webhook_subscriptions_user_id_fkey")
+	}
+
+	now := s.now()
+	subscription := database.WebhookSubscription{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    arg.UserID,
+		Url:       arg.Url,
+		Secret:    arg.Secret,
+		Events:    slices.Clone(arg.Events),
+	}
+	s.webhookSubscriptions = append(s.webhookSubscriptions, subscription)
+
+	return subscription, nil
+}
+
+func (s *Store) GetWebhookSubscriptionByID(ctx context.Context, id uuid.UUID) (database.WebhookSubscription, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscription, ok := find(s.webhookSubscriptions, func(w database.WebhookSubscription) bool { return w.ID == id })
+	if !ok {
+		return database.WebhookSubscription{}, sql.ErrNoRows
+	}
+
+	return *subscription, nil
+}
+
+func (s *Store) GetWebhookSubscriptionsByUser(ctx context.Context, userID uuid.UUID) ([]database.WebhookSubscription, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return filter(s.webhookSubscriptions, func(w database.WebhookSubscription) bool { return w.UserID == userID }), nil
+}
+
+func (s *Store) DeleteWebhookSubscriptionByID(ctx context.Context, id uuid.UUID) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteWebhookSubscriptions(func(w database.WebhookSubscription) bool { return w.ID == id })
+
+	return nil
+}
+
+func (s *Store) EnqueueWebhookDeliveries(ctx context.Context, arg database.EnqueueWebhookDeliveriesParams) ([]uuid.UUID, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	ids := []uuid.UUID{}
+	for _, subscription := range s.webhookSubscriptions {
+		if subscription.UserID != arg.UserID || !slices.Contains(subscription.Events, arg.Event) {
+			continue
+		}
+		delivery := database.WebhookDelivery{
+			ID:             uuid.New(),
+			CreatedAt:      now,
+			UpdatedAt:      now,
+			SubscriptionID: subscription.ID,
+			Event:          arg.Event,
+			Payload:        arg.Payload,
+			Status:         "pending",
+			NextAttemptAt:  now,
+		}
+		s.webhookDeliveries = append(s.webhookDeliveries, delivery)
+		ids = append(ids, delivery.ID)
+	}
+
+	return ids, nil
+}
+
+func (s *Store) ClaimDueWebhookDeliveries(ctx context.Context, arg database.ClaimDueWebhookDeliveriesParams) ([]database.WebhookDelivery, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var due []*database.WebhookDelivery
+	for i := range s.webhookDeliveries {
+		d := &s.webhookDeliveries[i]
+		if d.Status == "pending" && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	slices.SortStableFunc(due, func(a, b *database.WebhookDelivery) int {
+		return a.NextAttemptAt.Compare(b.NextAttemptAt)
+	})
+
+	claimed := []database.WebhookDelivery{}
+	for _, d := range page(due, arg.BatchSize, 0) {
+		d.NextAttemptAt = arg.LeaseUntil
+		d.UpdatedAt = now
+		claimed = append(claimed, *d)
+	}
+
+	return claimed, nil
+}
+
+func (s *Store) MarkWebhookDeliverySucceeded(ctx context.Context, id uuid.UUID) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d, ok := find(s.webhookDeliveries, func(d database.WebhookDelivery) bool { return d.ID == id }); ok {
+		now := s.now()
+		d.Status = "succeeded"
+		d.Attempts++
+		d.DeliveredAt = nullTime(now)
+		d.UpdatedAt = now
+	}
+
+	return nil
+}
+
+func (s *Store) MarkWebhookDeliveryFailed(ctx context.Context, arg database.MarkWebhookDeliveryFailedParams) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d, ok := find(s.webhookDeliveries, func(d database.WebhookDelivery) bool { return d.ID == arg.ID }); ok {
+		d.Status = arg.Status
+		d.Attempts++
+		d.NextAttemptAt = arg.NextAttemptAt
+		d.UpdatedAt = s.now()
+	}
+
+	return nil
+}
+
+func (s *Store) RedeliverWebhookDelivery(ctx context.Context, id uuid.UUID) (database.WebhookDelivery, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := find(s.webhookDeliveries, func(d database.WebhookDelivery) bool { return d.ID == id })
+	if !ok {
+		return database.WebhookDelivery{}, sql.ErrNoRows
+	}
+
+	now := s.now()
+	d.Status = "pending"
+	d.Attempts = 0
+	d.NextAttemptAt = now
+	d.DeliveredAt = sql.NullTime{}
+	d.UpdatedAt = now
+
+	return *d, nil
+}
+
+func (s *Store) GetWebhookDeliveryByID(ctx context.Context, id uuid.UUID) (database.WebhookDelivery, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := find(s.webhookDeliveries, func(d database.WebhookDelivery) bool { return d.ID == id })
+	if !ok {
+		return database.WebhookDelivery{}, sql.ErrNoRows
+	}
+
+	return *d, nil
+}
+
+func (s *Store) GetWebhookDeliveries(ctx context.Context, arg database.GetWebhookDeliveriesParams) ([]database.WebhookDelivery, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deliveries := []database.WebhookDelivery{}
+	for _, d := range slices.Backward(s.webhookDeliveries) {
+		if d.SubscriptionID != arg.SubscriptionID {
+			continue
+		}
+		if arg.Status.Valid && d.Status != arg.Status.String {
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return page(deliveries, arg.RowLimit, arg.RowOffset), nil
+}
+
+func (s *Store) CreateWebhookDeliveryAttempt(ctx context.Context, arg database.CreateWebhookDeliveryAttemptParams) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !slices.ContainsFunc(s.webhookDeliveries, func(d database.WebhookDelivery) bool { return d.ID == arg.DeliveryID }) {
+		return foreignKey("webhook_delivery_attempts_delivery_id_fkey")
+	}
+
+	s.webhookAttempts = append(s.webhookAttempts, database.WebhookDeliveryAttempt{
+		ID:         uuid.New(),
+		CreatedAt:  s.now(),
+		DeliveryID: arg.DeliveryID,
+		StatusCode: arg.StatusCode,
+		Error:      arg.Error,
+		DurationMs: arg.DurationMs,
+	})
+
+	return nil
+}
+
+func (s *Store) GetWebhookDeliveryAttempts(ctx context.Context, deliveryID uuid.UUID) ([]database.WebhookDeliveryAttempt, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return filter(s.webhookAttempts, func(a database.WebhookDeliveryAttempt) bool { return a.DeliveryID == deliveryID }), nil
+}
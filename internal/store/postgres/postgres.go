@@ -0,0 +1,67 @@
+// Package postgres implements store.Store on top of the sqlc queries.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/store"
+	"github.com/ghis9917/chirpy/internal/tracing"
+	"github.com/lib/pq"
+)
+
+// MaxAttempts bounds how often InTx runs a transaction that keeps failing to
+// serialize.
+const MaxAttempts = 3
+
+type Store struct {
+	*database.Queries
+	db *sql.DB
+}
+
+var _ store.Store = (*Store)(nil)
+
+func New(db *sql.DB) *Store {
+	return &Store{
+		Queries: database.New(tracing.DB(db)),
+		db:      db,
+	}
+}
+
+// InTx runs fn in a serializable transaction. Serialization failures and
+// deadlocks roll back and run fn again, up to MaxAttempts times.
+func (s *Store) InTx(ctx context.Context, fn func(q store.Queries) error) error {
+	return store.Retry(ctx, MaxAttempts, retryable, func() error {
+
+		tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := fn(database.New(tracing.DB(tx))); err != nil {
+			return err
+		}
+
+		return tx.Commit()
+	})
+}
+
+// retryable reports whether err means the transaction lost a race with
+// another one and can simply be run again.
+func retryable(err error) bool {
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+
+	switch pqErr.Code {
+	case "40001", "40P01": // serialization_failure, deadlock_detected
+		return true
+	}
+
+	return false
+}
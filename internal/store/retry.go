@@ -0,0 +1,32 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// RetryDelay is multiplied by the attempt number between retries.
+const RetryDelay = 10 * time.Millisecond
+
+// Retry calls fn until it succeeds, fails with an error retryable does not
+// accept, or has been called attempts times, and returns the last error.
+// Backends use it to run transactions that lost a race with another one
+// again.
+func Retry(ctx context.Context, attempts int, retryable func(error) bool, fn func() error) error {
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = fn()
+		if err == nil || !retryable(err) || attempt == attempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * RetryDelay):
+		}
+	}
+
+	return err
+}
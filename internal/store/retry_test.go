@@ -0,0 +1,41 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRetry(t *testing.T) {
+	conflict := errors.New("conflict")
+	other := errors.New("constraint violated")
+	retryable := func(err error) bool { return errors.Is(err, conflict) }
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "success", errs: []error{nil}, wantCalls: 1},
+		{name: "retried", errs: []error{conflict, nil}, wantCalls: 2},
+		{name: "not retryable", errs: []error{other}, wantCalls: 1, wantErr: other},
+		{name: "gives up", errs: []error{conflict, conflict, conflict, nil}, wantCalls: 3, wantErr: conflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := Retry(context.Background(), 3, retryable, func() error {
+				calls++
+				return tt.errs[calls-1]
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Retry() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
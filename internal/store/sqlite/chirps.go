@@ -0,0 +1,84 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/database/sqlitedb"
+	"github.com/google/uuid"
+)
+
+func (q queries) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	chirp, err := q.q.CreateChirp(ctx, sqlitedb.CreateChirpParams(arg))
+	return one(chirp, err, toChirp)
+}
+
+func (q queries) GetChirpByID(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	chirp, err := q.q.GetChirpByID(ctx, id)
+	return one(chirp, err, toChirp)
+}
+
+func (q queries) GetAllChirps(ctx context.Context) ([]database.Chirp, error) {
+	chirps, err := q.q.GetAllChirps(ctx)
+	return many(chirps, err, toChirp)
+}
+
+func (q queries) GetAllChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	chirps, err := q.q.GetAllChirpsByAuthor(ctx, userID)
+	return many(chirps, err, toChirp)
+}
+
+func (q queries) DeleteChirpByID(ctx context.Context, id uuid.UUID) error {
+	return q.q.DeleteChirpByID(ctx, id)
+}
+
+func (q queries) CreateScheduledChirp(ctx context.Context, arg database.CreateScheduledChirpParams) (database.ScheduledChirp, error) {
+	scheduled, err := q.q.CreateScheduledChirp(ctx, sqlitedb.CreateScheduledChirpParams(arg))
+	return one(scheduled, err, toScheduledChirp)
+}
+
+func (q queries) GetScheduledChirpByID(ctx context.Context, id uuid.UUID) (database.ScheduledChirp, error) {
+	scheduled, err := q.q.GetScheduledChirpByID(ctx, id)
+	return one(scheduled, err, toScheduledChirp)
+}
+
+func (q queries) GetScheduledChirpsByUser(ctx context.Context, userID uuid.UUID) ([]database.ScheduledChirp, error) {
+	scheduled, err := q.q.GetScheduledChirpsByUser(ctx, userID)
+	return many(scheduled, err, toScheduledChirp)
+}
+
+func (q queries) DeleteScheduledChirpByID(ctx context.Context, id uuid.UUID) error {
+	return q.q.DeleteScheduledChirpByID(ctx, id)
+}
+
+// ClaimDueScheduledChirp has no row lock to take: the transaction it runs in
+// already holds the database write lock.
+func (q queries) ClaimDueScheduledChirp(ctx context.Context, attempts int32) (database.ScheduledChirp, error) {
+	scheduled, err := q.q.ClaimDueScheduledChirp(ctx, attempts)
+	return one(scheduled, err, toScheduledChirp)
+}
+
+func (q queries) RecordScheduledChirpFailure(ctx context.Context, arg database.RecordScheduledChirpFailureParams) error {
+	return q.q.RecordScheduledChirpFailure(ctx, sqlitedb.RecordScheduledChirpFailureParams(arg))
+}
+
+func (q queries) GetChirpEventByID(ctx context.Context, id int64) (database.ChirpEvent, error) {
+	event, err := q.q.GetChirpEventByID(ctx, id)
+	return one(event, err, toChirpEvent)
+}
+
+func (q queries) GetChirpEventsSince(ctx context.Context, arg database.GetChirpEventsSinceParams) ([]database.ChirpEvent, error) {
+	events, err := q.q.GetChirpEventsSince(
+		ctx,
+		sqlitedb.GetChirpEventsSinceParams{
+			ID:    arg.ID,
+			Limit: int64(arg.Limit),
+		},
+	)
+	return many(events, err, toChirpEvent)
+}
+
+func (q queries) DeleteChirpEventsBefore(ctx context.Context, createdAt time.Time) error {
+	return q.q.DeleteChirpEventsBefore(ctx, createdAt)
+}
@@ -0,0 +1,84 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/database/sqlitedb"
+	"github.com/google/uuid"
+)
+
+func (q queries) CreateBookmark(ctx context.Context, arg database.CreateBookmarkParams) error {
+	return q.q.CreateBookmark(ctx, sqlitedb.CreateBookmarkParams(arg))
+}
+
+func (q queries) DeleteBookmark(ctx context.Context, arg database.DeleteBookmarkParams) error {
+	return q.q.DeleteBookmark(ctx, sqlitedb.DeleteBookmarkParams(arg))
+}
+
+func (q queries) GetBookmarkedChirps(ctx context.Context, arg database.GetBookmarkedChirpsParams) ([]database.Chirp, error) {
+	chirps, err := q.q.GetBookmarkedChirps(
+		ctx,
+		sqlitedb.GetBookmarkedChirpsParams{
+			UserID: arg.UserID,
+			Limit:  int64(arg.Limit),
+			Offset: int64(arg.Offset),
+		},
+	)
+	return many(chirps, err, toChirp)
+}
+
+func (q queries) GetBookmarkedChirpIDs(ctx context.Context, arg database.GetBookmarkedChirpIDsParams) ([]uuid.UUID, error) {
+	return q.q.GetBookmarkedChirpIDs(ctx, sqlitedb.GetBookmarkedChirpIDsParams(arg))
+}
+
+func (q queries) CreateCollection(ctx context.Context, arg database.CreateCollectionParams) (database.Collection, error) {
+	collection, err := q.q.CreateCollection(ctx, sqlitedb.CreateCollectionParams(arg))
+	return one(collection, err, toCollection)
+}
+
+func (q queries) GetCollectionByID(ctx context.Context, id uuid.UUID) (database.Collection, error) {
+	collection, err := q.q.GetCollectionByID(ctx, id)
+	return one(collection, err, toCollection)
+}
+
+func (q queries) GetCollectionsByUser(ctx context.Context, arg database.GetCollectionsByUserParams) ([]database.Collection, error) {
+	collections, err := q.q.GetCollectionsByUser(
+		ctx,
+		sqlitedb.GetCollectionsByUserParams{
+			UserID: arg.UserID,
+			Limit:  int64(arg.Limit),
+			Offset: int64(arg.Offset),
+		},
+	)
+	return many(collections, err, toCollection)
+}
+
+func (q queries) RenameCollection(ctx context.Context, arg database.RenameCollectionParams) (database.Collection, error) {
+	collection, err := q.q.RenameCollection(ctx, sqlitedb.RenameCollectionParams(arg))
+	return one(collection, err, toCollection)
+}
+
+func (q queries) DeleteCollectionByID(ctx context.Context, id uuid.UUID) error {
+	return q.q.DeleteCollectionByID(ctx, id)
+}
+
+func (q queries) AddChirpToCollection(ctx context.Context, arg database.AddChirpToCollectionParams) error {
+	return q.q.AddChirpToCollection(ctx, sqlitedb.AddChirpToCollectionParams(arg))
+}
+
+func (q queries) RemoveChirpFromCollection(ctx context.Context, arg database.RemoveChirpFromCollectionParams) error {
+	return q.q.RemoveChirpFromCollection(ctx, sqlitedb.RemoveChirpFromCollectionParams(arg))
+}
+
+func (q queries) GetCollectionChirps(ctx context.Context, arg database.GetCollectionChirpsParams) ([]database.Chirp, error) {
+	chirps, err := q.q.GetCollectionChirps(
+		ctx,
+		sqlitedb.GetCollectionChirpsParams{
+			CollectionID: arg.CollectionID,
+			Limit:        int64(arg.Limit),
+			Offset:       int64(arg.Offset),
+		},
+	)
+	return many(chirps, err, toChirp)
+}
@@ -0,0 +1,265 @@
+package sqlite
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/database/sqlitedb"
+	"github.com/google/uuid"
+)
+
+// The sqlc models for SQLite differ from the Postgres ones only where
+// Postgres has arrays; every other row converts directly.
+
+// one converts the row of a :one query.
+func one[T, U any](row T, err error, convert func(T) (U, error)) (U, error) {
+
+	if err != nil {
+		var zero U
+		return zero, err
+	}
+
+	return convert(row)
+}
+
+// many converts the rows of a :many query, keeping sqlc's nil for none.
+func many[T, U any](rows []T, err error, convert func(T) (U, error)) ([]U, error) {
+
+	if err != nil {
+		return nil, err
+	}
+
+	var converted []U
+	for _, row := range rows {
+		c, err := convert(row)
+		if err != nil {
+			return nil, err
+		}
+		converted = append(converted, c)
+	}
+
+	return converted, nil
+}
+
+func encodeList(list []string) string {
+
+	if list == nil {
+		list = []string{}
+	}
+
+	data, _ := json.Marshal(list)
+	return string(data)
+}
+
+func decodeList[T any](column, data string) ([]T, error) {
+
+	var list []T
+	if err := json.Unmarshal([]byte(data), &list); err != nil {
+		return nil, fmt.Errorf("sqlite: decoding %s: %w", column, err)
+	}
+
+	return list, nil
+}
+
+func toUser(u sqlitedb.User) (database.User, error) {
+	return database.User(u), nil
+}
+
+func toChirp(c sqlitedb.Chirp) (database.Chirp, error) {
+	return database.Chirp(c), nil
+}
+
+func toChirpEvent(e sqlitedb.ChirpEvent) (database.ChirpEvent, error) {
+	return database.ChirpEvent(e), nil
+}
+
+func toScheduledChirp(c sqlitedb.ScheduledChirp) (database.ScheduledChirp, error) {
+	return database.ScheduledChirp(c), nil
+}
+
+func toDraft(d sqlitedb.Draft) (database.Draft, error) {
+	return database.Draft(d), nil
+}
+
+func toCollection(c sqlitedb.Collection) (database.Collection, error) {
+	return database.Collection(c), nil
+}
+
+func toNotification(n sqlitedb.Notification) (database.Notification, error) {
+	return database.Notification(n), nil
+}
+
+func toGroupedNotification(r sqlitedb.GetGroupedNotificationsRow) (database.GetGroupedNotificationsRow, error) {
+
+	latestAt, err := time.Parse(timeFormat, r.LatestAt)
+	if err != nil {
+		return database.GetGroupedNotificationsRow{}, fmt.Errorf("sqlite: decoding latest_at: %w", err)
+	}
+	actorIDs, err := decodeList[uuid.UUID]("actor_ids", r.ActorIds)
+	if err != nil {
+		return database.GetGroupedNotificationsRow{}, err
+	}
+
+	return database.GetGroupedNotificationsRow{
+		Type:     r.Type,
+		ChirpID:  r.ChirpID,
+		Count:    int32(r.Count),
+		Unread:   int32(r.Unread),
+		LatestAt: latestAt.UTC(),
+		ActorIds: actorIDs,
+	}, nil
+}
+
+func toWebhookSubscription(s sqlitedb.WebhookSubscription) (database.WebhookSubscription, error) {
+
+	events, err := decodeList[string]("events", s.Events)
+	if err != nil {
+		return database.WebhookSubscription{}, err
+	}
+
+	return database.WebhookSubscription{
+		ID:        s.ID,
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+		UserID:    s.UserID,
+		Url:       s.Url,
+		Secret:    s.Secret,
+		Events:    events,
+	}, nil
+}
+
+func toWebhookDelivery(d sqlitedb.WebhookDelivery) (database.WebhookDelivery, error) {
+	return database.WebhookDelivery(d), nil
+}
+
+func toWebhookDeliveryAttempt(a sqlitedb.WebhookDeliveryAttempt) (database.WebhookDeliveryAttempt, error) {
+	return database.WebhookDeliveryAttempt(a), nil
+}
+
+func toRefreshToken(t sqlitedb.RefreshToken) (database.RefreshToken, error) {
+
+	scopes, err := decodeList[string]("scopes", t.Scopes)
+	if err != nil {
+		return database.RefreshToken{}, err
+	}
+
+	return database.RefreshToken{
+		Token:     t.Token,
+		CreatedAt: t.CreatedAt,
+		UpdatedAt: t.UpdatedAt,
+		UserID:    t.UserID,
+		ExpiresAt: t.ExpiresAt,
+		RevokedAt: t.RevokedAt,
+		ClientID:  t.ClientID,
+		Scopes:    scopes,
+	}, nil
+}
+
+func toAPIKey(k sqlitedb.ApiKey) (database.ApiKey, error) {
+
+	scopes, err := decodeList[string]("scopes", k.Scopes)
+	if err != nil {
+		return database.ApiKey{}, err
+	}
+
+	return database.ApiKey{
+		ID:         k.ID,
+		CreatedAt:  k.CreatedAt,
+		UpdatedAt:  k.UpdatedAt,
+		UserID:     k.UserID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		KeyHash:    k.KeyHash,
+		Scopes:     scopes,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+	}, nil
+}
+
+func toOAuthClient(c sqlitedb.OauthClient) (database.OauthClient, error) {
+
+	redirectURIs, err := decodeList[string]("redirect_uris", c.RedirectUris)
+	if err != nil {
+		return database.OauthClient{}, err
+	}
+	scopes, err := decodeList[string]("scopes", c.Scopes)
+	if err != nil {
+		return database.OauthClient{}, err
+	}
+
+	return database.OauthClient{
+		ID:           c.ID,
+		CreatedAt:    c.CreatedAt,
+		UpdatedAt:    c.UpdatedAt,
+		UserID:       c.UserID,
+		Name:         c.Name,
+		SecretHash:   c.SecretHash,
+		RedirectUris: redirectURIs,
+		Scopes:       scopes,
+	}, nil
+}
+
+func toAuthorizationCode(c sqlitedb.OauthAuthorizationCode) (database.OauthAuthorizationCode, error) {
+
+	scopes, err := decodeList[string]("scopes", c.Scopes)
+	if err != nil {
+		return database.OauthAuthorizationCode{}, err
+	}
+
+	return database.OauthAuthorizationCode{
+		CodeHash:      c.CodeHash,
+		CreatedAt:     c.CreatedAt,
+		ClientID:      c.ClientID,
+		UserID:        c.UserID,
+		RedirectUri:   c.RedirectUri,
+		Scopes:        scopes,
+		CodeChallenge: c.CodeChallenge,
+		ExpiresAt:     c.ExpiresAt,
+		UsedAt:        c.UsedAt,
+	}, nil
+}
+
+func toOAuthConsent(c sqlitedb.OauthConsent) (database.OauthConsent, error) {
+
+	scopes, err := decodeList[string]("scopes", c.Scopes)
+	if err != nil {
+		return database.OauthConsent{}, err
+	}
+
+	return database.OauthConsent{
+		UserID:    c.UserID,
+		ClientID:  c.ClientID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		Scopes:    scopes,
+	}, nil
+}
+
+func toOAuthAccessToken(t sqlitedb.OauthAccessToken) (database.OauthAccessToken, error) {
+
+	scopes, err := decodeList[string]("scopes", t.Scopes)
+	if err != nil {
+		return database.OauthAccessToken{}, err
+	}
+
+	return database.OauthAccessToken{
+		ID:        t.ID,
+		CreatedAt: t.CreatedAt,
+		ClientID:  t.ClientID,
+		UserID:    t.UserID,
+		Scopes:    scopes,
+		ExpiresAt: t.ExpiresAt,
+		RevokedAt: t.RevokedAt,
+	}, nil
+}
+
+func toIdentity(i sqlitedb.Identity) (database.Identity, error) {
+	return database.Identity(i), nil
+}
+
+func toOIDCLoginState(s sqlitedb.OidcLoginState) (database.OidcLoginState, error) {
+	return database.OidcLoginState(s), nil
+}
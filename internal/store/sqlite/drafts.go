@@ -0,0 +1,38 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/database/sqlitedb"
+	"github.com/google/uuid"
+)
+
+func (q queries) CreateDraft(ctx context.Context, arg database.CreateDraftParams) (database.Draft, error) {
+	draft, err := q.q.CreateDraft(ctx, sqlitedb.CreateDraftParams(arg))
+	return one(draft, err, toDraft)
+}
+
+func (q queries) GetDraftByID(ctx context.Context, id uuid.UUID) (database.Draft, error) {
+	draft, err := q.q.GetDraftByID(ctx, id)
+	return one(draft, err, toDraft)
+}
+
+func (q queries) GetDraftsByUser(ctx context.Context, userID uuid.UUID) ([]database.Draft, error) {
+	drafts, err := q.q.GetDraftsByUser(ctx, userID)
+	return many(drafts, err, toDraft)
+}
+
+func (q queries) UpdateDraft(ctx context.Context, arg database.UpdateDraftParams) (database.Draft, error) {
+	draft, err := q.q.UpdateDraft(ctx, sqlitedb.UpdateDraftParams(arg))
+	return one(draft, err, toDraft)
+}
+
+func (q queries) DeleteDraftByID(ctx context.Context, id uuid.UUID) error {
+	return q.q.DeleteDraftByID(ctx, id)
+}
+
+func (q queries) ClaimDraft(ctx context.Context, arg database.ClaimDraftParams) (database.Draft, error) {
+	draft, err := q.q.ClaimDraft(ctx, sqlitedb.ClaimDraftParams(arg))
+	return one(draft, err, toDraft)
+}
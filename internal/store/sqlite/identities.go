@@ -0,0 +1,50 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/database/sqlitedb"
+	"github.com/google/uuid"
+)
+
+func (q queries) CreateIdentity(ctx context.Context, arg database.CreateIdentityParams) (database.Identity, error) {
+	identity, err := q.q.CreateIdentity(ctx, sqlitedb.CreateIdentityParams(arg))
+	return one(identity, err, toIdentity)
+}
+
+func (q queries) GetIdentityByID(ctx context.Context, id uuid.UUID) (database.Identity, error) {
+	identity, err := q.q.GetIdentityByID(ctx, id)
+	return one(identity, err, toIdentity)
+}
+
+func (q queries) GetIdentityBySubject(ctx context.Context, arg database.GetIdentityBySubjectParams) (database.Identity, error) {
+	identity, err := q.q.GetIdentityBySubject(ctx, sqlitedb.GetIdentityBySubjectParams(arg))
+	return one(identity, err, toIdentity)
+}
+
+func (q queries) GetIdentitiesByUser(ctx context.Context, userID uuid.UUID) ([]database.Identity, error) {
+	identities, err := q.q.GetIdentitiesByUser(ctx, userID)
+	return many(identities, err, toIdentity)
+}
+
+func (q queries) CountIdentitiesByUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return q.q.CountIdentitiesByUser(ctx, userID)
+}
+
+func (q queries) DeleteIdentityByID(ctx context.Context, id uuid.UUID) error {
+	return q.q.DeleteIdentityByID(ctx, id)
+}
+
+func (q queries) CreateOIDCLoginState(ctx context.Context, arg database.CreateOIDCLoginStateParams) error {
+	return q.q.CreateOIDCLoginState(ctx, sqlitedb.CreateOIDCLoginStateParams(arg))
+}
+
+func (q queries) ConsumeOIDCLoginState(ctx context.Context, stateHash string) (database.OidcLoginState, error) {
+	state, err := q.q.ConsumeOIDCLoginState(ctx, stateHash)
+	return one(state, err, toOIDCLoginState)
+}
+
+func (q queries) DeleteExpiredOIDCLoginStates(ctx context.Context) error {
+	return q.q.DeleteExpiredOIDCLoginStates(ctx)
+}
@@ -0,0 +1,56 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/database/sqlitedb"
+	"github.com/google/uuid"
+)
+
+func (q queries) CreateNotification(ctx context.Context, arg database.CreateNotificationParams) (database.Notification, error) {
+	notification, err := q.q.CreateNotification(ctx, sqlitedb.CreateNotificationParams(arg))
+	return one(notification, err, toNotification)
+}
+
+func (q queries) GetNotificationByID(ctx context.Context, id uuid.UUID) (database.Notification, error) {
+	notification, err := q.q.GetNotificationByID(ctx, id)
+	return one(notification, err, toNotification)
+}
+
+func (q queries) GetGroupedNotifications(ctx context.Context, arg database.GetGroupedNotificationsParams) ([]database.GetGroupedNotificationsRow, error) {
+	rows, err := q.q.GetGroupedNotifications(
+		ctx,
+		sqlitedb.GetGroupedNotificationsParams{
+			UserID: arg.UserID,
+			Limit:  int64(arg.Limit),
+			Offset: int64(arg.Offset),
+		},
+	)
+	return many(rows, err, toGroupedNotification)
+}
+
+func (q queries) CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return q.q.CountUnreadNotifications(ctx, userID)
+}
+
+func (q queries) MarkNotificationsRead(ctx context.Context, arg database.MarkNotificationsReadParams) error {
+	return q.q.MarkNotificationsRead(ctx, sqlitedb.MarkNotificationsReadParams(arg))
+}
+
+func (q queries) IsNotificationTypeMuted(ctx context.Context, arg database.IsNotificationTypeMutedParams) (bool, error) {
+	muted, err := q.q.IsNotificationTypeMuted(ctx, sqlitedb.IsNotificationTypeMutedParams(arg))
+	return muted != 0, err
+}
+
+func (q queries) GetMutedNotificationTypes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	return q.q.GetMutedNotificationTypes(ctx, userID)
+}
+
+func (q queries) MuteNotificationType(ctx context.Context, arg database.MuteNotificationTypeParams) error {
+	return q.q.MuteNotificationType(ctx, sqlitedb.MuteNotificationTypeParams(arg))
+}
+
+func (q queries) DeleteMutedNotificationTypes(ctx context.Context, userID uuid.UUID) error {
+	return q.q.DeleteMutedNotificationTypes(ctx, userID)
+}
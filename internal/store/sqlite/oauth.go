@@ -0,0 +1,110 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/database/sqlitedb"
+	"github.com/google/uuid"
+)
+
+func (q queries) CreateOAuthClient(ctx context.Context, arg database.CreateOAuthClientParams) (database.OauthClient, error) {
+	client, err := q.q.CreateOAuthClient(
+		ctx,
+		sqlitedb.CreateOAuthClientParams{
+			ID:           arg.ID,
+			UserID:       arg.UserID,
+			Name:         arg.Name,
+			SecretHash:   arg.SecretHash,
+			RedirectUris: encodeList(arg.RedirectUris),
+			Scopes:       encodeList(arg.Scopes),
+		},
+	)
+	return one(client, err, toOAuthClient)
+}
+
+func (q queries) GetOAuthClientByID(ctx context.Context, id string) (database.OauthClient, error) {
+	client, err := q.q.GetOAuthClientByID(ctx, id)
+	return one(client, err, toOAuthClient)
+}
+
+func (q queries) GetOAuthClientsByUser(ctx context.Context, userID uuid.UUID) ([]database.OauthClient, error) {
+	clients, err := q.q.GetOAuthClientsByUser(ctx, userID)
+	return many(clients, err, toOAuthClient)
+}
+
+func (q queries) DeleteOAuthClientByID(ctx context.Context, id string) error {
+	return q.q.DeleteOAuthClientByID(ctx, id)
+}
+
+func (q queries) CreateAuthorizationCode(ctx context.Context, arg database.CreateAuthorizationCodeParams) error {
+	return q.q.CreateAuthorizationCode(
+		ctx,
+		sqlitedb.CreateAuthorizationCodeParams{
+			CodeHash:      arg.CodeHash,
+			ClientID:      arg.ClientID,
+			UserID:        arg.UserID,
+			RedirectUri:   arg.RedirectUri,
+			Scopes:        encodeList(arg.Scopes),
+			CodeChallenge: arg.CodeChallenge,
+			ExpiresAt:     arg.ExpiresAt,
+		},
+	)
+}
+
+func (q queries) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (database.OauthAuthorizationCode, error) {
+	code, err := q.q.ConsumeAuthorizationCode(ctx, codeHash)
+	return one(code, err, toAuthorizationCode)
+}
+
+func (q queries) UpsertOAuthConsent(ctx context.Context, arg database.UpsertOAuthConsentParams) (database.OauthConsent, error) {
+	consent, err := q.q.UpsertOAuthConsent(
+		ctx,
+		sqlitedb.UpsertOAuthConsentParams{
+			UserID:   arg.UserID,
+			ClientID: arg.ClientID,
+			Scopes:   encodeList(arg.Scopes),
+		},
+	)
+	return one(consent, err, toOAuthConsent)
+}
+
+func (q queries) GetOAuthConsent(ctx context.Context, arg database.GetOAuthConsentParams) (database.OauthConsent, error) {
+	consent, err := q.q.GetOAuthConsent(ctx, sqlitedb.GetOAuthConsentParams(arg))
+	return one(consent, err, toOAuthConsent)
+}
+
+func (q queries) GetOAuthConsentsByUser(ctx context.Context, userID uuid.UUID) ([]database.OauthConsent, error) {
+	consents, err := q.q.GetOAuthConsentsByUser(ctx, userID)
+	return many(consents, err, toOAuthConsent)
+}
+
+func (q queries) DeleteOAuthConsent(ctx context.Context, arg database.DeleteOAuthConsentParams) error {
+	return q.q.DeleteOAuthConsent(ctx, sqlitedb.DeleteOAuthConsentParams(arg))
+}
+
+func (q queries) CreateOAuthAccessToken(ctx context.Context, arg database.CreateOAuthAccessTokenParams) error {
+	return q.q.CreateOAuthAccessToken(
+		ctx,
+		sqlitedb.CreateOAuthAccessTokenParams{
+			ID:        arg.ID,
+			ClientID:  arg.ClientID,
+			UserID:    arg.UserID,
+			Scopes:    encodeList(arg.Scopes),
+			ExpiresAt: arg.ExpiresAt,
+		},
+	)
+}
+
+func (q queries) GetOAuthAccessToken(ctx context.Context, id string) (database.OauthAccessToken, error) {
+	token, err := q.q.GetOAuthAccessToken(ctx, id)
+	return one(token, err, toOAuthAccessToken)
+}
+
+func (q queries) RevokeOAuthAccessToken(ctx context.Context, id string) error {
+	return q.q.RevokeOAuthAccessToken(ctx, id)
+}
+
+func (q queries) RevokeOAuthAccessTokensForClient(ctx context.Context, arg database.RevokeOAuthAccessTokensForClientParams) error {
+	return q.q.RevokeOAuthAccessTokensForClient(ctx, sqlitedb.RevokeOAuthAccessTokensForClientParams(arg))
+}
@@ -0,0 +1,147 @@
+// Package sqlite implements store.Store on top of the SQLite sqlc queries, so
+// the API can run locally from a single file instead of Postgres. The schema
+// mirrors the Postgres one: list columns hold JSON arrays and timestamps are
+// stored as UTC text.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/database/sqlitedb"
+	"github.com/ghis9917/chirpy/internal/store"
+	"github.com/ghis9917/chirpy/internal/tracing"
+	"github.com/google/uuid"
+	msqlite "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// MaxAttempts bounds how often InTx runs a transaction that keeps finding the
+// database locked by another connection.
+const MaxAttempts = 3
+
+// timeFormat is how the driver writes time arguments, and so how NOW() has
+// to write timestamps for the two to compare as text.
+const timeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+func init() {
+	// The queries keep the Postgres spelling of these two functions.
+	msqlite.MustRegisterScalarFunction("now", 0, func(*msqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+		return time.Now().UTC().Format(timeFormat), nil
+	})
+	msqlite.MustRegisterScalarFunction("gen_random_uuid", 0, func(*msqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+		return uuid.NewString(), nil
+	})
+}
+
+// Open opens the database file at path, creating it if needed. Foreign keys
+// are enforced, connections wait for each other's locks instead of failing
+// at once, and transactions take the write lock when they begin so two of
+// them never deadlock upgrading a read lock.
+func Open(path string) (*sql.DB, error) {
+
+	// Every connection in the pool would get its own in-memory database.
+	if path == "" || path == ":memory:" {
+		return nil, errors.New("sqlite: a database file is required")
+	}
+
+	return sql.Open(
+		"sqlite",
+		"file:"+path+
+			"?_pragma=foreign_keys(1)"+
+			"&_pragma=busy_timeout(5000)"+
+			"&_pragma=journal_mode(WAL)"+
+			"&_time_format=sqlite"+
+			"&_txlock=immediate",
+	)
+}
+
+type Store struct {
+	queries
+	db *sql.DB
+}
+
+var _ store.Store = (*Store)(nil)
+
+func New(db *sql.DB) *Store {
+	return &Store{
+		queries: newQueries(db),
+		db:      db,
+	}
+}
+
+// InTx runs fn in a transaction. A transaction that cannot get the write
+// lock in time rolls back and runs fn again, up to MaxAttempts times.
+func (s *Store) InTx(ctx context.Context, fn func(q store.Queries) error) error {
+	return store.Retry(ctx, MaxAttempts, retryable, func() error {
+
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := fn(newQueries(tx)); err != nil {
+			return err
+		}
+
+		return tx.Commit()
+	})
+}
+
+// retryable reports whether err means another connection held the lock for
+// longer than the busy timeout.
+func retryable(err error) bool {
+
+	var sqliteErr *msqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	return sqliteErr.Code()&0xff == sqlite3.SQLITE_BUSY
+}
+
+// queries adapts the SQLite queries to store.Queries.
+type queries struct {
+	q *sqlitedb.Queries
+}
+
+func newQueries(db sqlitedb.DBTX) queries {
+	return queries{q: sqlitedb.New(utcDB{tracing.SQLiteDB(db)})}
+}
+
+// utcDB writes every time argument in UTC. SQLite compares timestamps as
+// text, which only orders them correctly when they share an offset.
+type utcDB struct {
+	sqlitedb.DBTX
+}
+
+func (db utcDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.DBTX.ExecContext(ctx, query, utc(args)...)
+}
+
+func (db utcDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.DBTX.QueryContext(ctx, query, utc(args)...)
+}
+
+func (db utcDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.DBTX.QueryRowContext(ctx, query, utc(args)...)
+}
+
+func utc(args []interface{}) []interface{} {
+
+	for i, arg := range args {
+		switch t := arg.(type) {
+		case time.Time:
+			args[i] = t.UTC()
+		case sql.NullTime:
+			t.Time = t.Time.UTC()
+			args[i] = t
+		}
+	}
+
+	return args
+}
@@ -0,0 +1,73 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghis9917/chirpy/internal/migrate"
+	"github.com/ghis9917/chirpy/internal/store"
+	"github.com/ghis9917/chirpy/internal/store/storetest"
+	msqlite "modernc.org/sqlite"
+)
+
+func TestRetryable(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "chirpy.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Provoke a constraint error to check a non-busy *Error is not retried.
+	_, constraint := db.Exec("CREATE TABLE t (id INTEGER PRIMARY KEY); INSERT INTO t VALUES (1); INSERT INTO t VALUES (1)")
+	var sqliteErr *msqlite.Error
+	if !errors.As(constraint, &sqliteErr) {
+		t.Fatalf("duplicate insert error = %v, want a *sqlite.Error", constraint)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "constraint violation", err: fmt.Errorf("insert: %w", constraint)},
+		{name: "not a sqlite error", err: errors.New("disk full")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.err); got != tt.want {
+				t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenInMemory(t *testing.T) {
+	for _, path := range []string{"", ":memory:"} {
+		if _, err := Open(path); err == nil {
+			t.Errorf("Open(%q) error = nil, want an error", path)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		db, err := Open(filepath.Join(t.TempDir(), "chirpy.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		m, err := migrate.New(db, migrate.SQLite)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.Up(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return New(db)
+	})
+}
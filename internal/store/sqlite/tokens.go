@@ -0,0 +1,78 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/database/sqlitedb"
+	"github.com/google/uuid"
+)
+
+func (q queries) CreateRefreshRoken(ctx context.Context, arg database.CreateRefreshRokenParams) (database.RefreshToken, error) {
+	token, err := q.q.CreateRefreshRoken(ctx, sqlitedb.CreateRefreshRokenParams(arg))
+	return one(token, err, toRefreshToken)
+}
+
+func (q queries) CreateOAuthRefreshToken(ctx context.Context, arg database.CreateOAuthRefreshTokenParams) (database.RefreshToken, error) {
+	token, err := q.q.CreateOAuthRefreshToken(
+		ctx,
+		sqlitedb.CreateOAuthRefreshTokenParams{
+			Token:    arg.Token,
+			UserID:   arg.UserID,
+			ClientID: arg.ClientID,
+			Scopes:   encodeList(arg.Scopes),
+		},
+	)
+	return one(token, err, toRefreshToken)
+}
+
+func (q queries) GetRefreshToken(ctx context.Context, token string) (database.RefreshToken, error) {
+	refreshToken, err := q.q.GetRefreshToken(ctx, token)
+	return one(refreshToken, err, toRefreshToken)
+}
+
+func (q queries) RevokeRefreshToken(ctx context.Context, arg database.RevokeRefreshTokenParams) error {
+	return q.q.RevokeRefreshToken(ctx, sqlitedb.RevokeRefreshTokenParams(arg))
+}
+
+func (q queries) RevokeRefreshTokensForClient(ctx context.Context, arg database.RevokeRefreshTokensForClientParams) error {
+	return q.q.RevokeRefreshTokensForClient(ctx, sqlitedb.RevokeRefreshTokensForClientParams(arg))
+}
+
+func (q queries) CreateAPIKey(ctx context.Context, arg database.CreateAPIKeyParams) (database.ApiKey, error) {
+	key, err := q.q.CreateAPIKey(
+		ctx,
+		sqlitedb.CreateAPIKeyParams{
+			UserID:    arg.UserID,
+			Name:      arg.Name,
+			Prefix:    arg.Prefix,
+			KeyHash:   arg.KeyHash,
+			Scopes:    encodeList(arg.Scopes),
+			ExpiresAt: arg.ExpiresAt,
+		},
+	)
+	return one(key, err, toAPIKey)
+}
+
+func (q queries) GetAPIKeyByID(ctx context.Context, id uuid.UUID) (database.ApiKey, error) {
+	key, err := q.q.GetAPIKeyByID(ctx, id)
+	return one(key, err, toAPIKey)
+}
+
+func (q queries) GetAPIKeyByHash(ctx context.Context, keyHash string) (database.ApiKey, error) {
+	key, err := q.q.GetAPIKeyByHash(ctx, keyHash)
+	return one(key, err, toAPIKey)
+}
+
+func (q queries) GetAPIKeysByUser(ctx context.Context, userID uuid.UUID) ([]database.ApiKey, error) {
+	keys, err := q.q.GetAPIKeysByUser(ctx, userID)
+	return many(keys, err, toAPIKey)
+}
+
+func (q queries) RevokeAPIKey(ctx context.Context, id uuid.UUID) error {
+	return q.q.RevokeAPIKey(ctx, id)
+}
+
+func (q queries) TouchAPIKey(ctx context.Context, id uuid.UUID) error {
+	return q.q.TouchAPIKey(ctx, id)
+}
@@ -0,0 +1,46 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/database/sqlitedb"
+	"github.com/google/uuid"
+)
+
+func (q queries) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	user, err := q.q.CreateUser(ctx, sqlitedb.CreateUserParams(arg))
+	return one(user, err, toUser)
+}
+
+func (q queries) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, err := q.q.GetUserByID(ctx, id)
+	return one(user, err, toUser)
+}
+
+func (q queries) GetUserByEmail(ctx context.Context, email string) (database.User, error) {
+	user, err := q.q.GetUserByEmail(ctx, email)
+	return one(user, err, toUser)
+}
+
+func (q queries) GetUserCredentialsByEmail(ctx context.Context, email string) (database.User, error) {
+	user, err := q.q.GetUserCredentialsByEmail(ctx, email)
+	return one(user, err, toUser)
+}
+
+func (q queries) UpdateUser(ctx context.Context, arg database.UpdateUserParams) (database.User, error) {
+	user, err := q.q.UpdateUser(ctx, sqlitedb.UpdateUserParams(arg))
+	return one(user, err, toUser)
+}
+
+func (q queries) UpgradeUser(ctx context.Context, id uuid.UUID) error {
+	return q.q.UpgradeUser(ctx, id)
+}
+
+func (q queries) DeleteUserByID(ctx context.Context, id uuid.UUID) error {
+	return q.q.DeleteUserByID(ctx, id)
+}
+
+func (q queries) DeleteAllUsers(ctx context.Context) error {
+	return q.q.DeleteAllUsers(ctx)
+}
@@ -0,0 +1,91 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/database/sqlitedb"
+	"github.com/google/uuid"
+)
+
+func (q queries) CreateWebhookSubscription(ctx context.Context, arg database.CreateWebhookSubscriptionParams) (database.WebhookSubscription, error) {
+	subscription, err := q.q.CreateWebhookSubscription(
+		ctx,
+		sqlitedb.CreateWebhookSubscriptionParams{
+			UserID: arg.UserID,
+			Url:    arg.Url,
+			Secret: arg.Secret,
+			Events: encodeList(arg.Events),
+		},
+	)
+	return one(subscription, err, toWebhookSubscription)
+}
+
+func (q queries) GetWebhookSubscriptionByID(ctx context.Context, id uuid.UUID) (database.WebhookSubscription, error) {
+	subscription, err := q.q.GetWebhookSubscriptionByID(ctx, id)
+	return one(subscription, err, toWebhookSubscription)
+}
+
+func (q queries) GetWebhookSubscriptionsByUser(ctx context.Context, userID uuid.UUID) ([]database.WebhookSubscription, error) {
+	subscriptions, err := q.q.GetWebhookSubscriptionsByUser(ctx, userID)
+	return many(subscriptions, err, toWebhookSubscription)
+}
+
+func (q queries) DeleteWebhookSubscriptionByID(ctx context.Context, id uuid.UUID) error {
+	return q.q.DeleteWebhookSubscriptionByID(ctx, id)
+}
+
+func (q queries) EnqueueWebhookDeliveries(ctx context.Context, arg database.EnqueueWebhookDeliveriesParams) ([]uuid.UUID, error) {
+	return q.q.EnqueueWebhookDeliveries(ctx, sqlitedb.EnqueueWebhookDeliveriesParams(arg))
+}
+
+func (q queries) ClaimDueWebhookDeliveries(ctx context.Context, arg database.ClaimDueWebhookDeliveriesParams) ([]database.WebhookDelivery, error) {
+	deliveries, err := q.q.ClaimDueWebhookDeliveries(
+		ctx,
+		sqlitedb.ClaimDueWebhookDeliveriesParams{
+			LeaseUntil: arg.LeaseUntil,
+			BatchSize:  int64(arg.BatchSize),
+		},
+	)
+	return many(deliveries, err, toWebhookDelivery)
+}
+
+func (q queries) MarkWebhookDeliverySucceeded(ctx context.Context, id uuid.UUID) error {
+	return q.q.MarkWebhookDeliverySucceeded(ctx, id)
+}
+
+func (q queries) MarkWebhookDeliveryFailed(ctx context.Context, arg database.MarkWebhookDeliveryFailedParams) error {
+	return q.q.MarkWebhookDeliveryFailed(ctx, sqlitedb.MarkWebhookDeliveryFailedParams(arg))
+}
+
+func (q queries) RedeliverWebhookDelivery(ctx context.Context, id uuid.UUID) (database.WebhookDelivery, error) {
+	delivery, err := q.q.RedeliverWebhookDelivery(ctx, id)
+	return one(delivery, err, toWebhookDelivery)
+}
+
+func (q queries) GetWebhookDeliveryByID(ctx context.Context, id uuid.UUID) (database.WebhookDelivery, error) {
+	delivery, err := q.q.GetWebhookDeliveryByID(ctx, id)
+	return one(delivery, err, toWebhookDelivery)
+}
+
+func (q queries) GetWebhookDeliveries(ctx context.Context, arg database.GetWebhookDeliveriesParams) ([]database.WebhookDelivery, error) {
+	deliveries, err := q.q.GetWebhookDeliveries(
+		ctx,
+		sqlitedb.GetWebhookDeliveriesParams{
+			SubscriptionID: arg.SubscriptionID,
+			Status:         arg.Status,
+			RowLimit:       int64(arg.RowLimit),
+			RowOffset:      int64(arg.RowOffset),
+		},
+	)
+	return many(deliveries, err, toWebhookDelivery)
+}
+
+func (q queries) CreateWebhookDeliveryAttempt(ctx context.Context, arg database.CreateWebhookDeliveryAttemptParams) error {
+	return q.q.CreateWebhookDeliveryAttempt(ctx, sqlitedb.CreateWebhookDeliveryAttemptParams(arg))
+}
+
+func (q queries) GetWebhookDeliveryAttempts(ctx context.Context, deliveryID uuid.UUID) ([]database.WebhookDeliveryAttempt, error) {
+	attempts, err := q.q.GetWebhookDeliveryAttempts(ctx, deliveryID)
+	return many(attempts, err, toWebhookDeliveryAttempt)
+}
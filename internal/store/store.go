@@ -0,0 +1,184 @@
+// Package store defines the persistence interfaces the handlers and
+// background services depend on. The method sets mirror the sqlc queries, so
+// *database.Queries implements every one of them against Postgres; the
+// postgres package adds transactions on top, the sqlite package implements
+// them against a local file and the memory package in process for tests. The
+// cached package wraps any of them with a read-through cache.
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type UserStore interface {
+	CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error)
+	GetUserByEmail(ctx context.Context, email string) (database.User, error)
+	// GetUserCredentialsByEmail is GetUserByEmail for checking a password:
+	// it is never cached, so the user always comes with its password hash.
+	GetUserCredentialsByEmail(ctx context.Context, email string) (database.User, error)
+	UpdateUser(ctx context.Context, arg database.UpdateUserParams) (database.User, error)
+	UpgradeUser(ctx context.Context, id uuid.UUID) error
+	DeleteUserByID(ctx context.Context, id uuid.UUID) error
+	DeleteAllUsers(ctx context.Context) error
+}
+
+// ChirpStore covers published chirps, the ones waiting to be published and
+// the event log every change to a chirp is recorded in.
+type ChirpStore interface {
+	CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error)
+	GetChirpByID(ctx context.Context, id uuid.UUID) (database.Chirp, error)
+	GetAllChirps(ctx context.Context) ([]database.Chirp, error)
+	GetAllChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error)
+	DeleteChirpByID(ctx context.Context, id uuid.UUID) error
+
+	CreateScheduledChirp(ctx context.Context, arg database.CreateScheduledChirpParams) (database.ScheduledChirp, error)
+	GetScheduledChirpByID(ctx context.Context, id uuid.UUID) (database.ScheduledChirp, error)
+	GetScheduledChirpsByUser(ctx context.Context, userID uuid.UUID) ([]database.ScheduledChirp, error)
+	DeleteScheduledChirpByID(ctx context.Context, id uuid.UUID) error
+	ClaimDueScheduledChirp(ctx context.Context, attempts int32) (database.ScheduledChirp, error)
+	RecordScheduledChirpFailure(ctx context.Context, arg database.RecordScheduledChirpFailureParams) error
+
+	GetChirpEventByID(ctx context.Context, id int64) (database.ChirpEvent, error)
+	GetChirpEventsSince(ctx context.Context, arg database.GetChirpEventsSinceParams) ([]database.ChirpEvent, error)
+	DeleteChirpEventsBefore(ctx context.Context, createdAt time.Time) error
+}
+
+type DraftStore interface {
+	CreateDraft(ctx context.Context, arg database.CreateDraftParams) (database.Draft, error)
+	GetDraftByID(ctx context.Context, id uuid.UUID) (database.Draft, error)
+	GetDraftsByUser(ctx context.Context, userID uuid.UUID) ([]database.Draft, error)
+	UpdateDraft(ctx context.Context, arg database.UpdateDraftParams) (database.Draft, error)
+	DeleteDraftByID(ctx context.Context, id uuid.UUID) error
+	ClaimDraft(ctx context.Context, arg database.ClaimDraftParams) (database.Draft, error)
+}
+
+// CollectionStore covers bookmarks as well as named collections; both are
+// per-user lists of chirps.
+type CollectionStore interface {
+	CreateBookmark(ctx context.Context, arg database.CreateBookmarkParams) error
+	DeleteBookmark(ctx context.Context, arg database.DeleteBookmarkParams) error
+	GetBookmarkedChirps(ctx context.Context, arg database.GetBookmarkedChirpsParams) ([]database.Chirp, error)
+	GetBookmarkedChirpIDs(ctx context.Context, arg database.GetBookmarkedChirpIDsParams) ([]uuid.UUID, error)
+
+	CreateCollection(ctx context.Context, arg database.CreateCollectionParams) (database.Collection, error)
+	GetCollectionByID(ctx context.Context, id uuid.UUID) (database.Collection, error)
+	GetCollectionsByUser(ctx context.Context, arg database.GetCollectionsByUserParams) ([]database.Collection, error)
+	RenameCollection(ctx context.Context, arg database.RenameCollectionParams) (database.Collection, error)
+	DeleteCollectionByID(ctx context.Context, id uuid.UUID) error
+	AddChirpToCollection(ctx context.Context, arg database.AddChirpToCollectionParams) error
+	RemoveChirpFromCollection(ctx context.Context, arg database.RemoveChirpFromCollectionParams) error
+	GetCollectionChirps(ctx context.Context, arg database.GetCollectionChirpsParams) ([]database.Chirp, error)
+}
+
+type NotificationStore interface {
+	CreateNotification(ctx context.Context, arg database.CreateNotificationParams) (database.Notification, error)
+	GetNotificationByID(ctx context.Context, id uuid.UUID) (database.Notification, error)
+	GetGroupedNotifications(ctx context.Context, arg database.GetGroupedNotificationsParams) ([]database.GetGroupedNotificationsRow, error)
+	CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int64, error)
+	MarkNotificationsRead(ctx context.Context, arg database.MarkNotificationsReadParams) error
+
+	IsNotificationTypeMuted(ctx context.Context, arg database.IsNotificationTypeMutedParams) (bool, error)
+	GetMutedNotificationTypes(ctx context.Context, userID uuid.UUID) ([]string, error)
+	MuteNotificationType(ctx context.Context, arg database.MuteNotificationTypeParams) error
+	DeleteMutedNotificationTypes(ctx context.Context, userID uuid.UUID) error
+}
+
+type WebhookStore interface {
+	CreateWebhookSubscription(ctx context.Context, arg database.CreateWebhookSubscriptionParams) (database.WebhookSubscription, error)
+	GetWebhookSubscriptionByID(ctx context.Context, id uuid.UUID) (database.WebhookSubscription, error)
+	GetWebhookSubscriptionsByUser(ctx context.Context, userID uuid.UUID) ([]database.WebhookSubscription, error)
+	DeleteWebhookSubscriptionByID(ctx context.Context, id uuid.UUID) error
+
+	EnqueueWebhookDeliveries(ctx context.Context, arg database.EnqueueWebhookDeliveriesParams) ([]uuid.UUID, error)
+	ClaimDueWebhookDeliveries(ctx context.Context, arg database.ClaimDueWebhookDeliveriesParams) ([]database.WebhookDelivery, error)
+	MarkWebhookDeliverySucceeded(ctx context.Context, id uuid.UUID) error
+	MarkWebhookDeliveryFailed(ctx context.Context, arg database.MarkWebhookDeliveryFailedParams) error
+	RedeliverWebhookDelivery(ctx context.Context, id uuid.UUID) (database.WebhookDelivery, error)
+	GetWebhookDeliveryByID(ctx context.Context, id uuid.UUID) (database.WebhookDelivery, error)
+	GetWebhookDeliveries(ctx context.Context, arg database.GetWebhookDeliveriesParams) ([]database.WebhookDelivery, error)
+	CreateWebhookDeliveryAttempt(ctx context.Context, arg database.CreateWebhookDeliveryAttemptParams) error
+	GetWebhookDeliveryAttempts(ctx context.Context, deliveryID uuid.UUID) ([]database.WebhookDeliveryAttempt, error)
+}
+
+// TokenStore covers the credentials handed out to first-party clients:
+// refresh tokens and personal API keys.
+type TokenStore interface {
+	CreateRefreshRoken(ctx context.Context, arg database.CreateRefreshRokenParams) (database.RefreshToken, error)
+	CreateOAuthRefreshToken(ctx context.Context, arg database.CreateOAuthRefreshTokenParams) (database.RefreshToken, error)
+	GetRefreshToken(ctx context.Context, token string) (database.RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, arg database.RevokeRefreshTokenParams) error
+	RevokeRefreshTokensForClient(ctx context.Context, arg database.RevokeRefreshTokensForClientParams) error
+
+	CreateAPIKey(ctx context.Context, arg database.CreateAPIKeyParams) (database.ApiKey, error)
+	GetAPIKeyByID(ctx context.Context, id uuid.UUID) (database.ApiKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (database.ApiKey, error)
+	GetAPIKeysByUser(ctx context.Context, userID uuid.UUID) ([]database.ApiKey, error)
+	RevokeAPIKey(ctx context.Context, id uuid.UUID) error
+	TouchAPIKey(ctx context.Context, id uuid.UUID) error
+}
+
+// OAuthStore covers third-party clients and what users granted them.
+type OAuthStore interface {
+	CreateOAuthClient(ctx context.Context, arg database.CreateOAuthClientParams) (database.OauthClient, error)
+	GetOAuthClientByID(ctx context.Context, id string) (database.OauthClient, error)
+	GetOAuthClientsByUser(ctx context.Context, userID uuid.UUID) ([]database.OauthClient, error)
+	DeleteOAuthClientByID(ctx context.Context, id string) error
+
+	CreateAuthorizationCode(ctx context.Context, arg database.CreateAuthorizationCodeParams) error
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string) (database.OauthAuthorizationCode, error)
+
+	UpsertOAuthConsent(ctx context.Context, arg database.UpsertOAuthConsentParams) (database.OauthConsent, error)
+	GetOAuthConsent(ctx context.Context, arg database.GetOAuthConsentParams) (database.OauthConsent, error)
+	GetOAuthConsentsByUser(ctx context.Context, userID uuid.UUID) ([]database.OauthConsent, error)
+	DeleteOAuthConsent(ctx context.Context, arg database.DeleteOAuthConsentParams) error
+
+	CreateOAuthAccessToken(ctx context.Context, arg database.CreateOAuthAccessTokenParams) error
+	GetOAuthAccessToken(ctx context.Context, id string) (database.OauthAccessToken, error)
+	RevokeOAuthAccessToken(ctx context.Context, id string) error
+	RevokeOAuthAccessTokensForClient(ctx context.Context, arg database.RevokeOAuthAccessTokensForClientParams) error
+}
+
+// IdentityStore covers accounts at external identity providers and the
+// short-lived state of sign-ins in progress.
+type IdentityStore interface {
+	CreateIdentity(ctx context.Context, arg database.CreateIdentityParams) (database.Identity, error)
+	GetIdentityByID(ctx context.Context, id uuid.UUID) (database.Identity, error)
+	GetIdentityBySubject(ctx context.Context, arg database.GetIdentityBySubjectParams) (database.Identity, error)
+	GetIdentitiesByUser(ctx context.Context, userID uuid.UUID) ([]database.Identity, error)
+	CountIdentitiesByUser(ctx context.Context, userID uuid.UUID) (int64, error)
+	DeleteIdentityByID(ctx context.Context, id uuid.UUID) error
+
+	CreateOIDCLoginState(ctx context.Context, arg database.CreateOIDCLoginStateParams) error
+	ConsumeOIDCLoginState(ctx context.Context, stateHash string) (database.OidcLoginState, error)
+	DeleteExpiredOIDCLoginStates(ctx context.Context) error
+}
+
+// Queries is every query the API runs.
+type Queries interface {
+	UserStore
+	ChirpStore
+	DraftStore
+	CollectionStore
+	NotificationStore
+	WebhookStore
+	TokenStore
+	OAuthStore
+	IdentityStore
+}
+
+var _ Queries = (*database.Queries)(nil)
+
+// Store is everything the API needs from persistence.
+type Store interface {
+	Queries
+
+	// InTx runs fn in a transaction and commits it if fn returns nil. fn
+	// may be called more than once when the transaction has to be retried,
+	// so it must not have side effects outside q.
+	InTx(ctx context.Context, fn func(q Queries) error) error
+}
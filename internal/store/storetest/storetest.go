@@ -0,0 +1,939 @@
+// Package storetest is a conformance suite for store.Store. Every backend
+// runs it, so handlers see the same behaviour whichever one is configured.
+package storetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/store"
+	"github.com/google/uuid"
+)
+
+// Run runs the suite. open must return an empty, migrated store; it is
+// called once per test.
+func Run(t *testing.T, open func(t *testing.T) store.Store) {
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s store.Store)
+	}{
+		{"Users", testUsers},
+		{"Chirps", testChirps},
+		{"ChirpEvents", testChirpEvents},
+		{"ScheduledChirps", testScheduledChirps},
+		{"Drafts", testDrafts},
+		{"Bookmarks", testBookmarks},
+		{"Collections", testCollections},
+		{"Notifications", testNotifications},
+		{"Webhooks", testWebhooks},
+		{"RefreshTokens", testRefreshTokens},
+		{"APIKeys", testAPIKeys},
+		{"OAuth", testOAuth},
+		{"Identities", testIdentities},
+		{"InTx", testInTx},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, open(t))
+		})
+	}
+}
+
+// now is truncated to the microsecond precision of a Postgres TIMESTAMP so
+// times written and read back compare equal on every backend.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func createUser(t *testing.T, s store.Store, email string) database.User {
+	t.Helper()
+
+	user, err := s.CreateUser(context.Background(), database.CreateUserParams{Email: email, HashedPassword: "hash"})
+	if err != nil {
+		t.Fatalf("CreateUser(%s) error = %v", email, err)
+	}
+	return user
+}
+
+func createChirp(t *testing.T, s store.Store, userID uuid.UUID, body string) database.Chirp {
+	t.Helper()
+
+	chirp, err := s.CreateChirp(context.Background(), database.CreateChirpParams{Body: body, UserID: userID})
+	if err != nil {
+		t.Fatalf("CreateChirp(%q) error = %v", body, err)
+	}
+	return chirp
+}
+
+func wantNoRows(t *testing.T, name string, err error) {
+	t.Helper()
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("%s error = %v, want %v", name, err, sql.ErrNoRows)
+	}
+}
+
+func testUsers(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	user := createUser(t, s, "alice@example.com")
+	if user.ID == uuid.Nil || user.CreatedAt.IsZero() || user.IsChirpyRed {
+		t.Errorf("CreateUser() = %+v, want an ID, a creation time and no Chirpy Red", user)
+	}
+	if _, err := s.CreateUser(ctx, database.CreateUserParams{Email: "alice@example.com", HashedPassword: "hash"}); err == nil {
+		t.Error("CreateUser() with a taken email error = nil, want a unique violation")
+	}
+
+	if got, err := s.GetUserByEmail(ctx, "alice@example.com"); err != nil || got.ID != user.ID {
+		t.Errorf("GetUserByEmail() = %v, %v, want %v", got.ID, err, user.ID)
+	}
+	if got, err := s.GetUserCredentialsByEmail(ctx, "alice@example.com"); err != nil || got.ID != user.ID || got.HashedPassword != user.HashedPassword {
+		t.Errorf("GetUserCredentialsByEmail() = %+v, %v, want %+v with its hash", got, err, user)
+	}
+	_, err := s.GetUserCredentialsByEmail(ctx, "nobody@example.com")
+	wantNoRows(t, "GetUserCredentialsByEmail(unknown)", err)
+	_, err = s.GetUserByID(ctx, uuid.New())
+	wantNoRows(t, "GetUserByID(unknown)", err)
+
+	updated, err := s.UpdateUser(ctx, database.UpdateUserParams{Email: "alice@example.org", HashedPassword: "new", ID: user.ID})
+	if err != nil || updated.Email != "alice@example.org" || updated.HashedPassword != "new" {
+		t.Errorf("UpdateUser() = %+v, %v", updated, err)
+	}
+
+	if err := s.UpgradeUser(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.GetUserByID(ctx, user.ID); err != nil || !got.IsChirpyRed {
+		t.Errorf("GetUserByID() after UpgradeUser = %+v, %v, want Chirpy Red", got, err)
+	}
+
+	chirp := createChirp(t, s, user.ID, "users test chirp")
+	if err := s.DeleteUserByID(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.GetUserByID(ctx, user.ID)
+	wantNoRows(t, "GetUserByID(deleted)", err)
+	_, err = s.GetChirpByID(ctx, chirp.ID)
+	wantNoRows(t, "GetChirpByID(chirp of deleted user)", err)
+
+	createUser(t, s, "bob@example.com")
+	if err := s.DeleteAllUsers(ctx); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.GetUserByEmail(ctx, "bob@example.com")
+	wantNoRows(t, "GetUserByEmail() after DeleteAllUsers", err)
+}
+
+func testChirps(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	alice := createUser(t, s, "alice@example.com")
+	bob := createUser(t, s, "bob@example.com")
+
+	first := createChirp(t, s, alice.ID, "first")
+	second := createChirp(t, s, bob.ID, "second")
+	third := createChirp(t, s, alice.ID, "third")
+
+	if _, err := s.CreateChirp(ctx, database.CreateChirpParams{Body: "first", UserID: bob.ID}); err == nil {
+		t.Error("CreateChirp() with a duplicate body error = nil, want a unique violation")
+	}
+	if _, err := s.CreateChirp(ctx, database.CreateChirpParams{Body: "orphan", UserID: uuid.New()}); err == nil {
+		t.Error("CreateChirp() for an unknown user error = nil, want a foreign key violation")
+	}
+
+	all, err := s.GetAllChirps(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := chirpIDs(all), []uuid.UUID{first.ID, second.ID, third.ID}; !slices.Equal(got, want) {
+		t.Errorf("GetAllChirps() = %v, want %v in creation order", got, want)
+	}
+
+	byAlice, err := s.GetAllChirpsByAuthor(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := chirpIDs(byAlice), []uuid.UUID{first.ID, third.ID}; !slices.Equal(got, want) {
+		t.Errorf("GetAllChirpsByAuthor() = %v, want %v", got, want)
+	}
+
+	if got, err := s.GetChirpByID(ctx, second.ID); err != nil || got.Body != "second" || got.UserID != bob.ID {
+		t.Errorf("GetChirpByID() = %+v, %v", got, err)
+	}
+
+	if err := s.DeleteChirpByID(ctx, second.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.GetChirpByID(ctx, second.ID)
+	wantNoRows(t, "GetChirpByID(deleted)", err)
+}
+
+func testChirpEvents(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "alice@example.com")
+
+	chirp := createChirp(t, s, user.ID, "hello")
+	if err := s.DeleteChirpByID(ctx, chirp.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := s.GetChirpEventsSince(ctx, database.GetChirpEventsSinceParams{ID: 0, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, e := range events {
+		types = append(types, e.Type)
+		if e.ChirpID != chirp.ID || e.UserID != user.ID || e.Body != "hello" {
+			t.Errorf("event %d = %+v, want it to describe chirp %v", e.ID, e, chirp.ID)
+		}
+	}
+	if want := []string{"chirp.created", "chirp.deleted"}; !slices.Equal(types, want) {
+		t.Fatalf("GetChirpEventsSince() types = %v, want %v", types, want)
+	}
+	if events[0].ID >= events[1].ID {
+		t.Errorf("event IDs = %d, %d, want them increasing", events[0].ID, events[1].ID)
+	}
+
+	if got, err := s.GetChirpEventByID(ctx, events[1].ID); err != nil || got.Type != "chirp.deleted" {
+		t.Errorf("GetChirpEventByID() = %+v, %v", got, err)
+	}
+	_, err = s.GetChirpEventByID(ctx, events[1].ID+1)
+	wantNoRows(t, "GetChirpEventByID(unknown)", err)
+
+	since, err := s.GetChirpEventsSince(ctx, database.GetChirpEventsSinceParams{ID: events[0].ID, Limit: 10})
+	if err != nil || len(since) != 1 || since[0].ID != events[1].ID {
+		t.Errorf("GetChirpEventsSince(first) = %+v, %v, want only the second event", since, err)
+	}
+	limited, err := s.GetChirpEventsSince(ctx, database.GetChirpEventsSinceParams{ID: 0, Limit: 1})
+	if err != nil || len(limited) != 1 || limited[0].ID != events[0].ID {
+		t.Errorf("GetChirpEventsSince(limit 1) = %+v, %v, want only the first event", limited, err)
+	}
+
+	if err := s.DeleteChirpEventsBefore(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if left, err := s.GetChirpEventsSince(ctx, database.GetChirpEventsSinceParams{ID: 0, Limit: 10}); err != nil || len(left) != 0 {
+		t.Errorf("GetChirpEventsSince() after pruning = %+v, %v, want none", left, err)
+	}
+}
+
+func testScheduledChirps(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "alice@example.com")
+
+	later := now().Add(time.Hour)
+	earlier := now().Add(-2 * time.Minute)
+	due := now().Add(-time.Minute)
+
+	var ids []uuid.UUID
+	for _, at := range []time.Time{later, earlier, due} {
+		scheduled, err := s.CreateScheduledChirp(ctx, database.CreateScheduledChirpParams{Body: "at " + at.String(), UserID: user.ID, ScheduledAt: at})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !scheduled.ScheduledAt.Equal(at) || scheduled.Attempts != 0 {
+			t.Errorf("CreateScheduledChirp() = %+v, want it scheduled at %v", scheduled, at)
+		}
+		ids = append(ids, scheduled.ID)
+	}
+
+	byUser, err := s.GetScheduledChirpsByUser(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []uuid.UUID
+	for _, c := range byUser {
+		got = append(got, c.ID)
+	}
+	if want := []uuid.UUID{ids[1], ids[2], ids[0]}; !slices.Equal(got, want) {
+		t.Errorf("GetScheduledChirpsByUser() = %v, want %v ordered by time", got, want)
+	}
+
+	claimed, err := s.ClaimDueScheduledChirp(ctx, 2)
+	if err != nil || claimed.ID != ids[1] {
+		t.Fatalf("ClaimDueScheduledChirp() = %v, %v, want the earliest due %v", claimed.ID, err, ids[1])
+	}
+
+	for range 2 {
+		if err := s.RecordScheduledChirpFailure(
+			ctx,
+			database.RecordScheduledChirpFailureParams{
+				LastError:     sql.NullString{String: "boom", Valid: true},
+				NextAttemptAt: sql.NullTime{Time: now(), Valid: true},
+				ID:            ids[1],
+			},
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+	failed, err := s.GetScheduledChirpByID(ctx, ids[1])
+	if err != nil || failed.Attempts != 2 || failed.LastError.String != "boom" || !failed.NextAttemptAt.Valid {
+		t.Errorf("GetScheduledChirpByID() after failures = %+v, %v, want 2 attempts", failed, err)
+	}
+
+	if claimed, err := s.ClaimDueScheduledChirp(ctx, 2); err != nil || claimed.ID != ids[2] {
+		t.Errorf("ClaimDueScheduledChirp() = %v, %v, want %v once the first is out of attempts", claimed.ID, err, ids[2])
+	}
+
+	if err := s.RecordScheduledChirpFailure(
+		ctx,
+		database.RecordScheduledChirpFailureParams{
+			LastError:     sql.NullString{String: "boom", Valid: true},
+			NextAttemptAt: sql.NullTime{Time: now().Add(time.Hour), Valid: true},
+			ID:            ids[2],
+		},
+	); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.ClaimDueScheduledChirp(ctx, 2)
+	wantNoRows(t, "ClaimDueScheduledChirp() while backing off", err)
+
+	if err := s.DeleteScheduledChirpByID(ctx, ids[2]); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.ClaimDueScheduledChirp(ctx, 2)
+	wantNoRows(t, "ClaimDueScheduledChirp() with nothing due", err)
+}
+
+func testDrafts(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "alice@example.com")
+
+	first, err := s.CreateDraft(ctx, database.CreateDraftParams{Body: "first", UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.CreateDraft(ctx, database.CreateDraftParams{Body: "second", UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := s.UpdateDraft(ctx, database.UpdateDraftParams{Body: "first, edited", ID: first.ID})
+	if err != nil || updated.Body != "first, edited" || !updated.UpdatedAt.After(first.UpdatedAt) {
+		t.Errorf("UpdateDraft() = %+v, %v", updated, err)
+	}
+
+	drafts, err := s.GetDraftsByUser(ctx, user.ID)
+	if err != nil || len(drafts) != 2 || drafts[0].ID != first.ID || drafts[1].ID != second.ID {
+		t.Errorf("GetDraftsByUser() = %+v, %v, want the edited draft first", drafts, err)
+	}
+
+	if err := s.DeleteDraftByID(ctx, second.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.GetDraftByID(ctx, second.ID)
+	wantNoRows(t, "GetDraftByID(deleted)", err)
+
+	other := createUser(t, s, "bob@example.com")
+	_, err = s.ClaimDraft(ctx, database.ClaimDraftParams{ID: first.ID, UserID: other.ID})
+	wantNoRows(t, "ClaimDraft(someone else's)", err)
+	claimed, err := s.ClaimDraft(ctx, database.ClaimDraftParams{ID: first.ID, UserID: user.ID})
+	if err != nil || claimed.ID != first.ID || claimed.Body != "first, edited" {
+		t.Errorf("ClaimDraft() = %+v, %v, want the edited draft", claimed, err)
+	}
+	_, err = s.ClaimDraft(ctx, database.ClaimDraftParams{ID: first.ID, UserID: user.ID})
+	wantNoRows(t, "ClaimDraft(claimed)", err)
+}
+
+func testBookmarks(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "alice@example.com")
+	first := createChirp(t, s, user.ID, "first")
+	second := createChirp(t, s, user.ID, "second")
+	unmarked := createChirp(t, s, user.ID, "unmarked")
+
+	for _, id := range []uuid.UUID{first.ID, second.ID, first.ID} {
+		if err := s.CreateBookmark(ctx, database.CreateBookmarkParams{UserID: user.ID, ChirpID: id}); err != nil {
+			t.Fatalf("CreateBookmark() error = %v, want bookmarking twice to be a no-op", err)
+		}
+	}
+
+	bookmarked, err := s.GetBookmarkedChirps(ctx, database.GetBookmarkedChirpsParams{UserID: user.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := chirpIDs(bookmarked), []uuid.UUID{second.ID, first.ID}; !slices.Equal(got, want) {
+		t.Errorf("GetBookmarkedChirps() = %v, want %v newest first", got, want)
+	}
+	paged, err := s.GetBookmarkedChirps(ctx, database.GetBookmarkedChirpsParams{UserID: user.ID, Limit: 1, Offset: 1})
+	if got, want := chirpIDs(paged), []uuid.UUID{first.ID}; err != nil || !slices.Equal(got, want) {
+		t.Errorf("GetBookmarkedChirps(page 2) = %v, %v, want %v", got, err, want)
+	}
+
+	ids, err := s.GetBookmarkedChirpIDs(ctx, database.GetBookmarkedChirpIDsParams{UserID: user.ID, ChirpIds: []uuid.UUID{first.ID, unmarked.ID}})
+	if err != nil || !slices.Equal(ids, []uuid.UUID{first.ID}) {
+		t.Errorf("GetBookmarkedChirpIDs() = %v, %v, want only %v", ids, err, first.ID)
+	}
+	ids, err = s.GetBookmarkedChirpIDs(ctx, database.GetBookmarkedChirpIDsParams{UserID: user.ID, ChirpIds: []uuid.UUID{}})
+	if err != nil || len(ids) != 0 {
+		t.Errorf("GetBookmarkedChirpIDs(none) = %v, %v, want none", ids, err)
+	}
+
+	if err := s.DeleteBookmark(ctx, database.DeleteBookmarkParams{UserID: user.ID, ChirpID: second.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteChirpByID(ctx, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if left, err := s.GetBookmarkedChirps(ctx, database.GetBookmarkedChirpsParams{UserID: user.ID, Limit: 10}); err != nil || len(left) != 0 {
+		t.Errorf("GetBookmarkedChirps() = %v, %v, want none once unbookmarked or deleted", chirpIDs(left), err)
+	}
+}
+
+func testCollections(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "alice@example.com")
+	chirp := createChirp(t, s, user.ID, "collected")
+
+	reading, err := s.CreateCollection(ctx, database.CreateCollectionParams{UserID: user.ID, Name: "reading"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateCollection(ctx, database.CreateCollectionParams{UserID: user.ID, Name: "reading"}); err == nil {
+		t.Error("CreateCollection() with a taken name error = nil, want a unique violation")
+	}
+	if _, err := s.CreateCollection(ctx, database.CreateCollectionParams{UserID: user.ID, Name: "archive"}); err != nil {
+		t.Fatal(err)
+	}
+
+	collections, err := s.GetCollectionsByUser(ctx, database.GetCollectionsByUserParams{UserID: user.ID, Limit: 10})
+	if err != nil || len(collections) != 2 || collections[0].Name != "archive" || collections[1].Name != "reading" {
+		t.Errorf("GetCollectionsByUser() = %+v, %v, want them ordered by name", collections, err)
+	}
+
+	renamed, err := s.RenameCollection(ctx, database.RenameCollectionParams{Name: "later", ID: reading.ID})
+	if err != nil || renamed.Name != "later" {
+		t.Errorf("RenameCollection() = %+v, %v", renamed, err)
+	}
+
+	for range 2 {
+		if err := s.AddChirpToCollection(ctx, database.AddChirpToCollectionParams{CollectionID: reading.ID, ChirpID: chirp.ID}); err != nil {
+			t.Fatalf("AddChirpToCollection() error = %v, want adding twice to be a no-op", err)
+		}
+	}
+	chirps, err := s.GetCollectionChirps(ctx, database.GetCollectionChirpsParams{CollectionID: reading.ID, Limit: 10})
+	if got, want := chirpIDs(chirps), []uuid.UUID{chirp.ID}; err != nil || !slices.Equal(got, want) {
+		t.Errorf("GetCollectionChirps() = %v, %v, want %v", got, err, want)
+	}
+
+	if err := s.RemoveChirpFromCollection(ctx, database.RemoveChirpFromCollectionParams{CollectionID: reading.ID, ChirpID: chirp.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if chirps, err := s.GetCollectionChirps(ctx, database.GetCollectionChirpsParams{CollectionID: reading.ID, Limit: 10}); err != nil || len(chirps) != 0 {
+		t.Errorf("GetCollectionChirps() after removal = %v, %v, want none", chirpIDs(chirps), err)
+	}
+
+	if err := s.DeleteCollectionByID(ctx, reading.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.GetCollectionByID(ctx, reading.ID)
+	wantNoRows(t, "GetCollectionByID(deleted)", err)
+}
+
+func testNotifications(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	alice := createUser(t, s, "alice@example.com")
+	chirp := createChirp(t, s, alice.ID, "popular")
+
+	var actors []uuid.UUID
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com", "f@example.com"} {
+		actor := createUser(t, s, email)
+		actors = append(actors, actor.ID)
+		if _, err := s.CreateNotification(
+			ctx,
+			database.CreateNotificationParams{
+				UserID:  alice.ID,
+				ActorID: actor.ID,
+				Type:    "like",
+				ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+			},
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+	follow, err := s.CreateNotification(ctx, database.CreateNotificationParams{UserID: alice.ID, ActorID: actors[0], Type: "follow"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.GetNotificationByID(ctx, follow.ID); err != nil || got.Type != "follow" || got.ChirpID.Valid || got.ReadAt.Valid {
+		t.Errorf("GetNotificationByID() = %+v, %v", got, err)
+	}
+
+	groups, err := s.GetGroupedNotifications(ctx, database.GetGroupedNotificationsParams{UserID: alice.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("GetGroupedNotifications() = %+v, want 2 groups", groups)
+	}
+	if g := groups[0]; g.Type != "follow" || g.ChirpID.Valid || g.Count != 1 || g.Unread != 1 || !g.LatestAt.Equal(follow.CreatedAt) {
+		t.Errorf("first group = %+v, want the follow", g)
+	}
+	likes := groups[1]
+	if likes.Type != "like" || likes.ChirpID.UUID != chirp.ID || likes.Count != 6 || likes.Unread != 6 {
+		t.Errorf("second group = %+v, want 6 unread likes", likes)
+	}
+	if want := []uuid.UUID{actors[5], actors[4], actors[3], actors[2], actors[1]}; !slices.Equal(likes.ActorIds, want) {
+		t.Errorf("ActorIds = %v, want the 5 latest %v", likes.ActorIds, want)
+	}
+	paged, err := s.GetGroupedNotifications(ctx, database.GetGroupedNotificationsParams{UserID: alice.ID, Limit: 1, Offset: 1})
+	if err != nil || len(paged) != 1 || paged[0].Type != "like" {
+		t.Errorf("GetGroupedNotifications(page 2) = %+v, %v, want the likes", paged, err)
+	}
+
+	if err := s.MarkNotificationsRead(ctx, database.MarkNotificationsReadParams{UserID: alice.ID, Type: sql.NullString{String: "follow", Valid: true}}); err != nil {
+		t.Fatal(err)
+	}
+	if unread, err := s.CountUnreadNotifications(ctx, alice.ID); err != nil || unread != 6 {
+		t.Errorf("CountUnreadNotifications() = %d, %v, want 6 after reading follows", unread, err)
+	}
+	if err := s.MarkNotificationsRead(ctx, database.MarkNotificationsReadParams{UserID: alice.ID, ChirpID: uuid.NullUUID{UUID: uuid.New(), Valid: true}}); err != nil {
+		t.Fatal(err)
+	}
+	if unread, err := s.CountUnreadNotifications(ctx, alice.ID); err != nil || unread != 6 {
+		t.Errorf("CountUnreadNotifications() = %d, %v, want 6 after reading another chirp", unread, err)
+	}
+	if err := s.MarkNotificationsRead(ctx, database.MarkNotificationsReadParams{UserID: alice.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if unread, err := s.CountUnreadNotifications(ctx, alice.ID); err != nil || unread != 0 {
+		t.Errorf("CountUnreadNotifications() = %d, %v, want 0 after reading all", unread, err)
+	}
+
+	for _, kind := range []string{"like", "follow", "like"} {
+		if err := s.MuteNotificationType(ctx, database.MuteNotificationTypeParams{UserID: alice.ID, Type: kind}); err != nil {
+			t.Fatalf("MuteNotificationType() error = %v, want muting twice to be a no-op", err)
+		}
+	}
+	if muted, err := s.GetMutedNotificationTypes(ctx, alice.ID); err != nil || !slices.Equal(muted, []string{"follow", "like"}) {
+		t.Errorf("GetMutedNotificationTypes() = %v, %v", muted, err)
+	}
+	if muted, err := s.IsNotificationTypeMuted(ctx, database.IsNotificationTypeMutedParams{UserID: alice.ID, Type: "like"}); err != nil || !muted {
+		t.Errorf("IsNotificationTypeMuted(like) = %v, %v, want true", muted, err)
+	}
+	if err := s.DeleteMutedNotificationTypes(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if muted, err := s.IsNotificationTypeMuted(ctx, database.IsNotificationTypeMutedParams{UserID: alice.ID, Type: "like"}); err != nil || muted {
+		t.Errorf("IsNotificationTypeMuted(like) after unmuting = %v, %v, want false", muted, err)
+	}
+}
+
+func testWebhooks(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "alice@example.com")
+
+	subscription, err := s.CreateWebhookSubscription(
+		ctx,
+		database.CreateWebhookSubscriptionParams{
+			UserID: user.ID,
+			Url:    "https://example.com/hook",
+			Secret: "secret",
+			Events: []string{"chirp.created", "user.upgraded"},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.GetWebhookSubscriptionByID(ctx, subscription.ID); err != nil || !slices.Equal(got.Events, []string{"chirp.created", "user.upgraded"}) {
+		t.Errorf("GetWebhookSubscriptionByID() = %+v, %v", got, err)
+	}
+	if _, err := s.CreateWebhookSubscription(ctx, database.CreateWebhookSubscriptionParams{UserID: user.ID, Url: "https://example.com/other", Secret: "secret", Events: []string{"chirp.deleted"}}); err != nil {
+		t.Fatal(err)
+	}
+	if subscriptions, err := s.GetWebhookSubscriptionsByUser(ctx, user.ID); err != nil || len(subscriptions) != 2 || subscriptions[0].ID != subscription.ID {
+		t.Errorf("GetWebhookSubscriptionsByUser() = %+v, %v", subscriptions, err)
+	}
+
+	ids, err := s.EnqueueWebhookDeliveries(ctx, database.EnqueueWebhookDeliveriesParams{Event: "chirp.created", Payload: `{"id":1}`, UserID: user.ID})
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("EnqueueWebhookDeliveries() = %v, %v, want one delivery", ids, err)
+	}
+	if ids, err := s.EnqueueWebhookDeliveries(ctx, database.EnqueueWebhookDeliveriesParams{Event: "chirp", Payload: "{}", UserID: user.ID}); err != nil || len(ids) != 0 {
+		t.Errorf("EnqueueWebhookDeliveries(unsubscribed event) = %v, %v, want none", ids, err)
+	}
+	delivery, err := s.GetWebhookDeliveryByID(ctx, ids[0])
+	if err != nil || delivery.Status != "pending" || delivery.Payload != `{"id":1}` || delivery.SubscriptionID != subscription.ID {
+		t.Errorf("GetWebhookDeliveryByID() = %+v, %v", delivery, err)
+	}
+
+	lease := now().Add(time.Minute)
+	claimed, err := s.ClaimDueWebhookDeliveries(ctx, database.ClaimDueWebhookDeliveriesParams{LeaseUntil: lease, BatchSize: 10})
+	if err != nil || len(claimed) != 1 || claimed[0].ID != ids[0] || !claimed[0].NextAttemptAt.Equal(lease) {
+		t.Fatalf("ClaimDueWebhookDeliveries() = %+v, %v, want the delivery leased until %v", claimed, err, lease)
+	}
+	if claimed, err := s.ClaimDueWebhookDeliveries(ctx, database.ClaimDueWebhookDeliveriesParams{LeaseUntil: lease, BatchSize: 10}); err != nil || len(claimed) != 0 {
+		t.Errorf("ClaimDueWebhookDeliveries() while leased = %+v, %v, want none", claimed, err)
+	}
+
+	if err := s.CreateWebhookDeliveryAttempt(
+		ctx,
+		database.CreateWebhookDeliveryAttemptParams{
+			DeliveryID: ids[0],
+			StatusCode: sql.NullInt32{Int32: 500, Valid: true},
+			DurationMs: 12,
+		},
+	); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MarkWebhookDeliveryFailed(ctx, database.MarkWebhookDeliveryFailedParams{Status: "failed", NextAttemptAt: now(), ID: ids[0]}); err != nil {
+		t.Fatal(err)
+	}
+	failed, err := s.GetWebhookDeliveries(ctx, database.GetWebhookDeliveriesParams{SubscriptionID: subscription.ID, Status: sql.NullString{String: "failed", Valid: true}, RowLimit: 10})
+	if err != nil || len(failed) != 1 || failed[0].Attempts != 1 {
+		t.Errorf("GetWebhookDeliveries(failed) = %+v, %v, want the delivery after 1 attempt", failed, err)
+	}
+	if pending, err := s.GetWebhookDeliveries(ctx, database.GetWebhookDeliveriesParams{SubscriptionID: subscription.ID, Status: sql.NullString{String: "pending", Valid: true}, RowLimit: 10}); err != nil || len(pending) != 0 {
+		t.Errorf("GetWebhookDeliveries(pending) = %+v, %v, want none", pending, err)
+	}
+
+	redelivered, err := s.RedeliverWebhookDelivery(ctx, ids[0])
+	if err != nil || redelivered.Status != "pending" || redelivered.Attempts != 0 {
+		t.Errorf("RedeliverWebhookDelivery() = %+v, %v", redelivered, err)
+	}
+	if err := s.MarkWebhookDeliverySucceeded(ctx, ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.GetWebhookDeliveryByID(ctx, ids[0]); err != nil || got.Status != "succeeded" || !got.DeliveredAt.Valid {
+		t.Errorf("GetWebhookDeliveryByID() after success = %+v, %v", got, err)
+	}
+
+	attempts, err := s.GetWebhookDeliveryAttempts(ctx, ids[0])
+	if err != nil || len(attempts) != 1 || attempts[0].StatusCode.Int32 != 500 || attempts[0].DurationMs != 12 {
+		t.Errorf("GetWebhookDeliveryAttempts() = %+v, %v", attempts, err)
+	}
+
+	if err := s.DeleteWebhookSubscriptionByID(ctx, subscription.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.GetWebhookDeliveryByID(ctx, ids[0])
+	wantNoRows(t, "GetWebhookDeliveryByID(deleted subscription)", err)
+}
+
+func testRefreshTokens(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "alice@example.com")
+	client := createOAuthClient(t, s, user.ID)
+
+	token, err := s.CreateRefreshRoken(ctx, database.CreateRefreshRokenParams{Token: "first-party", UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if days := token.ExpiresAt.Sub(token.CreatedAt).Hours() / 24; days < 59.9 || days > 60.1 {
+		t.Errorf("refresh token lasts %.1f days, want 60", days)
+	}
+	if token.ClientID.Valid || len(token.Scopes) != 0 {
+		t.Errorf("CreateRefreshRoken() = %+v, want no client and no scopes", token)
+	}
+
+	oauthToken, err := s.CreateOAuthRefreshToken(
+		ctx,
+		database.CreateOAuthRefreshTokenParams{
+			Token:    "third-party",
+			UserID:   user.ID,
+			ClientID: sql.NullString{String: client.ID, Valid: true},
+			Scopes:   []string{"read"},
+		},
+	)
+	if err != nil || !slices.Equal(oauthToken.Scopes, []string{"read"}) {
+		t.Errorf("CreateOAuthRefreshToken() = %+v, %v", oauthToken, err)
+	}
+
+	revokedAt := now()
+	if err := s.RevokeRefreshToken(ctx, database.RevokeRefreshTokenParams{RevokedAt: sql.NullTime{Time: revokedAt, Valid: true}, Token: "first-party"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.GetRefreshToken(ctx, "first-party"); err != nil || !got.RevokedAt.Time.Equal(revokedAt) || !got.UpdatedAt.Equal(revokedAt) {
+		t.Errorf("GetRefreshToken() after revoking = %+v, %v, want it revoked at %v", got, err, revokedAt)
+	}
+
+	if err := s.RevokeRefreshTokensForClient(ctx, database.RevokeRefreshTokensForClientParams{UserID: user.ID, ClientID: sql.NullString{String: client.ID, Valid: true}}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.GetRefreshToken(ctx, "third-party"); err != nil || !got.RevokedAt.Valid {
+		t.Errorf("GetRefreshToken() after revoking the client = %+v, %v, want it revoked", got, err)
+	}
+	_, err = s.GetRefreshToken(ctx, "unknown")
+	wantNoRows(t, "GetRefreshToken(unknown)", err)
+}
+
+func testAPIKeys(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "alice@example.com")
+
+	key, err := s.CreateAPIKey(
+		ctx,
+		database.CreateAPIKeyParams{
+			UserID:  user.ID,
+			Name:    "ci",
+			Prefix:  "chirpy_ab",
+			KeyHash: "hash",
+			Scopes:  []string{"read", "write"},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(key.Scopes, []string{"read", "write"}) || key.ExpiresAt.Valid || key.LastUsedAt.Valid {
+		t.Errorf("CreateAPIKey() = %+v", key)
+	}
+	if _, err := s.CreateAPIKey(ctx, database.CreateAPIKeyParams{UserID: user.ID, Name: "copy", Prefix: "chirpy_ab", KeyHash: "hash", Scopes: []string{}}); err == nil {
+		t.Error("CreateAPIKey() with a taken hash error = nil, want a unique violation")
+	}
+	if got, err := s.GetAPIKeyByHash(ctx, "hash"); err != nil || got.ID != key.ID {
+		t.Errorf("GetAPIKeyByHash() = %v, %v, want %v", got.ID, err, key.ID)
+	}
+	if keys, err := s.GetAPIKeysByUser(ctx, user.ID); err != nil || len(keys) != 1 {
+		t.Errorf("GetAPIKeysByUser() = %+v, %v", keys, err)
+	}
+
+	if err := s.TouchAPIKey(ctx, key.ID); err != nil {
+		t.Fatal(err)
+	}
+	touched, err := s.GetAPIKeyByID(ctx, key.ID)
+	if err != nil || !touched.LastUsedAt.Valid {
+		t.Fatalf("GetAPIKeyByID() after TouchAPIKey = %+v, %v, want it used", touched, err)
+	}
+	if err := s.TouchAPIKey(ctx, key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.GetAPIKeyByID(ctx, key.ID); err != nil || !got.LastUsedAt.Time.Equal(touched.LastUsedAt.Time) {
+		t.Errorf("LastUsedAt = %v, %v, want %v unchanged within a minute", got.LastUsedAt.Time, err, touched.LastUsedAt.Time)
+	}
+
+	if err := s.RevokeAPIKey(ctx, key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.GetAPIKeyByID(ctx, key.ID); err != nil || !got.RevokedAt.Valid {
+		t.Errorf("GetAPIKeyByID() after RevokeAPIKey = %+v, %v, want it revoked", got, err)
+	}
+}
+
+func createOAuthClient(t *testing.T, s store.Store, userID uuid.UUID) database.OauthClient {
+	t.Helper()
+
+	client, err := s.CreateOAuthClient(
+		context.Background(),
+		database.CreateOAuthClientParams{
+			ID:           "chirpy_" + uuid.NewString(),
+			UserID:       userID,
+			Name:         "app",
+			RedirectUris: []string{"https://app.example.com/callback"},
+			Scopes:       []string{"read", "write"},
+		},
+	)
+	if err != nil {
+		t.Fatalf("CreateOAuthClient() error = %v", err)
+	}
+	return client
+}
+
+func testOAuth(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "alice@example.com")
+	client := createOAuthClient(t, s, user.ID)
+
+	got, err := s.GetOAuthClientByID(ctx, client.ID)
+	if err != nil || got.SecretHash.Valid || !slices.Equal(got.RedirectUris, []string{"https://app.example.com/callback"}) || !slices.Equal(got.Scopes, []string{"read", "write"}) {
+		t.Errorf("GetOAuthClientByID() = %+v, %v", got, err)
+	}
+	if clients, err := s.GetOAuthClientsByUser(ctx, user.ID); err != nil || len(clients) != 1 {
+		t.Errorf("GetOAuthClientsByUser() = %+v, %v", clients, err)
+	}
+
+	for _, code := range []struct {
+		hash      string
+		expiresAt time.Time
+	}{
+		{"valid", now().Add(time.Minute)},
+		{"expired", now().Add(-time.Minute)},
+	} {
+		if err := s.CreateAuthorizationCode(
+			ctx,
+			database.CreateAuthorizationCodeParams{
+				CodeHash:      code.hash,
+				ClientID:      client.ID,
+				UserID:        user.ID,
+				RedirectUri:   "https://app.example.com/callback",
+				Scopes:        []string{"read"},
+				CodeChallenge: "challenge",
+				ExpiresAt:     code.expiresAt,
+			},
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+	consumed, err := s.ConsumeAuthorizationCode(ctx, "valid")
+	if err != nil || !consumed.UsedAt.Valid || !slices.Equal(consumed.Scopes, []string{"read"}) {
+		t.Errorf("ConsumeAuthorizationCode() = %+v, %v", consumed, err)
+	}
+	_, err = s.ConsumeAuthorizationCode(ctx, "valid")
+	wantNoRows(t, "ConsumeAuthorizationCode(used)", err)
+	_, err = s.ConsumeAuthorizationCode(ctx, "expired")
+	wantNoRows(t, "ConsumeAuthorizationCode(expired)", err)
+
+	consent, err := s.UpsertOAuthConsent(ctx, database.UpsertOAuthConsentParams{UserID: user.ID, ClientID: client.ID, Scopes: []string{"read"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := s.UpsertOAuthConsent(ctx, database.UpsertOAuthConsentParams{UserID: user.ID, ClientID: client.ID, Scopes: []string{"read", "write"}})
+	if err != nil || !updated.CreatedAt.Equal(consent.CreatedAt) || !slices.Equal(updated.Scopes, []string{"read", "write"}) {
+		t.Errorf("UpsertOAuthConsent() again = %+v, %v, want the scopes replaced", updated, err)
+	}
+	if got, err := s.GetOAuthConsent(ctx, database.GetOAuthConsentParams{UserID: user.ID, ClientID: client.ID}); err != nil || len(got.Scopes) != 2 {
+		t.Errorf("GetOAuthConsent() = %+v, %v", got, err)
+	}
+	if consents, err := s.GetOAuthConsentsByUser(ctx, user.ID); err != nil || len(consents) != 1 {
+		t.Errorf("GetOAuthConsentsByUser() = %+v, %v, want one consent", consents, err)
+	}
+
+	for _, id := range []string{"token-1", "token-2"} {
+		if err := s.CreateOAuthAccessToken(
+			ctx,
+			database.CreateOAuthAccessTokenParams{
+				ID:        id,
+				ClientID:  client.ID,
+				UserID:    user.ID,
+				Scopes:    []string{"read"},
+				ExpiresAt: now().Add(time.Hour),
+			},
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if token, err := s.GetOAuthAccessToken(ctx, "token-1"); err != nil || token.RevokedAt.Valid || !slices.Equal(token.Scopes, []string{"read"}) {
+		t.Errorf("GetOAuthAccessToken() = %+v, %v", token, err)
+	}
+	if err := s.RevokeOAuthAccessToken(ctx, "token-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RevokeOAuthAccessTokensForClient(ctx, database.RevokeOAuthAccessTokensForClientParams{UserID: user.ID, ClientID: client.ID}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"token-1", "token-2"} {
+		if token, err := s.GetOAuthAccessToken(ctx, id); err != nil || !token.RevokedAt.Valid {
+			t.Errorf("GetOAuthAccessToken(%s) = %+v, %v, want it revoked", id, token, err)
+		}
+	}
+
+	if err := s.DeleteOAuthConsent(ctx, database.DeleteOAuthConsentParams{UserID: user.ID, ClientID: client.ID}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.GetOAuthConsent(ctx, database.GetOAuthConsentParams{UserID: user.ID, ClientID: client.ID})
+	wantNoRows(t, "GetOAuthConsent(deleted)", err)
+
+	if err := s.DeleteOAuthClientByID(ctx, client.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.GetOAuthAccessToken(ctx, "token-2")
+	wantNoRows(t, "GetOAuthAccessToken(deleted client)", err)
+}
+
+func testIdentities(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "alice@example.com")
+
+	identity, err := s.CreateIdentity(ctx, database.CreateIdentityParams{UserID: user.ID, Provider: "google", Subject: "123", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateIdentity(ctx, database.CreateIdentityParams{UserID: user.ID, Provider: "google", Subject: "456", Email: "alice@example.com"}); err == nil {
+		t.Error("CreateIdentity() for a linked provider error = nil, want a unique violation")
+	}
+	if _, err := s.CreateIdentity(ctx, database.CreateIdentityParams{UserID: user.ID, Provider: "github", Subject: "789", Email: "alice@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := s.GetIdentityBySubject(ctx, database.GetIdentityBySubjectParams{Provider: "google", Subject: "123"}); err != nil || got.ID != identity.ID {
+		t.Errorf("GetIdentityBySubject() = %v, %v, want %v", got.ID, err, identity.ID)
+	}
+	if identities, err := s.GetIdentitiesByUser(ctx, user.ID); err != nil || len(identities) != 2 || identities[0].ID != identity.ID {
+		t.Errorf("GetIdentitiesByUser() = %+v, %v", identities, err)
+	}
+	if err := s.DeleteIdentityByID(ctx, identity.ID); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := s.CountIdentitiesByUser(ctx, user.ID); err != nil || count != 1 {
+		t.Errorf("CountIdentitiesByUser() = %d, %v, want 1", count, err)
+	}
+	_, err = s.GetIdentityByID(ctx, identity.ID)
+	wantNoRows(t, "GetIdentityByID(deleted)", err)
+
+	for _, state := range []struct {
+		hash      string
+		expiresAt time.Time
+	}{
+		{"valid", now().Add(time.Minute)},
+		{"expired", now().Add(-time.Minute)},
+	} {
+		if err := s.CreateOIDCLoginState(
+			ctx,
+			database.CreateOIDCLoginStateParams{
+				StateHash:    state.hash,
+				Provider:     "google",
+				Nonce:        "nonce",
+				CodeVerifier: "verifier",
+				UserID:       uuid.NullUUID{UUID: user.ID, Valid: true},
+				ExpiresAt:    state.expiresAt,
+			},
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if state, err := s.ConsumeOIDCLoginState(ctx, "valid"); err != nil || state.UserID.UUID != user.ID || state.Nonce != "nonce" {
+		t.Errorf("ConsumeOIDCLoginState() = %+v, %v", state, err)
+	}
+	_, err = s.ConsumeOIDCLoginState(ctx, "valid")
+	wantNoRows(t, "ConsumeOIDCLoginState(used)", err)
+	_, err = s.ConsumeOIDCLoginState(ctx, "expired")
+	wantNoRows(t, "ConsumeOIDCLoginState(expired)", err)
+	if err := s.DeleteExpiredOIDCLoginStates(ctx); err != nil {
+		t.Error(err)
+	}
+}
+
+func testInTx(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "alice@example.com")
+
+	failed := errors.New("second write failed")
+	err := s.InTx(ctx, func(q store.Queries) error {
+		if err := q.UpgradeUser(ctx, user.ID); err != nil {
+			return err
+		}
+		if _, err := q.CreateUser(ctx, database.CreateUserParams{Email: "bob@example.com", HashedPassword: "hash"}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("InTx() error = %v, want %v", err, failed)
+	}
+	if got, err := s.GetUserByID(ctx, user.ID); err != nil || got.IsChirpyRed {
+		t.Errorf("GetUserByID() = %+v, %v, want the upgrade rolled back", got, err)
+	}
+	_, err = s.GetUserByEmail(ctx, "bob@example.com")
+	wantNoRows(t, "GetUserByEmail() after rollback", err)
+
+	if err := s.InTx(ctx, func(q store.Queries) error { return q.UpgradeUser(ctx, user.ID) }); err != nil {
+		t.Fatalf("InTx() error = %v, want nil", err)
+	}
+	if got, err := s.GetUserByID(ctx, user.ID); err != nil || !got.IsChirpyRed {
+		t.Errorf("GetUserByID() = %+v, %v, want the upgrade committed", got, err)
+	}
+}
+
+func chirpIDs(chirps []database.Chirp) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, c := range chirps {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
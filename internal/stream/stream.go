@@ -0,0 +1,358 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/backoff"
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/store"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	ChirpChannel        = "chirp_events"
+	NotificationChannel = "notification_events"
+)
+
+// pollBatch is how many events Poll reads from the log per query.
+const pollBatch = 100
+
+// ReorderWindow is how many IDs below the highest one seen are read again
+// from the event log. IDs are drawn from a sequence when a transaction
+// inserts its event, not when it commits, so an event can become visible
+// after others with higher IDs.
+const ReorderWindow = 100
+
+type Subscriber[T any] struct {
+	C <-chan T
+	c chan T
+}
+
+// Hub fans events out to in-process subscribers.
+type Hub[T any] struct {
+	mu          sync.Mutex
+	subscribers map[*Subscriber[T]]struct{}
+}
+
+func NewHub[T any]() *Hub[T] {
+	return &Hub[T]{
+		subscribers: map[*Subscriber[T]]struct{}{},
+	}
+}
+
+func (h *Hub[T]) Subscribe(buffer int) *Subscriber[T] {
+
+	c := make(chan T, buffer)
+	sub := &Subscriber[T]{C: c, c: c}
+
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+
+	return sub
+}
+
+func (h *Hub[T]) Unsubscribe(sub *Subscriber[T]) {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.c)
+	}
+}
+
+// Publish never blocks: a subscriber whose buffer is full is dropped and its
+// channel closed, leaving it to resume from its last event ID.
+func (h *Hub[T]) Publish(event T) {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscribers {
+		select {
+		case sub.c <- event:
+		default:
+			delete(h.subscribers, sub)
+			close(sub.c)
+		}
+	}
+}
+
+// Seen remembers which events near the head of the log were relayed, so a
+// reader can re-read the last ReorderWindow IDs for late commits without
+// relaying anything twice.
+type Seen struct {
+	ids map[int64]struct{}
+	max int64
+}
+
+// NewSeen starts after last, the highest ID a reader already has.
+func NewSeen(last int64) *Seen {
+	return &Seen{ids: map[int64]struct{}{}, max: last}
+}
+
+// Add records id and reports whether it was new.
+func (s *Seen) Add(id int64) bool {
+
+	if _, ok := s.ids[id]; ok {
+		return false
+	}
+	s.ids[id] = struct{}{}
+
+	if id > s.max {
+		s.max = id
+		for seen := range s.ids {
+			if seen <= s.From() {
+				delete(s.ids, seen)
+			}
+		}
+	}
+
+	return true
+}
+
+// From is the ID to read the log after to pick up late commits.
+func (s *Seen) From() int64 {
+	return max(s.max-ReorderWindow, 0)
+}
+
+// Store is what the relay needs to load the rows it is notified about.
+type Store interface {
+	store.ChirpStore
+	store.NotificationStore
+}
+
+// Relay feeds the hubs from Postgres LISTEN/NOTIFY, so every instance sees
+// writes made by any other. Backends without it use Poll instead.
+type Relay struct {
+	db        Store
+	retention time.Duration
+	// baseRetry and maxRetry space out attempts to start relaying while the
+	// database is unavailable.
+	baseRetry time.Duration
+	maxRetry  time.Duration
+
+	Chirps        *Hub[database.ChirpEvent]
+	Notifications *Hub[database.Notification]
+}
+
+func NewRelay(db Store, retention time.Duration) *Relay {
+	return &Relay{
+		db:            db,
+		retention:     retention,
+		baseRetry:     time.Second,
+		maxRetry:      time.Minute,
+		Chirps:        NewHub[database.ChirpEvent](),
+		Notifications: NewHub[database.Notification](),
+	}
+}
+
+// Listen relays notifications as they arrive. Notifications sent while the
+// connection is down are lost, so after reconnecting it reads the chirp
+// events it missed from the log.
+func (r *Relay) Listen(ctx context.Context, dbURL string) {
+
+	seen := NewSeen(0)
+	if !r.retry(ctx, "Error reading chirp events", func() error {
+		return r.relayEventsSince(ctx, seen, func(database.ChirpEvent) {})
+	}) {
+		return
+	}
+
+	reconnected := make(chan struct{}, 1)
+	listener := pq.NewListener(
+		dbURL,
+		10*time.Second,
+		time.Minute,
+		func(ev pq.ListenerEventType, err error) {
+			if err != nil {
+				slog.Warn("Event listener", "err", err)
+			}
+			if ev == pq.ListenerEventReconnected {
+				select {
+				case reconnected <- struct{}{}:
+				default:
+				}
+			}
+		},
+	)
+	// Listen blocks while the database is unreachable; closing the listener
+	// is the only way to unblock it on shutdown.
+	closeOnDone := context.AfterFunc(ctx, func() { listener.Close() })
+	defer func() {
+		if closeOnDone() {
+			listener.Close()
+		}
+	}()
+
+	for _, channel := range []string{ChirpChannel, NotificationChannel} {
+		if !r.retry(ctx, "Error listening for events", func() error {
+			if err := listener.Listen(channel); err != nil && !errors.Is(err, pq.ErrChannelAlreadyOpen) {
+				return err
+			}
+			return nil
+		}) {
+			return
+		}
+	}
+
+	ping := time.NewTicker(time.Minute)
+	defer ping.Stop()
+	prune := time.NewTicker(time.Hour)
+	defer prune.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-listener.Notify:
+			if n == nil {
+				continue
+			}
+			r.dispatch(ctx, n, seen)
+		case <-reconnected:
+			if err := r.relayEventsSince(ctx, seen, r.Chirps.Publish); err != nil {
+				slog.Error("Error catching up on chirp events", "err", err)
+			}
+		case <-ping.C:
+			if err := listener.Ping(); err != nil {
+				slog.Warn("Event listener ping", "err", err)
+			}
+		case <-prune.C:
+			if err := r.db.DeleteChirpEventsBefore(ctx, time.Now().UTC().Add(-r.retention)); err != nil {
+				slog.Error("Error pruning chirp events", "err", err)
+			}
+		}
+	}
+
+}
+
+// Poll feeds the chirp hub by reading the event log every interval, for
+// databases that cannot notify listeners. Like Listen it only relays events
+// recorded after it starts; notifications have to be published to the hub
+// by whoever records them.
+func (r *Relay) Poll(ctx context.Context, interval time.Duration) {
+
+	seen := NewSeen(0)
+	if !r.retry(ctx, "Error reading chirp events", func() error {
+		return r.relayEventsSince(ctx, seen, func(database.ChirpEvent) {})
+	}) {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	prune := time.NewTicker(time.Hour)
+	defer prune.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.relayEventsSince(ctx, seen, r.Chirps.Publish); err != nil {
+				slog.Error("Error polling chirp events", "err", err)
+			}
+		case <-prune.C:
+			if err := r.db.DeleteChirpEventsBefore(ctx, time.Now().UTC().Add(-r.retention)); err != nil {
+				slog.Error("Error pruning chirp events", "err", err)
+			}
+		}
+	}
+
+}
+
+// retry calls f until it succeeds, waiting longer after each failure, and
+// reports false if ctx is done first.
+func (r *Relay) retry(ctx context.Context, msg string, f func() error) bool {
+
+	for attempts := int32(1); ; attempts++ {
+		err := f()
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+
+		wait := backoff.Exponential(attempts, r.baseRetry, r.maxRetry)
+		slog.Error(msg, "err", err, "retry_in", wait)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(wait):
+		}
+	}
+
+}
+
+// relayEventsSince passes every event in the log that seen has not had yet
+// to publish, including late commits within ReorderWindow.
+func (r *Relay) relayEventsSince(ctx context.Context, seen *Seen, publish func(database.ChirpEvent)) error {
+
+	after := seen.From()
+	for {
+		events, err := r.db.GetChirpEventsSince(
+			ctx,
+			database.GetChirpEventsSinceParams{
+				ID:    after,
+				Limit: pollBatch,
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if seen.Add(event.ID) {
+				publish(event)
+			}
+			after = event.ID
+		}
+		if len(events) < pollBatch {
+			return nil
+		}
+	}
+
+}
+
+func (r *Relay) dispatch(ctx context.Context, n *pq.Notification, seen *Seen) {
+
+	switch n.Channel {
+	case ChirpChannel:
+		id, err := strconv.ParseInt(n.Extra, 10, 64)
+		if err != nil {
+			slog.Error("Invalid event payload", "channel", n.Channel, "payload", n.Extra)
+			return
+		}
+		event, err := r.db.GetChirpEventByID(ctx, id)
+		if err != nil {
+			slog.Error("Error loading chirp event", "event_id", id, "err", err)
+			return
+		}
+		if seen.Add(event.ID) {
+			r.Chirps.Publish(event)
+		}
+	case NotificationChannel:
+		id, err := uuid.Parse(n.Extra)
+		if err != nil {
+			slog.Error("Invalid event payload", "channel", n.Channel, "payload", n.Extra)
+			return
+		}
+		notification, err := r.db.GetNotificationByID(ctx, id)
+		if err != nil {
+			slog.Error("Error loading notification", "notification_id", id, "err", err)
+			return
+		}
+		r.Notifications.Publish(notification)
+	}
+
+}
@@ -0,0 +1,168 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/database"
+)
+
+func TestPublish(t *testing.T) {
+	hub := NewHub[database.ChirpEvent]()
+	fast := hub.Subscribe(2)
+	slow := hub.Subscribe(1)
+	defer hub.Unsubscribe(fast)
+	defer hub.Unsubscribe(slow)
+
+	hub.Publish(database.ChirpEvent{ID: 1})
+	hub.Publish(database.ChirpEvent{ID: 2})
+
+	for _, want := range []int64{1, 2} {
+		if got := <-fast.C; got.ID != want {
+			t.Errorf("fast subscriber got event %d, want %d", got.ID, want)
+		}
+	}
+
+	if got := <-slow.C; got.ID != 1 {
+		t.Errorf("slow subscriber got event %d, want 1", got.ID)
+	}
+	if _, ok := <-slow.C; ok {
+		t.Error("slow subscriber should have been dropped when its buffer filled")
+	}
+}
+
+// eventLog serves a fixed slice of events, standing in for a table whose
+// rows become visible in commit order rather than ID order.
+type eventLog struct {
+	Store
+	events []database.ChirpEvent
+}
+
+func (l *eventLog) GetChirpEventsSince(_ context.Context, arg database.GetChirpEventsSinceParams) ([]database.ChirpEvent, error) {
+
+	var events []database.ChirpEvent
+	for _, e := range l.events {
+		if e.ID > arg.ID && len(events) < int(arg.Limit) {
+			events = append(events, e)
+		}
+	}
+
+	return events, nil
+}
+
+func TestRelayEventsSinceOutOfOrder(t *testing.T) {
+	ctx := context.Background()
+	log := &eventLog{events: []database.ChirpEvent{{ID: 1}}}
+	r := NewRelay(log, time.Hour)
+	seen := NewSeen(0)
+
+	var got []int64
+	publish := func(e database.ChirpEvent) { got = append(got, e.ID) }
+	poll := func() {
+		t.Helper()
+		if err := r.relayEventsSince(ctx, seen, publish); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	poll()
+	// Event 3 commits before event 2.
+	log.events = append(log.events, database.ChirpEvent{ID: 3})
+	poll()
+	log.events = []database.ChirpEvent{{ID: 1}, {ID: 2}, {ID: 3}}
+	poll()
+	poll()
+
+	if want := []int64{1, 3, 2}; !slices.Equal(got, want) {
+		t.Errorf("relayed %v, want %v", got, want)
+	}
+}
+
+// flakyLog fails its first reads, like a database that is still
+// starting up.
+type flakyLog struct {
+	*eventLog
+	mu       sync.Mutex
+	failures int
+	reads    int
+}
+
+func (l *flakyLog) GetChirpEventsSince(ctx context.Context, arg database.GetChirpEventsSinceParams) ([]database.ChirpEvent, error) {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.failures > 0 {
+		l.failures--
+		return nil, errors.New("connection refused")
+	}
+	l.reads++
+
+	return l.eventLog.GetChirpEventsSince(ctx, arg)
+}
+
+func (l *flakyLog) add(event database.ChirpEvent) {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.events = append(l.events, event)
+}
+
+func TestPollRetriesStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	log := &flakyLog{eventLog: &eventLog{events: []database.ChirpEvent{{ID: 1}}}, failures: 3}
+	r := NewRelay(log, time.Hour)
+	r.baseRetry, r.maxRetry = time.Millisecond, time.Millisecond
+	sub := r.Chirps.Subscribe(1)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Poll(ctx, time.Millisecond)
+	}()
+
+	for {
+		log.mu.Lock()
+		started := log.reads > 0
+		log.mu.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	log.add(database.ChirpEvent{ID: 2})
+
+	select {
+	case event := <-sub.C:
+		if event.ID != 2 {
+			t.Errorf("relayed event %d, want 2", event.ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Poll() stopped after failing to read the log")
+	}
+
+	cancel()
+	<-done
+}
+
+func TestSeen(t *testing.T) {
+	seen := NewSeen(1000)
+
+	if !seen.Add(1002) || !seen.Add(1001) || seen.Add(1002) {
+		t.Error("Add() should report each ID as new exactly once")
+	}
+	if got, want := seen.From(), int64(1002-ReorderWindow); got != want {
+		t.Errorf("From() = %d, want %d", got, want)
+	}
+
+	seen.Add(1002 + 2*ReorderWindow)
+	if len(seen.ids) != 1 {
+		t.Errorf("kept %d IDs, want those below the window forgotten", len(seen.ids))
+	}
+}
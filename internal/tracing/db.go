@@ -0,0 +1,102 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracedDB struct {
+	db     database.DBTX
+	system attribute.KeyValue
+}
+
+// DB wraps a connection or transaction so that every sqlc query gets a
+// client span named after the query.
+func DB(db database.DBTX) database.DBTX {
+	return tracedDB{db: db, system: semconv.DBSystemNamePostgreSQL}
+}
+
+// SQLiteDB is DB for the SQLite backend.
+func SQLiteDB(db database.DBTX) database.DBTX {
+	return tracedDB{db: db, system: semconv.DBSystemNameSQLite}
+}
+
+func (t tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := t.startQuery(ctx, query)
+	defer span.End()
+
+	result, err := t.db.ExecContext(ctx, query, args...)
+	recordError(span, err)
+	return result, err
+}
+
+func (t tracedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := t.startQuery(ctx, query)
+	defer span.End()
+
+	stmt, err := t.db.PrepareContext(ctx, query)
+	recordError(span, err)
+	return stmt, err
+}
+
+func (t tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := t.startQuery(ctx, query)
+	defer span.End()
+
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	recordError(span, err)
+	return rows, err
+}
+
+func (t tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := t.startQuery(ctx, query)
+	defer span.End()
+
+	row := t.db.QueryRowContext(ctx, query, args...)
+	recordError(span, row.Err())
+	return row
+}
+
+func (t tracedDB) startQuery(ctx context.Context, query string) (context.Context, trace.Span) {
+	name := QueryName(query)
+	return Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			t.system,
+			semconv.DBOperationName(name),
+			semconv.DBQueryText(query),
+		),
+	)
+}
+
+func recordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// QueryName reads the name sqlc puts in the first line of every query,
+// "-- name: GetChirpByID :one". Other queries are named "query".
+func QueryName(query string) string {
+
+	line, _, _ := strings.Cut(query, "\n")
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), "-- name:")
+	if !ok {
+		return "query"
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "query"
+	}
+
+	return fields[0]
+}
@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestQueryName(t *testing.T) {
+	for query, want := range map[string]string{
+		"-- name: GetChirpByID :one\nSELECT 1":  "GetChirpByID",
+		"-- name: DeleteAllUsers :exec\nDELETE": "DeleteAllUsers",
+		"SELECT 1":                              "query",
+		"-- name:\nSELECT 1":                    "query",
+	} {
+		if got := QueryName(query); got != want {
+			t.Errorf("QueryName(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/chirps/{chirpID}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := Tracer().Start(r.Context(), "GetChirpByID")
+		span.End()
+	})
+	// Like the logging middleware, this hands the mux a copy of the request,
+	// so only Route sees the pattern.
+	copying := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Route(mux).ServeHTTP(w, r.WithContext(r.Context()))
+	})
+	handler := Middleware(copying)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want the query and the request", len(spans))
+	}
+	query, server := spans[0], spans[1]
+
+	if server.Name() != "GET /api/chirps/{chirpID}" {
+		t.Errorf("server span name = %q, want the route pattern", server.Name())
+	}
+	if got := server.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("server span trace ID = %s, want the one from traceparent", got)
+	}
+	if query.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Error("handler span is not a child of the server span")
+	}
+}
@@ -0,0 +1,60 @@
+package webhooks
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+	"syscall"
+)
+
+// ErrPrivateAddress is returned for webhook hosts on the loopback interface,
+// a private or link-local network, or anywhere else the internet cannot
+// reach, so subscribers cannot use deliveries to probe internal services.
+var ErrPrivateAddress = errors.New("webhook address is not public")
+
+// sharedAddressSpace is carrier-grade NAT space (RFC 6598), which IsPrivate
+// leaves out.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Public reports whether ip is a globally routable unicast address.
+func Public(ip netip.Addr) bool {
+
+	ip = ip.Unmap()
+
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// CheckHost rejects a URL host that names a non-public address outright.
+// Other names are only resolved when delivering, where the dialer checks
+// every address they resolve to.
+func CheckHost(host string) error {
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrPrivateAddress
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !Public(ip) {
+		return ErrPrivateAddress
+	}
+
+	return nil
+}
+
+// control runs once an address is resolved and before connecting to it, so
+// it also catches names that resolve, or are rebound, to private addresses.
+func (d *Dispatcher) control(network, address string, _ syscall.RawConn) error {
+
+	if d.AllowPrivateNetworks {
+		return nil
+	}
+
+	addr, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !Public(addr.Addr()) {
+		return ErrPrivateAddress
+	}
+
+	return nil
+}
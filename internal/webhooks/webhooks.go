@@ -0,0 +1,265 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/backoff"
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/store"
+	"github.com/ghis9917/chirpy/internal/tracing"
+	"github.com/google/uuid"
+)
+
+const (
+	EventChirpCreated = "chirp.created"
+	EventChirpDeleted = "chirp.deleted"
+	EventUserUpgraded = "user.upgraded"
+)
+
+var Events = []string{EventChirpCreated, EventChirpDeleted, EventUserUpgraded}
+
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusDead      = "dead"
+)
+
+const (
+	SignatureHeader = "X-Chirpy-Signature"
+	EventHeader     = "X-Chirpy-Event"
+	DeliveryHeader  = "X-Chirpy-Delivery"
+)
+
+type Payload struct {
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+type Dispatcher struct {
+	db          store.WebhookStore
+	client      *http.Client
+	interval    time.Duration
+	batchSize   int32
+	maxAttempts int32
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+
+	// OnAttempt, when set, is called after every delivery attempt.
+	OnAttempt func(event string, succeeded bool)
+	// AllowPrivateNetworks lets deliveries reach non-public addresses, for
+	// receivers running on the same machine in tests.
+	AllowPrivateNetworks bool
+}
+
+// NewDispatcher sends deliveries directly, never through a proxy, so every
+// address it connects to is checked, and does not follow redirects, which
+// could point anywhere.
+func NewDispatcher(db store.WebhookStore, interval time.Duration) *Dispatcher {
+
+	d := &Dispatcher{
+		db:          db,
+		interval:    interval,
+		batchSize:   20,
+		maxAttempts: 8,
+		baseBackoff: 30 * time.Second,
+		maxBackoff:  6 * time.Hour,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   d.control,
+	}).DialContext
+	d.client = &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: tracing.Transport(transport),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return d
+}
+
+func NewSecret() (string, error) {
+
+	randomData := make([]byte, 32)
+	if _, err := rand.Read(randomData); err != nil {
+		return "", err
+	}
+
+	return "whsec_" + hex.EncodeToString(randomData), nil
+}
+
+// Sign returns the signature header value for a delivery body. Receivers
+// recompute HMAC-SHA256 over "<timestamp>.<body>" with their secret.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Enqueue stores one pending delivery per subscription of userID that listens
+// for event. Deliveries are sent later by Run.
+func Enqueue(ctx context.Context, db store.WebhookStore, userID uuid.UUID, event string, data any) error {
+
+	payload, err := json.Marshal(Payload{
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = db.EnqueueWebhookDeliveries(
+		ctx,
+		database.EnqueueWebhookDeliveriesParams{
+			Event:   event,
+			Payload: string(payload),
+			UserID:  userID,
+		},
+	)
+
+	return err
+}
+
+func (d *Dispatcher) Run(ctx context.Context) {
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.DeliverDue(ctx); err != nil {
+				slog.Error("Error delivering webhooks", "err", err)
+			}
+		}
+	}
+
+}
+
+// DeliverDue leases a batch of due deliveries and attempts each one. The
+// lease pushes next_attempt_at forward so other instances skip these rows
+// while the HTTP calls are in flight.
+func (d *Dispatcher) DeliverDue(ctx context.Context) error {
+
+	ctx, span := tracing.Tracer().Start(ctx, "webhooks.DeliverDue")
+	defer span.End()
+
+	deliveries, err := d.db.ClaimDueWebhookDeliveries(
+		ctx,
+		database.ClaimDueWebhookDeliveriesParams{
+			LeaseUntil: time.Now().UTC().Add(d.client.Timeout + time.Minute),
+			BatchSize:  d.batchSize,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		if err := d.deliver(ctx, delivery); err != nil {
+			slog.Error("Error recording webhook delivery", "delivery_id", delivery.ID, "err", err)
+		}
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery database.WebhookDelivery) error {
+
+	subscription, err := d.db.GetWebhookSubscriptionByID(ctx, delivery.SubscriptionID)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	statusCode, sendErr := d.send(ctx, subscription, delivery)
+	attempt := database.CreateWebhookDeliveryAttemptParams{
+		DeliveryID: delivery.ID,
+		DurationMs: int32(time.Since(start).Milliseconds()),
+	}
+	if statusCode != 0 {
+		attempt.StatusCode = sql.NullInt32{Int32: int32(statusCode), Valid: true}
+	}
+	if sendErr != nil {
+		attempt.Error = sql.NullString{String: sendErr.Error(), Valid: true}
+	}
+	if err := d.db.CreateWebhookDeliveryAttempt(ctx, attempt); err != nil {
+		return err
+	}
+
+	if d.OnAttempt != nil {
+		d.OnAttempt(delivery.Event, sendErr == nil)
+	}
+
+	if sendErr == nil {
+		return d.db.MarkWebhookDeliverySucceeded(ctx, delivery.ID)
+	}
+
+	attempts := delivery.Attempts + 1
+	status := StatusPending
+	if attempts >= d.maxAttempts {
+		status = StatusDead
+	}
+
+	return d.db.MarkWebhookDeliveryFailed(
+		ctx,
+		database.MarkWebhookDeliveryFailedParams{
+			Status:        status,
+			NextAttemptAt: time.Now().UTC().Add(backoff.Exponential(attempts, d.baseBackoff, d.maxBackoff)),
+			ID:            delivery.ID,
+		},
+	)
+}
+
+func (d *Dispatcher) send(ctx context.Context, subscription database.WebhookSubscription, delivery database.WebhookDelivery) (int, error) {
+
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Chirpy-Webhooks/1.0")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.ID.String())
+	req.Header.Set(SignatureHeader, Sign(subscription.Secret, time.Now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,81 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestSign(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	body := []byte(`{"event":"chirp.created"}`)
+
+	got := Sign("whsec_test", ts, body)
+	if got != Sign("whsec_test", ts, body) {
+		t.Error("Sign() is not deterministic")
+	}
+	if got == Sign("whsec_other", ts, body) {
+		t.Error("Sign() ignores the secret")
+	}
+	if got[:13] != "t=1700000000," {
+		t.Errorf("Sign() = %q, want timestamp prefix", got)
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	for host, want := range map[string]error{
+		"example.com":     nil,
+		"93.184.216.34":   nil,
+		"2606:4700::1111": nil,
+		"localhost":       ErrPrivateAddress,
+		"API.localhost.":  ErrPrivateAddress,
+		"127.0.0.1":       ErrPrivateAddress,
+		"::1":             ErrPrivateAddress,
+		"0.0.0.0":         ErrPrivateAddress,
+		"10.0.0.1":        ErrPrivateAddress,
+		"172.16.5.4":      ErrPrivateAddress,
+		"192.168.1.1":     ErrPrivateAddress,
+		"100.64.0.1":      ErrPrivateAddress,
+		"169.254.169.254": ErrPrivateAddress,
+		"fe80::1":         ErrPrivateAddress,
+		"fd00::1":         ErrPrivateAddress,
+		"::ffff:10.0.0.1": ErrPrivateAddress,
+	} {
+		if got := CheckHost(host); got != want {
+			t.Errorf("CheckHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestSendPrivateAddress(t *testing.T) {
+	hits := 0
+	private := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { hits++ }))
+	defer private.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(private.URL, http.StatusFound))
+	defer redirect.Close()
+
+	d := NewDispatcher(nil, time.Minute)
+	delivery := database.WebhookDelivery{ID: uuid.New(), Event: EventChirpCreated, Payload: "{}"}
+
+	// The receiver's loopback address is refused when dialing.
+	_, err := d.send(context.Background(), database.WebhookSubscription{Url: private.URL}, delivery)
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("send() to %s error = %v, want %v", private.URL, err, ErrPrivateAddress)
+	}
+
+	// Redirects are not followed, wherever they point.
+	d.AllowPrivateNetworks = true
+	status, err := d.send(context.Background(), database.WebhookSubscription{Url: redirect.URL}, delivery)
+	if status != http.StatusFound || err == nil {
+		t.Errorf("send() through a redirect = %d, %v, want the redirect recorded as a failure", status, err)
+	}
+	if hits != 0 {
+		t.Errorf("private receiver got %d requests, want none", hits)
+	}
+}
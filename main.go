@@ -1,13 +1,37 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
-	"sync/atomic"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/ghis9917/chirpy/internal/cache"
+	"github.com/ghis9917/chirpy/internal/compress"
+	"github.com/ghis9917/chirpy/internal/config"
 	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/fileserver"
+	"github.com/ghis9917/chirpy/internal/health"
+	"github.com/ghis9917/chirpy/internal/logging"
+	"github.com/ghis9917/chirpy/internal/metrics"
+	"github.com/ghis9917/chirpy/internal/migrate"
+	"github.com/ghis9917/chirpy/internal/notifications"
+	"github.com/ghis9917/chirpy/internal/oidc"
+	"github.com/ghis9917/chirpy/internal/scheduler"
+	"github.com/ghis9917/chirpy/internal/store"
+	"github.com/ghis9917/chirpy/internal/store/cached"
+	"github.com/ghis9917/chirpy/internal/store/postgres"
+	"github.com/ghis9917/chirpy/internal/store/sqlite"
+	"github.com/ghis9917/chirpy/internal/stream"
+	"github.com/ghis9917/chirpy/internal/tracing"
+	"github.com/ghis9917/chirpy/internal/webhooks"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
@@ -15,65 +39,335 @@ import (
 func main() {
 
 	godotenv.Load()
-	const filepathRoot = "."
-	const port = "8080"
 
-	platform := os.Getenv("PLATFORM")
-	dbURL := os.Getenv("DB_URL")
-	serverSecret := os.Getenv("SERVER_SECRET")
-	polkaSecret := os.Getenv("POLKA_KEY")
+	// Until the config is loaded we log at info; the level is adjusted in
+	// place afterwards so loggers derived from the default keep working.
+	logLevel := new(slog.LevelVar)
+	logger := logging.New(os.Stderr, logLevel)
+	slog.SetDefault(logger)
 
-	db, err := sql.Open("postgres", dbURL)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			fatal("Error migrating database", err)
+		}
+		return
+	}
+
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if err != nil {
+		fatal("Error loading config", err)
+	}
+	if cfg.PrintConfig {
+		fmt.Print(cfg)
+		return
+	}
+	level, _ := cfg.Log.SlogLevel()
+	logLevel.Set(level)
+	slog.Info("Loaded config", "config", cfg.Redacted())
+
+	shutdownTracing, err := tracing.Setup(
+		context.Background(),
+		tracing.Config{
+			ServiceName: cfg.Tracing.ServiceName,
+			Exporter:    cfg.Tracing.Exporter,
+			Endpoint:    cfg.Tracing.Endpoint,
+			SampleRatio: cfg.Tracing.SampleRatio,
+		},
+	)
 	if err != nil {
-		log.Fatal(err)
+		fatal("Error setting up tracing", err)
 	}
-	dbQueries := database.New(db)
+
+	db, driver, err := openDatabase(cfg.Database.URL)
+	if err != nil {
+		fatal("Error opening database", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.Database.ConnMaxIdleTime)
+
+	// A second signal skips the graceful path and exits immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.Database.AutoMigrate {
+		if err := autoMigrate(ctx, db, driver); err != nil {
+			fatal("Error migrating database", err)
+		}
+	}
+
+	var dbStore store.Store = postgres.New(db)
+	if driver == migrate.SQLite {
+		dbStore = sqlite.New(db)
+	}
+	appMetrics := metrics.New(db)
+	var lookupCache cache.Cache
+	switch cfg.Cache.Backend {
+	case "memory":
+		lookupCache = cache.NewLRU(cfg.Cache.Size)
+	case "redis":
+		redis := cache.NewRedis(cfg.Cache.RedisAddr, "chirpy:")
+		defer redis.Close()
+		lookupCache = redis
+	}
+	if lookupCache != nil {
+		cachedStore := cached.New(dbStore, lookupCache, cfg.Cache.TTL)
+		cachedStore.OnLookup = appMetrics.CacheLookup
+		dbStore = cachedStore
+	}
+	relay := stream.NewRelay(dbStore, cfg.Workers.StreamRetention)
+	notifier := notifications.New(dbStore)
+	if driver == migrate.SQLite {
+		// SQLite cannot notify the relay, so the notifier hands it new
+		// notifications directly.
+		notifier.OnNotify = func(_ context.Context, n database.Notification) { relay.Notifications.Publish(n) }
+	}
+
+	closing, closeStreams := context.WithCancel(context.Background())
+	defer closeStreams()
 
 	apiCfg := apiConfig{
-		fileserverHits: atomic.Int32{},
-		db:             dbQueries,
-		platform:       platform,
-		serverSecret:   serverSecret,
-		polkaSecret:    polkaSecret,
+		metrics:       appMetrics,
+		closing:       closing.Done(),
+		db:            dbStore,
+		platform:      cfg.Server.Platform,
+		serverSecret:  cfg.Secrets.ServerSecret,
+		polkaSecret:   cfg.Secrets.PolkaKey,
+		chirps:        cfg.Chirps,
+		notifier:      notifier,
+		stream:        relay,
+		oidcProviders: loadOIDCProviders(cfg.OIDC),
+		health:        health.NewRegistry(READINESS_TIMEOUT),
 	}
+	apiCfg.health.Register(health.Flag("server", apiCfg.ready.Load, "not accepting traffic"))
+	apiCfg.health.Register(health.Database(db))
+	apiCfg.health.Register(health.Migrations(db, migrate.LatestVersion()))
+
+	// Background workers get their own context so they keep running while
+	// in-flight requests drain and are only stopped afterwards.
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+
+	chirpScheduler := scheduler.New(dbStore, cfg.Workers.SchedulerInterval)
+	chirpScheduler.OnPublish = apiCfg.chirpCreated
+	workers.Go(func() { chirpScheduler.Run(workersCtx) })
+	if driver == migrate.SQLite {
+		workers.Go(func() { relay.Poll(workersCtx, STREAM_POLL_INTERVAL) })
+	} else {
+		workers.Go(func() { relay.Listen(workersCtx, cfg.Database.URL) })
+	}
+	dispatcher := webhooks.NewDispatcher(dbStore, cfg.Workers.WebhookDispatchInterval)
+	dispatcher.OnAttempt = apiCfg.metrics.WebhookAttempt
+	workers.Go(func() { dispatcher.Run(workersCtx) })
+
+	assets, err := fileserver.New(
+		cfg.Server.FilepathRoot,
+		fileserver.Options{
+			Allow:      ASSET_ALLOW_LIST,
+			Private:    []string{MEDIA_PRIVATE_DIR},
+			SigningKey: []byte(cfg.Secrets.ServerSecret),
+		},
+	)
+	if err != nil {
+		fatal("Error opening file server", err)
+	}
+	defer assets.Close()
+	apiCfg.assets = assets
+
+	mux := apiCfg.routes()
+
+	server := http.Server{
+		Addr: cfg.Addr(),
+		Handler: tracing.Middleware(
+			logging.Middleware(logger, apiCfg.metrics.Middleware(compress.Middleware(COMPRESSION_MIN_SIZE, tracing.Route(mux)))),
+		),
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+	}
+	server.RegisterOnShutdown(closeStreams)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+	apiCfg.ready.Store(true)
+	slog.Info("Serving files", "root", cfg.Server.FilepathRoot, "port", cfg.Server.Port)
+
+	var serveFailed error
+	select {
+	case serveFailed = <-serveErr:
+	case <-ctx.Done():
+		stop()
+		slog.Info("Shutting down", "drain", cfg.Server.ShutdownDrain)
+		apiCfg.ready.Store(false)
+		time.Sleep(cfg.Server.ShutdownDrain)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Error draining requests", "err", err)
+		}
+	}
+
+	stopWorkers()
+	workers.Wait()
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("Error flushing traces", "err", err)
+	}
+
+	if serveFailed != nil {
+		fatal("Server failed", serveFailed)
+	}
+	slog.Info("Server stopped")
+
+}
+
+// routes registers every endpoint. Middleware that applies to all of them
+// is added around the mux in main.
+func (cfg *apiConfig) routes() *http.ServeMux {
 
 	mux := http.NewServeMux()
 	mux.Handle(
 		"/app/",
-		apiCfg.middlewareMetricsInc(
+		cfg.middlewareMetricsInc(
 			http.StripPrefix(
 				"/app",
-				http.FileServer(
-					http.Dir(filepathRoot),
-				),
+				cfg.assets,
 			),
 		),
 	)
+	mux.Handle("GET /metrics", cfg.metrics.Handler())
 	// ============ ADMIN =============
-	mux.HandleFunc("GET /admin/metrics", apiCfg.handleGetMetrics)
-	mux.HandleFunc("POST /admin/reset", apiCfg.handleResetMetrics)
+	mux.HandleFunc("GET /admin/metrics", cfg.handleGetMetrics)
+	mux.HandleFunc("POST /admin/reset", cfg.handleResetMetrics)
 	// ============ API GET =============
-	mux.HandleFunc("GET /api/healthz", handleReadiness)
-	mux.HandleFunc("GET /api/chirps", apiCfg.handleGetAllChirps)
-	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.handleGetChirpByID)
+	mux.HandleFunc("GET /api/healthz", cfg.handleLiveness)
+	mux.HandleFunc("GET /api/readyz", cfg.handleReadiness)
+	mux.Handle("GET /api/users", cfg.middlewareScope(SCOPE_READ, cfg.handleGetUser))
+	mux.Handle("GET /api/chirps", cfg.middlewareOptionalAuth(cfg.handleGetAllChirps))
+	mux.Handle("GET /api/chirps/{chirpID}", cfg.middlewareOptionalAuth(cfg.handleGetChirpByID))
+	mux.Handle("GET /api/chirps/scheduled", cfg.middlewareScope(SCOPE_READ, cfg.handleGetScheduledChirps))
+	mux.Handle("GET /api/drafts", cfg.middlewareScope(SCOPE_READ, cfg.handleGetDrafts))
+	mux.Handle("GET /api/bookmarks", cfg.middlewareScope(SCOPE_READ, cfg.handleGetBookmarks))
+	mux.Handle("GET /api/collections", cfg.middlewareScope(SCOPE_READ, cfg.handleGetCollections))
+	mux.Handle("GET /api/collections/{collectionID}/chirps", cfg.middlewareScope(SCOPE_READ, cfg.handleGetCollectionChirps))
+	mux.Handle("GET /api/notifications", cfg.middlewareScope(SCOPE_READ, cfg.handleGetNotifications))
+	mux.Handle("GET /api/notifications/preferences", cfg.middlewareScope(SCOPE_READ, cfg.handleGetNotificationPreferences))
+	mux.HandleFunc("GET /api/stream", cfg.handleStream)
+	mux.Handle("GET /api/webhooks", cfg.middlewareScope(SCOPE_ADMIN, cfg.handleGetWebhooks))
+	mux.Handle("GET /api/webhooks/{webhookID}/deliveries", cfg.middlewareScope(SCOPE_ADMIN, cfg.handleGetWebhookDeliveries))
+	mux.Handle("GET /api/webhooks/{webhookID}/deliveries/{deliveryID}/attempts", cfg.middlewareScope(SCOPE_ADMIN, cfg.handleGetWebhookDeliveryAttempts))
+	// The WebSocket handler checks its own token, which browsers can only
+	// send in the query string.
+	mux.HandleFunc("GET /api/ws", cfg.handleWebSocket)
+	mux.Handle("GET /api/media/signed-url", cfg.middlewareScope(SCOPE_READ, cfg.handleSignMediaURL))
 	// ============ API POST =============
-	mux.HandleFunc("POST /api/chirps", apiCfg.handleCreateChirp)
-	mux.HandleFunc("POST /api/users", apiCfg.handleCreateUser)
-	mux.HandleFunc("POST /api/login", apiCfg.handleLogin)
-	mux.HandleFunc("POST /api/refresh", apiCfg.handleRefresh)
-	mux.HandleFunc("POST /api/revoke", apiCfg.handleRevoke)
-	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handleUpgradeUser)
+	mux.Handle("POST /api/chirps", cfg.middlewareScope(SCOPE_WRITE, cfg.handleCreateChirp))
+	mux.Handle("POST /api/drafts", cfg.middlewareScope(SCOPE_WRITE, cfg.handleCreateDraft))
+	mux.Handle("POST /api/drafts/{draftID}/publish", cfg.middlewareScope(SCOPE_WRITE, cfg.handlePublishDraft))
+	mux.Handle("POST /api/bookmarks/{chirpID}", cfg.middlewareScope(SCOPE_WRITE, cfg.handleCreateBookmark))
+	mux.Handle("POST /api/collections", cfg.middlewareScope(SCOPE_WRITE, cfg.handleCreateCollection))
+	mux.Handle("POST /api/collections/{collectionID}/chirps", cfg.middlewareScope(SCOPE_WRITE, cfg.handleAddCollectionChirp))
+	mux.Handle("POST /api/notifications/read", cfg.middlewareScope(SCOPE_WRITE, cfg.handleMarkNotificationsRead))
+	mux.Handle("POST /api/webhooks", cfg.middlewareScope(SCOPE_ADMIN, cfg.handleCreateWebhook))
+	mux.Handle("POST /api/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", cfg.middlewareScope(SCOPE_ADMIN, cfg.handleRedeliverWebhook))
+	mux.HandleFunc("POST /api/users", cfg.handleCreateUser)
+	mux.HandleFunc("POST /api/login", cfg.handleLogin)
+	mux.HandleFunc("POST /api/refresh", cfg.handleRefresh)
+	mux.HandleFunc("POST /api/revoke", cfg.handleRevoke)
+	mux.HandleFunc("POST /api/polka/webhooks", cfg.handleUpgradeUser)
+	// ============ OAUTH =============
+	mux.Handle("GET /api/oauth/authorize", cfg.middlewareFirstParty(cfg.handleAuthorize))
+	mux.Handle("POST /api/oauth/authorize", cfg.middlewareFirstParty(cfg.handleApproveAuthorization))
+	mux.HandleFunc("POST /api/oauth/token", cfg.handleOAuthToken)
+	mux.HandleFunc("POST /api/oauth/introspect", cfg.handleOAuthIntrospect)
+	mux.HandleFunc("POST /api/oauth/revoke", cfg.handleOAuthRevoke)
+	mux.Handle("GET /api/oauth/clients", cfg.middlewareFirstParty(cfg.handleGetOAuthClients))
+	mux.Handle("POST /api/oauth/clients", cfg.middlewareFirstParty(cfg.handleCreateOAuthClient))
+	mux.Handle("DELETE /api/oauth/clients/{clientID}", cfg.middlewareFirstParty(cfg.handleDeleteOAuthClient))
+	mux.Handle("GET /api/oauth/consents", cfg.middlewareFirstParty(cfg.handleGetOAuthConsents))
+	mux.Handle("DELETE /api/oauth/consents/{clientID}", cfg.middlewareFirstParty(cfg.handleDeleteOAuthConsent))
+	// ============ API KEYS =============
+	mux.Handle("GET /api/keys", cfg.middlewareFirstParty(cfg.handleGetAPIKeys))
+	mux.Handle("POST /api/keys", cfg.middlewareFirstParty(cfg.handleCreateAPIKey))
+	mux.Handle("DELETE /api/keys/{keyID}", cfg.middlewareFirstParty(cfg.handleRevokeAPIKey))
+	// ============ IDENTITIES =============
+	mux.HandleFunc("GET /api/auth/{provider}/login", cfg.handleOIDCLogin)
+	mux.HandleFunc("GET /api/auth/{provider}/callback", cfg.handleOIDCCallback)
+	mux.Handle("POST /api/auth/{provider}/link", cfg.middlewareFirstParty(cfg.handleLinkIdentity))
+	mux.Handle("GET /api/identities", cfg.middlewareFirstParty(cfg.handleGetIdentities))
+	mux.Handle("DELETE /api/identities/{identityID}", cfg.middlewareFirstParty(cfg.handleUnlinkIdentity))
 	// ============ API PUT =============
-	mux.HandleFunc("PUT /api/users", apiCfg.handleUpdateUser)
+	mux.Handle("PUT /api/users", cfg.middlewareFirstParty(cfg.handleUpdateUser))
+	mux.Handle("PUT /api/drafts/{draftID}", cfg.middlewareScope(SCOPE_WRITE, cfg.handleUpdateDraft))
+	mux.Handle("PUT /api/collections/{collectionID}", cfg.middlewareScope(SCOPE_WRITE, cfg.handleRenameCollection))
+	mux.Handle("PUT /api/notifications/preferences", cfg.middlewareScope(SCOPE_WRITE, cfg.handleUpdateNotificationPreferences))
 	// ============ API DELETE =============
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handleDeleteChirpByID)
+	mux.Handle("DELETE /api/users", cfg.middlewareFirstParty(cfg.handleDeleteUser))
+	mux.Handle("DELETE /api/chirps/{chirpID}", cfg.middlewareScope(SCOPE_WRITE, cfg.handleDeleteChirpByID))
+	mux.Handle("DELETE /api/chirps/scheduled/{scheduledID}", cfg.middlewareScope(SCOPE_WRITE, cfg.handleDeleteScheduledChirp))
+	mux.Handle("DELETE /api/drafts/{draftID}", cfg.middlewareScope(SCOPE_WRITE, cfg.handleDeleteDraft))
+	mux.Handle("DELETE /api/webhooks/{webhookID}", cfg.middlewareScope(SCOPE_ADMIN, cfg.handleDeleteWebhook))
+	mux.Handle("DELETE /api/bookmarks/{chirpID}", cfg.middlewareScope(SCOPE_WRITE, cfg.handleDeleteBookmark))
+	mux.Handle("DELETE /api/collections/{collectionID}", cfg.middlewareScope(SCOPE_WRITE, cfg.handleDeleteCollection))
+	mux.Handle("DELETE /api/collections/{collectionID}/chirps/{chirpID}", cfg.middlewareScope(SCOPE_WRITE, cfg.handleRemoveCollectionChirp))
 
-	server := http.Server{
-		Addr:    ":" + port,
-		Handler: mux,
+	return mux
+}
+
+// openDatabase opens the database DB_URL points at and returns its driver
+// name. "sqlite:path" (or "sqlite://path") opens a SQLite file for local
+// development; anything else is handed to the Postgres driver.
+func openDatabase(dbURL string) (*sql.DB, string, error) {
+
+	if path, ok := strings.CutPrefix(dbURL, "sqlite:"); ok {
+		db, err := sqlite.Open(strings.TrimPrefix(path, "//"))
+		return db, migrate.SQLite, err
 	}
 
-	log.Printf("Serving files from %s on port: %s\n", filepathRoot, port)
-	log.Fatal(server.ListenAndServe())
+	db, err := sql.Open(migrate.Postgres, dbURL)
+	return db, migrate.Postgres, err
+}
+
+// loadOIDCProviders discovers every configured identity provider. A provider
+// that cannot be discovered is logged and left out.
+func loadOIDCProviders(configs []config.OIDCProvider) map[string]*oidc.Provider {
+
+	providers := map[string]*oidc.Provider{}
+	for _, c := range configs {
+		ctx, cancel := context.WithTimeout(context.Background(), OIDC_DISCOVERY_TIMEOUT)
+		provider, err := oidc.NewProvider(
+			ctx,
+			oidc.Config{
+				Name:         c.Name,
+				Issuer:       c.Issuer,
+				ClientID:     c.ClientID,
+				ClientSecret: c.ClientSecret,
+				RedirectURL:  c.RedirectURL,
+			},
+		)
+		cancel()
+		if err != nil {
+			slog.Warn("Skipping identity provider", "provider", c.Name, "err", err)
+			continue
+		}
+		providers[c.Name] = provider
+	}
+
+	return providers
+}
 
+// fatal logs err and exits, like log.Fatal, without leaving the structured
+// log format.
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
 }
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/config"
+	"github.com/ghis9917/chirpy/internal/migrate"
+)
+
+const MIGRATE_USAGE = "usage: chirpy migrate up|down|status|redo [--config file]"
+
+// runMigrate implements the migrate subcommand. It only needs the database
+// URL, so the rest of the config is not validated.
+func runMigrate(args []string) error {
+
+	if len(args) == 0 {
+		return errors.New(MIGRATE_USAGE)
+	}
+	command := args[0]
+	if !slices.Contains([]string{"up", "down", "status", "redo"}, command) {
+		return fmt.Errorf("unknown migrate command %q\n%s", command, MIGRATE_USAGE)
+	}
+
+	cfg, err := config.Parse(args[1:], os.Getenv)
+	if err != nil {
+		return err
+	}
+	if cfg.Database.URL == "" {
+		return errors.New("database url is required (DB_URL)")
+	}
+
+	db, driver, err := openDatabase(cfg.Database.URL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migrate.New(db, driver)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch command {
+	case "up":
+		results, err := migrator.Up(ctx)
+		for _, r := range results {
+			fmt.Println(r)
+		}
+		if err == nil && len(results) == 0 {
+			fmt.Println("Already up to date")
+		}
+		return err
+	case "down":
+		result, err := migrator.Down(ctx)
+		if migrate.IsNoChange(err) {
+			fmt.Println("No migrations to roll back")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println(result)
+		return nil
+	case "redo":
+		results, err := migrator.Redo(ctx)
+		if migrate.IsNoChange(err) {
+			fmt.Println("No migrations to redo")
+			return nil
+		}
+		for _, r := range results {
+			fmt.Println(r)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tSTATE\tAPPLIED AT\tFILE")
+		for _, s := range statuses {
+			appliedAt := "-"
+			if !s.AppliedAt.IsZero() {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Source.Version, s.State, appliedAt, s.Source.Path)
+		}
+		return w.Flush()
+	}
+
+	return nil
+}
+
+// autoMigrate applies pending migrations at startup when enabled.
+func autoMigrate(ctx context.Context, db *sql.DB, driver string) error {
+
+	migrator, err := migrate.New(db, driver)
+	if err != nil {
+		return err
+	}
+
+	results, err := migrator.Up(ctx)
+	for _, r := range results {
+		slog.Info("Migrated", "migration", r.String())
+	}
+
+	return err
+}
@@ -1,28 +1,85 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
 	"sync/atomic"
 	"time"
 
-	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/config"
+	"github.com/ghis9917/chirpy/internal/fileserver"
+	"github.com/ghis9917/chirpy/internal/health"
+	"github.com/ghis9917/chirpy/internal/metrics"
+	"github.com/ghis9917/chirpy/internal/notifications"
+	"github.com/ghis9917/chirpy/internal/oidc"
+	"github.com/ghis9917/chirpy/internal/store"
+	"github.com/ghis9917/chirpy/internal/stream"
+	"github.com/ghis9917/chirpy/internal/webhooks"
 	"github.com/google/uuid"
 )
 
 type apiConfig struct {
-	fileserverHits atomic.Int32
-	db             *database.Queries
-	platform       string
-	serverSecret   string
-	polkaSecret    string
+	metrics *metrics.Metrics
+	ready   atomic.Bool
+	// closing is closed when shutdown starts so that long-lived SSE and
+	// WebSocket connections end instead of holding the server open.
+	closing       <-chan struct{}
+	db            store.Store
+	platform      string
+	serverSecret  string
+	polkaSecret   string
+	chirps        config.Chirps
+	assets        *fileserver.Server
+	notifier      *notifications.Service
+	stream        *stream.Relay
+	oidcProviders map[string]*oidc.Provider
+	health        *health.Registry
 }
 
 //===========/api/chirps: POST===============
 
 type createChirpParameters struct {
-	Body string `json:"body"`
+	Body        string     `json:"body"`
+	ScheduledAt *time.Time `json:"scheduled_at"`
 }
 
 type Chirp struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Body           string    `json:"body"`
+	UserID         uuid.UUID `json:"user_id"`
+	BookmarkedByMe *bool     `json:"bookmarked_by_me,omitempty"`
+}
+
+//===========/api/chirps/scheduled: GET===============
+
+type ScheduledChirp struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Body        string    `json:"body"`
+	UserID      uuid.UUID `json:"user_id"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+	Attempts    int32     `json:"attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	// NextAttemptAt is set while publishing is backing off from a failure.
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	// Failed is set once publishing has been given up on; the chirp stays
+	// listed until it is deleted.
+	Failed bool `json:"failed"`
+}
+
+//===========/api/drafts===============
+
+type draftParameters struct {
+	Body string `json:"body"`
+}
+
+type Draft struct {
 	ID        uuid.UUID `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -30,6 +87,285 @@ type Chirp struct {
 	UserID    uuid.UUID `json:"user_id"`
 }
 
+//===========/api/collections===============
+
+type collectionParameters struct {
+	Name string `json:"name"`
+}
+
+func (p collectionParameters) validate(v *validation) {
+	v.required("name", p.Name)
+}
+
+type collectionChirpParameters struct {
+	ChirpID string `json:"chirp_id"`
+}
+
+func (p collectionChirpParameters) validate(v *validation) {
+	v.uuid("chirp_id", p.ChirpID)
+}
+
+type Collection struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uuid.UUID `json:"user_id"`
+	Name      string    `json:"name"`
+}
+
+//===========/api/notifications===============
+
+type NotificationGroup struct {
+	Type     string      `json:"type"`
+	ChirpID  *uuid.UUID  `json:"chirp_id,omitempty"`
+	Count    int32       `json:"count"`
+	Unread   int32       `json:"unread"`
+	LatestAt time.Time   `json:"latest_at"`
+	ActorIDs []uuid.UUID `json:"actor_ids"`
+	Summary  string      `json:"summary"`
+}
+
+type notificationsResponse struct {
+	UnreadCount   int64               `json:"unread_count"`
+	Notifications []NotificationGroup `json:"notifications"`
+}
+
+type markNotificationsReadParameters struct {
+	Type    string `json:"type"`
+	ChirpID string `json:"chirp_id"`
+}
+
+// validate allows either field to be empty: marking everything read is the
+// default.
+func (p markNotificationsReadParameters) validate(v *validation) {
+
+	if p.Type != "" && !notifications.Type(p.Type).Valid() {
+		v.fail("type", "must be a notification type")
+	}
+	if p.ChirpID != "" {
+		v.uuid("chirp_id", p.ChirpID)
+	}
+
+}
+
+type notificationPreferences struct {
+	Muted []string `json:"muted"`
+}
+
+func (p notificationPreferences) validate(v *validation) {
+	for i, t := range p.Muted {
+		if !notifications.Type(t).Valid() {
+			v.fail(fmt.Sprintf("muted[%d]", i), "must be a notification type")
+		}
+	}
+}
+
+type Notification struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Type      string     `json:"type"`
+	ActorID   uuid.UUID  `json:"actor_id"`
+	ChirpID   *uuid.UUID `json:"chirp_id,omitempty"`
+	Read      bool       `json:"read"`
+}
+
+//===========/api/ws===============
+
+type wsClientMessage struct {
+	Type         string `json:"type"`
+	Topic        string `json:"topic,omitempty"`
+	ID           string `json:"id,omitempty"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+type wsServerMessage struct {
+	Type      string     `json:"type"`
+	Topic     string     `json:"topic,omitempty"`
+	ID        string     `json:"id,omitempty"`
+	Event     string     `json:"event,omitempty"`
+	Data      any        `json:"data,omitempty"`
+	Token     string     `json:"token,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+//===========/api/webhooks===============
+
+type createWebhookParameters struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+func (p createWebhookParameters) validate(v *validation) {
+
+	if v.httpURL("url", p.URL, false) {
+		// validate has checked the URL parses.
+		target, _ := url.Parse(p.URL)
+		if webhooks.CheckHost(target.Hostname()) != nil {
+			v.fail("url", "must point to a public address")
+		}
+	}
+
+	if len(p.Events) == 0 {
+		v.fail("events", "must list at least one event")
+	}
+	for i, event := range p.Events {
+		if !slices.Contains(webhooks.Events, event) {
+			v.fail(fmt.Sprintf("events[%d]", i), "must be one of %s", strings.Join(webhooks.Events, ", "))
+		}
+	}
+
+}
+
+type WebhookSubscription struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+}
+
+type WebhookDelivery struct {
+	ID            uuid.UUID       `json:"id"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	Event         string          `json:"event"`
+	Status        string          `json:"status"`
+	Attempts      int32           `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+type WebhookDeliveryAttempt struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	StatusCode *int32    `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int32     `json:"duration_ms"`
+}
+
+//===========/api/oauth===============
+
+type createOAuthClientParameters struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+	Confidential bool     `json:"confidential"`
+}
+
+// validate leaves scopes to parseScopes, which the authorization endpoint
+// shares.
+func (p createOAuthClientParameters) validate(v *validation) {
+
+	v.required("name", p.Name)
+
+	if len(p.RedirectURIs) == 0 {
+		v.fail("redirect_uris", "must list at least one URI")
+	}
+	for i, redirectURI := range p.RedirectURIs {
+		v.httpURL(fmt.Sprintf("redirect_uris[%d]", i), redirectURI, true)
+	}
+
+}
+
+type OAuthClient struct {
+	ID           string    `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	Secret       string    `json:"client_secret,omitempty"`
+}
+
+type authorizeParameters struct {
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+	Approve             bool   `json:"approve"`
+}
+
+type consentPrompt struct {
+	Client      OAuthClient `json:"client"`
+	Scopes      []string    `json:"scopes"`
+	RedirectURI string      `json:"redirect_uri"`
+	State       string      `json:"state,omitempty"`
+}
+
+type authorizeResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+type oauthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+type introspectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+}
+
+type OAuthConsent struct {
+	ClientID  string    `json:"client_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Scopes    []string  `json:"scopes"`
+}
+
+//===========/api/keys===============
+
+type createAPIKeyParameters struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (p createAPIKeyParameters) validate(v *validation) {
+
+	v.required("name", p.Name)
+	if p.ExpiresAt != nil && !p.ExpiresAt.After(time.Now()) {
+		v.fail("expires_at", "must be in the future")
+	}
+
+}
+
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Key        string     `json:"key,omitempty"`
+}
+
+//===========/api/identities===============
+
+type Identity struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email"`
+}
+
 //===========/api/users: POST===============
 
 type createUserParameters struct {
@@ -37,6 +373,11 @@ type createUserParameters struct {
 	Password string `json:"password"`
 }
 
+func (p createUserParameters) validate(v *validation) {
+	v.email("email", p.Email)
+	v.required("password", p.Password)
+}
+
 type createUserResponse struct {
 	ID          uuid.UUID `json:"id"`
 	CreatedAt   time.Time `json:"created_at"`
@@ -52,6 +393,13 @@ type loginUserParameters struct {
 	Password string `json:"password"`
 }
 
+// validate does not check the email's format: an account with any other
+// email cannot exist, and login answers the same for it either way.
+func (p loginUserParameters) validate(v *validation) {
+	v.required("email", p.Email)
+	v.required("password", p.Password)
+}
+
 type loginUserResponse struct {
 	ID           uuid.UUID `json:"id"`
 	CreatedAt    time.Time `json:"created_at"`
@@ -68,13 +416,18 @@ type refreshReponse struct {
 	Token string `json:"token"`
 }
 
-//===========/api/users: POST===============
+//===========/api/users: GET, PUT===============
 
 type updateUserParameters struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
+func (p updateUserParameters) validate(v *validation) {
+	v.email("email", p.Email)
+	v.required("password", p.Password)
+}
+
 type updateUserResponse struct {
 	ID          uuid.UUID `json:"id"`
 	CreatedAt   time.Time `json:"created_at"`
@@ -90,12 +443,48 @@ type upgradeUserParams struct {
 	Data  upgradeUserParamsData `json:"data"`
 }
 
+// Polka may add fields to its events; rejecting them would fail upgrades.
+func (upgradeUserParams) allowUnknownFields() {}
+
 type upgradeUserParamsData struct {
 	UserID string `json:"user_id"`
 }
 
+//===========/api/media/signed-url: GET===============
+
+type signedURLResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+//===========/admin/metrics: GET===============
+
+type metricSummary struct {
+	label  string
+	metric string
+	labels map[string]string
+}
+
 //===========Error Handling===============
 
 type jsonErr struct {
 	Error string `json:"error"`
 }
+
+// validationErr lists every invalid field, keeping jsonErr's error for
+// clients that only read that.
+type validationErr struct {
+	Error  string       `json:"error"`
+	Fields []fieldError `json:"fields"`
+}
+
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// oauthErr follows the RFC 6749 error response format.
+type oauthErr struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
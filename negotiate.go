@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/logging"
+)
+
+// negotiate returns the offer the Accept header weighs highest, or "" if it
+// accepts none of them. Each offer takes the weight of the most specific
+// range matching it, so "text/csv;q=0, */*" rules CSV out. A request without
+// Accept gets the first offer.
+func negotiate(header string, offers []string) string {
+
+	if strings.TrimSpace(header) == "" {
+		return offers[0]
+	}
+
+	type mediaRange struct {
+		typ, subtype string
+		q            float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+		}
+		typ, subtype, _ := strings.Cut(mediaType, "/")
+		ranges = append(ranges, mediaRange{typ: typ, subtype: subtype, q: q})
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		mediaType, _, err := mime.ParseMediaType(offer)
+		if err != nil {
+			continue
+		}
+		typ, subtype, _ := strings.Cut(mediaType, "/")
+
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			s := 0
+			switch {
+			case r.typ == typ && r.subtype == subtype:
+				s = 2
+			case r.typ == typ && r.subtype == "*":
+				s = 1
+			case r.typ == "*" && r.subtype == "*":
+				s = 0
+			default:
+				continue
+			}
+			if s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+
+	return best
+}
+
+// sendChirps sends a list of chirps as JSON, newline-delimited JSON or CSV,
+// whichever the client's Accept header prefers. JSON is tagged for
+// conditional requests like any other resource. The other formats are meant
+// for exports; the list is already in memory, so they are refused past
+// CHIRP_EXPORT_LIMIT chirps rather than tie up a connection for long.
+func sendChirps(w http.ResponseWriter, req *http.Request, chirps []Chirp) {
+
+	w.Header().Add("Vary", "Accept")
+
+	format := negotiate(req.Header.Get("Accept"), CHIRP_LIST_FORMATS)
+	if (format == CONTENT_TYPE_NDJSON || format == CONTENT_TYPE_CSV) && len(chirps) > CHIRP_EXPORT_LIMIT {
+		sendJSONResponse(w, http.StatusUnprocessableEntity, jsonErr{
+			Error: fmt.Sprintf("Exports are limited to %d chirps, filter or page through the list", CHIRP_EXPORT_LIMIT),
+		})
+		return
+	}
+
+	switch format {
+	case CONTENT_TYPE_JSON:
+		// Deleting a chirp leaves the latest updated_at as it was, so lists
+		// are only validated by their ETag.
+		sendConditionalJSONResponse(w, req, http.StatusOK, chirps, time.Time{})
+	case CONTENT_TYPE_NDJSON:
+		writeChirps(w, req, CONTENT_TYPE_NDJSON, chirps, writeNDJSON)
+	case CONTENT_TYPE_CSV:
+		writeChirps(w, req, CONTENT_TYPE_CSV, chirps, writeCSV)
+	default:
+		sendJSONResponse(w, http.StatusNotAcceptable, jsonErr{
+			Error: fmt.Sprintf("Not acceptable, available formats: %s", strings.Join(CHIRP_LIST_FORMATS, ", ")),
+		})
+	}
+
+}
+
+// writeChirps encodes chirps with write, flushing every
+// EXPORT_FLUSH_INTERVAL rows so the encoded output is not all buffered at
+// once. Once the first row is out the status cannot change, so failures are
+// only logged.
+func writeChirps(w http.ResponseWriter, req *http.Request, contentType string, chirps []Chirp, write func(w http.ResponseWriter, rc *http.ResponseController, chirps []Chirp) error) {
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if req.Method == http.MethodHead {
+		return
+	}
+
+	if err := write(w, http.NewResponseController(w), chirps); err != nil {
+		logging.FromContext(req.Context()).Warn("Error writing chirps", "content_type", contentType, "err", err)
+	}
+
+}
+
+func writeNDJSON(w http.ResponseWriter, rc *http.ResponseController, chirps []Chirp) error {
+
+	encoder := json.NewEncoder(w)
+	for i, chirp := range chirps {
+		if err := encoder.Encode(chirp); err != nil {
+			return err
+		}
+		if (i+1)%EXPORT_FLUSH_INTERVAL == 0 {
+			if err := rc.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeCSV leaves bookmarked_by_me empty for anonymous callers.
+func writeCSV(w http.ResponseWriter, rc *http.ResponseController, chirps []Chirp) error {
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(CHIRP_CSV_HEADER); err != nil {
+		return err
+	}
+	for i, chirp := range chirps {
+		bookmarked := ""
+		if chirp.BookmarkedByMe != nil {
+			bookmarked = strconv.FormatBool(*chirp.BookmarkedByMe)
+		}
+		record := []string{
+			chirp.ID.String(),
+			chirp.CreatedAt.UTC().Format(time.RFC3339Nano),
+			chirp.UpdatedAt.UTC().Format(time.RFC3339Nano),
+			chirp.Body,
+			chirp.UserID.String(),
+			bookmarked,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+		if (i+1)%EXPORT_FLUSH_INTERVAL == 0 {
+			writer.Flush()
+			if err := rc.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+
+	return writer.Error()
+}
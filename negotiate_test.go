@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: CONTENT_TYPE_JSON},
+		{header: "*/*", want: CONTENT_TYPE_JSON},
+		{header: "application/x-ndjson", want: CONTENT_TYPE_NDJSON},
+		{header: "text/csv", want: CONTENT_TYPE_CSV},
+		{header: "text/*", want: CONTENT_TYPE_CSV},
+		{header: "application/json;q=0.5, text/csv", want: CONTENT_TYPE_CSV},
+		{header: "text/csv;q=0, */*;q=0.1", want: CONTENT_TYPE_JSON},
+		{header: "application/*;q=0.2, application/json;q=0", want: CONTENT_TYPE_NDJSON},
+		{header: "text/html", want: ""},
+		{header: "application/json;q=0", want: ""},
+		{header: "not a type, text/csv", want: CONTENT_TYPE_CSV},
+	}
+
+	for _, tt := range tests {
+		if got := negotiate(tt.header, CHIRP_LIST_FORMATS); got != tt.want {
+			t.Errorf("negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestSendChirpsExportLimit(t *testing.T) {
+	chirps := make([]Chirp, CHIRP_EXPORT_LIMIT+1)
+
+	for accept, want := range map[string]int{
+		CONTENT_TYPE_JSON:   http.StatusOK,
+		CONTENT_TYPE_NDJSON: http.StatusUnprocessableEntity,
+		CONTENT_TYPE_CSV:    http.StatusUnprocessableEntity,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/chirps", nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		sendChirps(rec, req, chirps)
+		if rec.Code != want {
+			t.Errorf("export of %d chirps as %s = %d, want %d", len(chirps), accept, rec.Code, want)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps", nil)
+	req.Header.Set("Accept", CONTENT_TYPE_CSV)
+	rec := httptest.NewRecorder()
+	sendChirps(rec, req, chirps[:CHIRP_EXPORT_LIMIT])
+	if rec.Code != http.StatusOK {
+		t.Errorf("export of %d chirps = %d, want %d", CHIRP_EXPORT_LIMIT, rec.Code, http.StatusOK)
+	}
+}
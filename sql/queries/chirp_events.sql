@@ -0,0 +1,15 @@
+-- name: GetChirpEventByID :one
+SELECT *
+FROM chirp_events
+WHERE id = $1;
+
+-- name: GetChirpEventsSince :many
+SELECT *
+FROM chirp_events
+WHERE id > $1
+ORDER BY id ASC
+LIMIT $2;
+
+-- name: DeleteChirpEventsBefore :exec
+DELETE FROM chirp_events
+WHERE created_at < $1;
\ No newline at end of file
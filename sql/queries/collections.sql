@@ -0,0 +1,53 @@
+-- name: CreateCollection :one
+INSERT INTO collections (id, created_at, updated_at, user_id, name)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2
+)
+RETURNING *;
+
+-- name: GetCollectionByID :one
+SELECT *
+FROM collections
+WHERE id = $1;
+
+-- name: GetCollectionsByUser :many
+SELECT *
+FROM collections
+WHERE user_id = $1
+ORDER BY name ASC
+LIMIT $2 OFFSET $3;
+
+-- name: RenameCollection :one
+UPDATE collections
+SET name = $1, updated_at = NOW()
+WHERE id = $2
+RETURNING *;
+
+-- name: DeleteCollectionByID :exec
+DELETE FROM collections
+WHERE id = $1;
+
+-- name: AddChirpToCollection :exec
+INSERT INTO collection_chirps (collection_id, chirp_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveChirpFromCollection :exec
+DELETE FROM collection_chirps
+WHERE collection_id = $1 AND chirp_id = $2;
+
+-- name: GetCollectionChirps :many
+SELECT chirps.*
+FROM chirps
+JOIN collection_chirps ON collection_chirps.chirp_id = chirps.id
+WHERE collection_chirps.collection_id = $1
+ORDER BY collection_chirps.created_at DESC
+LIMIT $2 OFFSET $3;
\ No newline at end of file
@@ -0,0 +1,58 @@
+-- name: CreateIdentity :one
+INSERT INTO identities (id, created_at, updated_at, user_id, provider, subject, email)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: GetIdentityByID :one
+SELECT *
+FROM identities
+WHERE id = $1;
+
+-- name: GetIdentityBySubject :one
+SELECT *
+FROM identities
+WHERE provider = $1 AND subject = $2;
+
+-- name: GetIdentitiesByUser :many
+SELECT *
+FROM identities
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: CountIdentitiesByUser :one
+SELECT COUNT(*)
+FROM identities
+WHERE user_id = $1;
+
+-- name: DeleteIdentityByID :exec
+DELETE FROM identities
+WHERE id = $1;
+
+-- name: CreateOIDCLoginState :exec
+INSERT INTO oidc_login_states (state_hash, created_at, provider, nonce, code_verifier, user_id, expires_at)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+);
+
+-- name: ConsumeOIDCLoginState :one
+DELETE FROM oidc_login_states
+WHERE state_hash = $1 AND expires_at > NOW()
+RETURNING *;
+
+-- name: DeleteExpiredOIDCLoginStates :exec
+DELETE FROM oidc_login_states
+WHERE expires_at <= NOW();
\ No newline at end of file
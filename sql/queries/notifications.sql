@@ -0,0 +1,68 @@
+-- name: CreateNotification :one
+INSERT INTO notifications (id, created_at, user_id, actor_id, type, chirp_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: GetNotificationByID :one
+SELECT *
+FROM notifications
+WHERE id = $1;
+
+-- name: GetGroupedNotifications :many
+SELECT
+    type,
+    chirp_id,
+    COUNT(*)::int AS count,
+    (COUNT(*) FILTER (WHERE read_at IS NULL))::int AS unread,
+    MAX(created_at)::timestamp AS latest_at,
+    (array_agg(actor_id ORDER BY created_at DESC))[1:5]::uuid[] AS actor_ids
+FROM notifications
+WHERE user_id = $1
+GROUP BY type, chirp_id
+ORDER BY latest_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*)
+FROM notifications
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = sqlc.arg(user_id)
+    AND read_at IS NULL
+    AND (sqlc.narg(type)::text IS NULL OR type = sqlc.narg(type))
+    AND (sqlc.narg(chirp_id)::uuid IS NULL OR chirp_id = sqlc.narg(chirp_id));
+
+-- name: IsNotificationTypeMuted :one
+SELECT EXISTS (
+    SELECT 1
+    FROM muted_notification_types
+    WHERE user_id = $1 AND type = $2
+);
+
+-- name: GetMutedNotificationTypes :many
+SELECT type
+FROM muted_notification_types
+WHERE user_id = $1
+ORDER BY type ASC;
+
+-- name: MuteNotificationType :exec
+INSERT INTO muted_notification_types (user_id, type)
+VALUES (
+    $1,
+    $2
+)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteMutedNotificationTypes :exec
+DELETE FROM muted_notification_types
+WHERE user_id = $1;
\ No newline at end of file
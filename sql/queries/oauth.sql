@@ -0,0 +1,101 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (id, created_at, updated_at, user_id, name, secret_hash, redirect_uris, scopes)
+VALUES (
+    $1,
+    NOW(),
+    NOW(),
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING *;
+
+-- name: GetOAuthClientByID :one
+SELECT *
+FROM oauth_clients
+WHERE id = $1;
+
+-- name: GetOAuthClientsByUser :many
+SELECT *
+FROM oauth_clients
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: DeleteOAuthClientByID :exec
+DELETE FROM oauth_clients
+WHERE id = $1;
+
+-- name: CreateAuthorizationCode :exec
+INSERT INTO oauth_authorization_codes (code_hash, created_at, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    $4,
+    $5::text[],
+    $6,
+    $7
+);
+
+-- name: ConsumeAuthorizationCode :one
+UPDATE oauth_authorization_codes
+SET used_at = NOW()
+WHERE code_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+RETURNING *;
+
+-- name: UpsertOAuthConsent :one
+INSERT INTO oauth_consents (user_id, client_id, created_at, updated_at, scopes)
+VALUES (
+    $1,
+    $2,
+    NOW(),
+    NOW(),
+    $3
+)
+ON CONFLICT (user_id, client_id) DO UPDATE
+SET scopes = EXCLUDED.scopes, updated_at = NOW()
+RETURNING *;
+
+-- name: GetOAuthConsent :one
+SELECT *
+FROM oauth_consents
+WHERE user_id = $1 AND client_id = $2;
+
+-- name: GetOAuthConsentsByUser :many
+SELECT *
+FROM oauth_consents
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: DeleteOAuthConsent :exec
+DELETE FROM oauth_consents
+WHERE user_id = $1 AND client_id = $2;
+
+-- name: CreateOAuthAccessToken :exec
+INSERT INTO oauth_access_tokens (id, created_at, client_id, user_id, scopes, expires_at)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    $4::text[],
+    $5
+);
+
+-- name: GetOAuthAccessToken :one
+SELECT *
+FROM oauth_access_tokens
+WHERE id = $1;
+
+-- name: RevokeOAuthAccessToken :exec
+UPDATE oauth_access_tokens
+SET revoked_at = NOW()
+WHERE id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeOAuthAccessTokensForClient :exec
+UPDATE oauth_access_tokens
+SET revoked_at = NOW()
+WHERE user_id = $1 AND client_id = $2 AND revoked_at IS NULL;
\ No newline at end of file
@@ -18,3 +18,21 @@ WHERE token = $1;
 UPDATE refresh_tokens
 SET revoked_at = $1, updated_at = $1
 WHERE token = $2;
+
+-- name: CreateOAuthRefreshToken :one
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, client_id, scopes)
+VALUES (
+    $1,
+    NOW(),
+    NOW(),
+    $2,
+    NOW() + INTERVAL '60 days',
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: RevokeRefreshTokensForClient :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND client_id = $2 AND revoked_at IS NULL;
\ No newline at end of file
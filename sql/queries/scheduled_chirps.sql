@@ -0,0 +1,41 @@
+-- name: CreateScheduledChirp :one
+INSERT INTO scheduled_chirps (id, created_at, updated_at, body, user_id, scheduled_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+RETURNING *;
+
+-- name: GetScheduledChirpsByUser :many
+SELECT *
+FROM scheduled_chirps
+WHERE user_id = $1
+ORDER BY scheduled_at ASC;
+
+-- name: GetScheduledChirpByID :one
+SELECT *
+FROM scheduled_chirps
+WHERE id = $1;
+
+-- name: DeleteScheduledChirpByID :exec
+DELETE FROM scheduled_chirps
+WHERE id = $1;
+
+-- name: ClaimDueScheduledChirp :one
+SELECT *
+FROM scheduled_chirps
+WHERE scheduled_at <= NOW()
+    AND (next_attempt_at IS NULL OR next_attempt_at <= NOW())
+    AND attempts < $1
+ORDER BY scheduled_at ASC
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: RecordScheduledChirpFailure :exec
+UPDATE scheduled_chirps
+SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2, updated_at = NOW()
+WHERE id = $3;
\ No newline at end of file
@@ -12,11 +12,20 @@ RETURNING *;
 -- name: DeleteAllUsers :exec
 DELETE FROM users;
 
+-- name: DeleteUserByID :exec
+DELETE FROM users
+WHERE id = $1;
+
 -- name: GetUserByEmail :one
 SELECT *
 FROM users
 WHERE email = $1;
 
+-- name: GetUserCredentialsByEmail :one
+SELECT *
+FROM users
+WHERE email = $1;
+
 -- name: UpdateUser :one
 UPDATE users
 SET email = $1, hashed_password = $2
@@ -26,4 +35,9 @@ RETURNING *;
 -- name: UpgradeUser :exec
 UPDATE users
 SET is_chirpy_red = true
+WHERE id = $1;
+
+-- name: GetUserByID :one
+SELECT *
+FROM users
 WHERE id = $1;
\ No newline at end of file
@@ -8,4 +8,4 @@ CREATE TABLE chirps (
 );
 
 -- +goose Down
-DROP TABLE users;
\ No newline at end of file
+DROP TABLE chirps;
\ No newline at end of file
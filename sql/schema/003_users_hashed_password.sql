@@ -3,5 +3,5 @@ ALTER TABLE users
 ADD COLUMN hashed_password TEXT NOT NULL DEFAULT 'unset';
 
 -- +goose Down
-ALTER TABLE
+ALTER TABLE users
 DROP COLUMN hashed_password;
\ No newline at end of file
@@ -3,5 +3,5 @@ ALTER TABLE users
 ADD COLUMN is_chirpy_red BOOLEAN NOT NULL DEFAULT FALSE;
 
 -- +goose Down
-ALTER TABLE
+ALTER TABLE users
 DROP COLUMN is_chirpy_red;
\ No newline at end of file
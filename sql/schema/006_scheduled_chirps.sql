@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE scheduled_chirps (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    body TEXT NOT NULL,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    scheduled_at TIMESTAMP NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT
+);
+
+CREATE INDEX scheduled_chirps_scheduled_at_idx ON scheduled_chirps (scheduled_at);
+
+-- +goose Down
+DROP TABLE scheduled_chirps;
\ No newline at end of file
@@ -0,0 +1,48 @@
+-- +goose Up
+CREATE TABLE chirp_events (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    type TEXT NOT NULL,
+    chirp_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    body TEXT NOT NULL,
+    chirp_created_at TIMESTAMP NOT NULL,
+    chirp_updated_at TIMESTAMP NOT NULL
+);
+
+-- +goose StatementBegin
+CREATE FUNCTION record_chirp_event() RETURNS trigger AS $$
+DECLARE
+    chirp chirps%ROWTYPE;
+    kind TEXT;
+    event_id BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        chirp := OLD;
+        kind := 'chirp.deleted';
+    ELSIF TG_OP = 'UPDATE' THEN
+        chirp := NEW;
+        kind := 'chirp.updated';
+    ELSE
+        chirp := NEW;
+        kind := 'chirp.created';
+    END IF;
+
+    INSERT INTO chirp_events (created_at, type, chirp_id, user_id, body, chirp_created_at, chirp_updated_at)
+    VALUES (NOW(), kind, chirp.id, chirp.user_id, chirp.body, chirp.created_at, chirp.updated_at)
+    RETURNING id INTO event_id;
+
+    PERFORM pg_notify('chirp_events', event_id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirps_record_event
+AFTER INSERT OR UPDATE OR DELETE ON chirps
+FOR EACH ROW EXECUTE FUNCTION record_chirp_event();
+
+-- +goose Down
+DROP TRIGGER chirps_record_event ON chirps;
+DROP FUNCTION record_chirp_event();
+DROP TABLE chirp_events;
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION notify_notification_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('notification_events', NEW.id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER notifications_notify_event
+AFTER INSERT ON notifications
+FOR EACH ROW EXECUTE FUNCTION notify_notification_event();
+
+-- +goose Down
+DROP TRIGGER notifications_notify_event ON notifications;
+DROP FUNCTION notify_notification_event();
\ No newline at end of file
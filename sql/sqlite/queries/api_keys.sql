@@ -0,0 +1,40 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (id, created_at, updated_at, user_id, name, prefix, key_hash, scopes, expires_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?
+)
+RETURNING *;
+
+-- name: GetAPIKeyByID :one
+SELECT *
+FROM api_keys
+WHERE id = ?;
+
+-- name: GetAPIKeyByHash :one
+SELECT *
+FROM api_keys
+WHERE key_hash = ?;
+
+-- name: GetAPIKeysByUser :many
+SELECT *
+FROM api_keys
+WHERE user_id = ?
+ORDER BY created_at ASC;
+
+-- name: RevokeAPIKey :exec
+UPDATE api_keys
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE id = ? AND revoked_at IS NULL;
+
+-- name: TouchAPIKey :exec
+UPDATE api_keys
+SET last_used_at = NOW()
+WHERE id = ? AND (last_used_at IS NULL OR last_used_at < strftime('%Y-%m-%d %H:%M:%f+00:00', 'now', '-1 minute'));
\ No newline at end of file
@@ -0,0 +1,25 @@
+-- name: CreateBookmark :exec
+INSERT INTO bookmarks (user_id, chirp_id, created_at)
+VALUES (
+    ?,
+    ?,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteBookmark :exec
+DELETE FROM bookmarks
+WHERE user_id = ? AND chirp_id = ?;
+
+-- name: GetBookmarkedChirps :many
+SELECT chirps.*
+FROM chirps
+JOIN bookmarks ON bookmarks.chirp_id = chirps.id
+WHERE bookmarks.user_id = ?
+ORDER BY bookmarks.created_at DESC
+LIMIT ? OFFSET ?;
+
+-- name: GetBookmarkedChirpIDs :many
+SELECT chirp_id
+FROM bookmarks
+WHERE user_id = ? AND chirp_id IN (sqlc.slice(chirp_ids));
\ No newline at end of file
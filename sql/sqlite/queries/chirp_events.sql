@@ -0,0 +1,15 @@
+-- name: GetChirpEventByID :one
+SELECT *
+FROM chirp_events
+WHERE id = ?;
+
+-- name: GetChirpEventsSince :many
+SELECT *
+FROM chirp_events
+WHERE id > ?
+ORDER BY id ASC
+LIMIT ?;
+
+-- name: DeleteChirpEventsBefore :exec
+DELETE FROM chirp_events
+WHERE created_at < ?;
\ No newline at end of file
@@ -0,0 +1,30 @@
+-- name: CreateChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    ?,
+    ?
+)
+RETURNING *;
+
+-- name: GetAllChirps :many
+SELECT *
+FROM chirps
+ORDER BY created_at ASC;
+
+-- name: GetChirpByID :one
+SELECT *
+FROM chirps
+WHERE id = ?;
+
+-- name: DeleteChirpByID :exec
+DELETE FROM chirps 
+WHERE id = ?;
+
+-- name: GetAllChirpsByAuthor :many
+SELECT *
+FROM chirps
+WHERE user_id = ?
+ORDER BY created_at ASC;
\ No newline at end of file
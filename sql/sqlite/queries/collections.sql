@@ -0,0 +1,53 @@
+-- name: CreateCollection :one
+INSERT INTO collections (id, created_at, updated_at, user_id, name)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    ?,
+    ?
+)
+RETURNING *;
+
+-- name: GetCollectionByID :one
+SELECT *
+FROM collections
+WHERE id = ?;
+
+-- name: GetCollectionsByUser :many
+SELECT *
+FROM collections
+WHERE user_id = ?
+ORDER BY name ASC
+LIMIT ? OFFSET ?;
+
+-- name: RenameCollection :one
+UPDATE collections
+SET name = ?, updated_at = NOW()
+WHERE id = ?
+RETURNING *;
+
+-- name: DeleteCollectionByID :exec
+DELETE FROM collections
+WHERE id = ?;
+
+-- name: AddChirpToCollection :exec
+INSERT INTO collection_chirps (collection_id, chirp_id, created_at)
+VALUES (
+    ?,
+    ?,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveChirpFromCollection :exec
+DELETE FROM collection_chirps
+WHERE collection_id = ? AND chirp_id = ?;
+
+-- name: GetCollectionChirps :many
+SELECT chirps.*
+FROM chirps
+JOIN collection_chirps ON collection_chirps.chirp_id = chirps.id
+WHERE collection_chirps.collection_id = ?
+ORDER BY collection_chirps.created_at DESC
+LIMIT ? OFFSET ?;
\ No newline at end of file
@@ -0,0 +1,36 @@
+-- name: CreateDraft :one
+INSERT INTO drafts (id, created_at, updated_at, body, user_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    ?,
+    ?
+)
+RETURNING *;
+
+-- name: GetDraftsByUser :many
+SELECT *
+FROM drafts
+WHERE user_id = ?
+ORDER BY updated_at DESC;
+
+-- name: GetDraftByID :one
+SELECT *
+FROM drafts
+WHERE id = ?;
+
+-- name: UpdateDraft :one
+UPDATE drafts
+SET body = ?, updated_at = NOW()
+WHERE id = ?
+RETURNING *;
+
+-- name: DeleteDraftByID :exec
+DELETE FROM drafts
+WHERE id = ?;
+
+-- name: ClaimDraft :one
+DELETE FROM drafts
+WHERE id = ? AND user_id = ?
+RETURNING *;
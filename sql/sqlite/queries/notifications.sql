@@ -0,0 +1,79 @@
+-- name: CreateNotification :one
+INSERT INTO notifications (id, created_at, user_id, actor_id, type, chirp_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    ?,
+    ?,
+    ?,
+    ?
+)
+RETURNING *;
+
+-- name: GetNotificationByID :one
+SELECT *
+FROM notifications
+WHERE id = ?;
+
+-- name: GetGroupedNotifications :many
+SELECT
+    type,
+    chirp_id,
+    CAST(COUNT(*) AS INTEGER) AS count,
+    CAST(SUM(read_at IS NULL) AS INTEGER) AS unread,
+    CAST(MAX(created_at) AS TEXT) AS latest_at,
+    CAST((
+        SELECT json_group_array(latest.actor_id)
+        FROM (
+            SELECT actor_id
+            FROM notifications AS grouped
+            WHERE grouped.user_id = notifications.user_id
+                AND grouped.type = notifications.type
+                AND grouped.chirp_id IS notifications.chirp_id
+            ORDER BY grouped.created_at DESC
+            LIMIT 5
+        ) AS latest
+    ) AS TEXT) AS actor_ids
+FROM notifications
+WHERE user_id = ?
+GROUP BY type, chirp_id
+ORDER BY latest_at DESC
+LIMIT ? OFFSET ?;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*)
+FROM notifications
+WHERE user_id = ? AND read_at IS NULL;
+
+-- name: MarkNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = sqlc.arg(user_id)
+    AND read_at IS NULL
+    AND (sqlc.narg(type) IS NULL OR type = sqlc.narg(type))
+    AND (sqlc.narg(chirp_id) IS NULL OR chirp_id = sqlc.narg(chirp_id));
+
+-- name: IsNotificationTypeMuted :one
+SELECT EXISTS (
+    SELECT 1
+    FROM muted_notification_types
+    WHERE user_id = ? AND type = ?
+);
+
+-- name: GetMutedNotificationTypes :many
+SELECT type
+FROM muted_notification_types
+WHERE user_id = ?
+ORDER BY type ASC;
+
+-- name: MuteNotificationType :exec
+INSERT INTO muted_notification_types (user_id, type)
+VALUES (
+    ?,
+    ?
+)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteMutedNotificationTypes :exec
+DELETE FROM muted_notification_types
+WHERE user_id = ?;
\ No newline at end of file
@@ -0,0 +1,101 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (id, created_at, updated_at, user_id, name, secret_hash, redirect_uris, scopes)
+VALUES (
+    ?,
+    NOW(),
+    NOW(),
+    ?,
+    ?,
+    ?,
+    ?,
+    ?
+)
+RETURNING *;
+
+-- name: GetOAuthClientByID :one
+SELECT *
+FROM oauth_clients
+WHERE id = ?;
+
+-- name: GetOAuthClientsByUser :many
+SELECT *
+FROM oauth_clients
+WHERE user_id = ?
+ORDER BY created_at ASC;
+
+-- name: DeleteOAuthClientByID :exec
+DELETE FROM oauth_clients
+WHERE id = ?;
+
+-- name: CreateAuthorizationCode :exec
+INSERT INTO oauth_authorization_codes (code_hash, created_at, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at)
+VALUES (
+    ?,
+    NOW(),
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?
+);
+
+-- name: ConsumeAuthorizationCode :one
+UPDATE oauth_authorization_codes
+SET used_at = NOW()
+WHERE code_hash = ? AND used_at IS NULL AND expires_at > NOW()
+RETURNING *;
+
+-- name: UpsertOAuthConsent :one
+INSERT INTO oauth_consents (user_id, client_id, created_at, updated_at, scopes)
+VALUES (
+    ?,
+    ?,
+    NOW(),
+    NOW(),
+    ?
+)
+ON CONFLICT (user_id, client_id) DO UPDATE
+SET scopes = EXCLUDED.scopes, updated_at = NOW()
+RETURNING *;
+
+-- name: GetOAuthConsent :one
+SELECT *
+FROM oauth_consents
+WHERE user_id = ? AND client_id = ?;
+
+-- name: GetOAuthConsentsByUser :many
+SELECT *
+FROM oauth_consents
+WHERE user_id = ?
+ORDER BY created_at ASC;
+
+-- name: DeleteOAuthConsent :exec
+DELETE FROM oauth_consents
+WHERE user_id = ? AND client_id = ?;
+
+-- name: CreateOAuthAccessToken :exec
+INSERT INTO oauth_access_tokens (id, created_at, client_id, user_id, scopes, expires_at)
+VALUES (
+    ?,
+    NOW(),
+    ?,
+    ?,
+    ?,
+    ?
+);
+
+-- name: GetOAuthAccessToken :one
+SELECT *
+FROM oauth_access_tokens
+WHERE id = ?;
+
+-- name: RevokeOAuthAccessToken :exec
+UPDATE oauth_access_tokens
+SET revoked_at = NOW()
+WHERE id = ? AND revoked_at IS NULL;
+
+-- name: RevokeOAuthAccessTokensForClient :exec
+UPDATE oauth_access_tokens
+SET revoked_at = NOW()
+WHERE user_id = ? AND client_id = ? AND revoked_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,38 @@
+-- name: CreateRefreshRoken :one
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at)
+VALUES (
+    ?,
+    NOW(),
+    NOW(),
+    ?,
+    strftime('%Y-%m-%d %H:%M:%f+00:00', 'now', '+60 days')
+)
+RETURNING *;
+
+-- name: GetRefreshToken :one
+SELECT *
+FROM refresh_tokens
+WHERE token = ?;
+
+-- name: RevokeRefreshToken :exec
+UPDATE refresh_tokens
+SET revoked_at = sqlc.arg(revoked_at), updated_at = sqlc.arg(revoked_at)
+WHERE token = sqlc.arg(token);
+
+-- name: CreateOAuthRefreshToken :one
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, client_id, scopes)
+VALUES (
+    ?,
+    NOW(),
+    NOW(),
+    ?,
+    strftime('%Y-%m-%d %H:%M:%f+00:00', 'now', '+60 days'),
+    ?,
+    ?
+)
+RETURNING *;
+
+-- name: RevokeRefreshTokensForClient :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = ? AND client_id = ? AND revoked_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,40 @@
+-- name: CreateScheduledChirp :one
+INSERT INTO scheduled_chirps (id, created_at, updated_at, body, user_id, scheduled_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    ?,
+    ?,
+    ?
+)
+RETURNING *;
+
+-- name: GetScheduledChirpsByUser :many
+SELECT *
+FROM scheduled_chirps
+WHERE user_id = ?
+ORDER BY scheduled_at ASC;
+
+-- name: GetScheduledChirpByID :one
+SELECT *
+FROM scheduled_chirps
+WHERE id = ?;
+
+-- name: DeleteScheduledChirpByID :exec
+DELETE FROM scheduled_chirps
+WHERE id = ?;
+
+-- name: ClaimDueScheduledChirp :one
+SELECT *
+FROM scheduled_chirps
+WHERE scheduled_at <= NOW()
+    AND (next_attempt_at IS NULL OR next_attempt_at <= NOW())
+    AND attempts < ?
+ORDER BY scheduled_at ASC
+LIMIT 1;
+
+-- name: RecordScheduledChirpFailure :exec
+UPDATE scheduled_chirps
+SET attempts = attempts + 1, last_error = ?, next_attempt_at = ?, updated_at = NOW()
+WHERE id = ?;
\ No newline at end of file
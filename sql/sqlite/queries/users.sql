@@ -0,0 +1,43 @@
+-- name: CreateUser :one
+INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    ?,
+    ?
+)
+RETURNING *;
+
+-- name: DeleteAllUsers :exec
+DELETE FROM users;
+
+-- name: DeleteUserByID :exec
+DELETE FROM users
+WHERE id = ?;
+
+-- name: GetUserByEmail :one
+SELECT *
+FROM users
+WHERE email = ?;
+
+-- name: GetUserCredentialsByEmail :one
+SELECT *
+FROM users
+WHERE email = ?;
+
+-- name: UpdateUser :one
+UPDATE users
+SET email = ?, hashed_password = ?
+WHERE id = ?
+RETURNING *;
+
+-- name: UpgradeUser :exec
+UPDATE users
+SET is_chirpy_red = true
+WHERE id = ?;
+
+-- name: GetUserByID :one
+SELECT *
+FROM users
+WHERE id = ?;
\ No newline at end of file
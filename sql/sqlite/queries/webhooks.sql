@@ -0,0 +1,92 @@
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (id, created_at, updated_at, user_id, url, secret, events)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    ?,
+    ?,
+    ?,
+    ?
+)
+RETURNING *;
+
+-- name: GetWebhookSubscriptionByID :one
+SELECT *
+FROM webhook_subscriptions
+WHERE id = ?;
+
+-- name: GetWebhookSubscriptionsByUser :many
+SELECT *
+FROM webhook_subscriptions
+WHERE user_id = ?
+ORDER BY created_at ASC;
+
+-- name: DeleteWebhookSubscriptionByID :exec
+DELETE FROM webhook_subscriptions
+WHERE id = ?;
+
+-- name: EnqueueWebhookDeliveries :many
+INSERT INTO webhook_deliveries (id, created_at, updated_at, subscription_id, event, payload, next_attempt_at)
+SELECT gen_random_uuid(), NOW(), NOW(), webhook_subscriptions.id, sqlc.arg(event), sqlc.arg(payload), NOW()
+FROM webhook_subscriptions
+WHERE webhook_subscriptions.user_id = sqlc.arg(user_id) AND sqlc.arg(event) IN (SELECT value FROM json_each(webhook_subscriptions.events))
+RETURNING id;
+
+-- name: ClaimDueWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET next_attempt_at = sqlc.arg(lease_until), updated_at = NOW()
+WHERE webhook_deliveries.id IN (
+    SELECT due.id
+    FROM webhook_deliveries AS due
+    WHERE due.status = 'pending' AND due.next_attempt_at <= NOW()
+    ORDER BY due.next_attempt_at ASC
+    LIMIT sqlc.arg(batch_size)
+)
+RETURNING *;
+
+-- name: MarkWebhookDeliverySucceeded :exec
+UPDATE webhook_deliveries
+SET status = 'succeeded', attempts = attempts + 1, delivered_at = NOW(), updated_at = NOW()
+WHERE id = ?;
+
+-- name: MarkWebhookDeliveryFailed :exec
+UPDATE webhook_deliveries
+SET status = ?, attempts = attempts + 1, next_attempt_at = ?, updated_at = NOW()
+WHERE id = ?;
+
+-- name: RedeliverWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status = 'pending', attempts = 0, next_attempt_at = NOW(), delivered_at = NULL, updated_at = NOW()
+WHERE id = ?
+RETURNING *;
+
+-- name: GetWebhookDeliveryByID :one
+SELECT *
+FROM webhook_deliveries
+WHERE id = ?;
+
+-- name: GetWebhookDeliveries :many
+SELECT *
+FROM webhook_deliveries
+WHERE subscription_id = sqlc.arg(subscription_id)
+    AND (sqlc.narg(status) IS NULL OR status = sqlc.narg(status))
+ORDER BY created_at DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: CreateWebhookDeliveryAttempt :exec
+INSERT INTO webhook_delivery_attempts (id, created_at, delivery_id, status_code, error, duration_ms)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    ?,
+    ?,
+    ?,
+    ?
+);
+
+-- name: GetWebhookDeliveryAttempts :many
+SELECT *
+FROM webhook_delivery_attempts
+WHERE delivery_id = ?
+ORDER BY created_at ASC;
\ No newline at end of file
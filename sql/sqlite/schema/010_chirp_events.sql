@@ -0,0 +1,49 @@
+-- +goose Up
+-- Every change to a chirp is recorded here by the triggers below. Unlike
+-- Postgres there is no NOTIFY, so the relay polls this table instead.
+CREATE TABLE chirp_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at TIMESTAMP NOT NULL,
+    type TEXT NOT NULL,
+    chirp_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    body TEXT NOT NULL,
+    chirp_created_at TIMESTAMP NOT NULL,
+    chirp_updated_at TIMESTAMP NOT NULL
+);
+
+-- +goose StatementBegin
+CREATE TRIGGER chirps_record_insert
+AFTER INSERT ON chirps
+FOR EACH ROW
+BEGIN
+    INSERT INTO chirp_events (created_at, type, chirp_id, user_id, body, chirp_created_at, chirp_updated_at)
+    VALUES (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'), 'chirp.created', NEW.id, NEW.user_id, NEW.body, NEW.created_at, NEW.updated_at);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER chirps_record_update
+AFTER UPDATE ON chirps
+FOR EACH ROW
+BEGIN
+    INSERT INTO chirp_events (created_at, type, chirp_id, user_id, body, chirp_created_at, chirp_updated_at)
+    VALUES (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'), 'chirp.updated', NEW.id, NEW.user_id, NEW.body, NEW.created_at, NEW.updated_at);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER chirps_record_delete
+AFTER DELETE ON chirps
+FOR EACH ROW
+BEGIN
+    INSERT INTO chirp_events (created_at, type, chirp_id, user_id, body, chirp_created_at, chirp_updated_at)
+    VALUES (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'), 'chirp.deleted', OLD.id, OLD.user_id, OLD.body, OLD.created_at, OLD.updated_at);
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER chirps_record_delete;
+DROP TRIGGER chirps_record_update;
+DROP TRIGGER chirps_record_insert;
+DROP TABLE chirp_events;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Postgres notifies listeners of new notifications from a trigger. SQLite
+-- runs in a single process, which publishes them directly, so there is
+-- nothing to do here; the migration only keeps the versions in step.
+SELECT 1;
+
+-- +goose Down
+SELECT 1;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE scheduled_chirps ADD COLUMN next_attempt_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE scheduled_chirps DROP COLUMN next_attempt_at;
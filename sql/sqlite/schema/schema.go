@@ -0,0 +1,9 @@
+// Package schema embeds the SQLite migrations. They mirror the Postgres ones
+// in sql/schema version for version, so both backends report the same
+// schema version.
+package schema
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
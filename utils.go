@@ -1,11 +1,25 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"mime"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/ghis9917/chirpy/internal/auth"
+	"github.com/ghis9917/chirpy/internal/database"
+	"github.com/ghis9917/chirpy/internal/logging"
+	"github.com/ghis9917/chirpy/internal/scheduler"
+	"github.com/google/uuid"
 )
 
 func sendResponse(w http.ResponseWriter, contentType string, statusCode int, content []byte) {
@@ -14,24 +28,234 @@ func sendResponse(w http.ResponseWriter, contentType string, statusCode int, con
 	w.Write(content)
 }
 
+// extractParams decodes a JSON request body of at most
+// MAX_REQUEST_BODY_SIZE bytes into params, rejecting unknown fields and
+// anything after the object, then validates it if it is a validator.
 func extractParams[T any](params T, req *http.Request) (T, error) {
 
-	decoder := json.NewDecoder(req.Body)
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mediaType != CONTENT_TYPE_JSON {
+		return params, errUnsupportedMediaType
+	}
 
-	err := decoder.Decode(&params)
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, req.Body, MAX_REQUEST_BODY_SIZE))
+	if _, ok := any(params).(unknownFieldsAllowed); !ok {
+		decoder.DisallowUnknownFields()
+	}
+
+	err = decoder.Decode(&params)
+	if err == nil {
+		if _, trailing := decoder.Token(); trailing != io.EOF {
+			err = errors.New("unexpected data after the JSON object")
+		}
+	}
 	if err != nil {
-		log.Printf("Error decoding parameters: %s", err)
-		return params, fmt.Errorf("Error decoding parameters: %s", err)
+		logging.FromContext(req.Context()).Info("Error decoding parameters", "err", err)
+		return params, fmt.Errorf("Error decoding parameters: %w", err)
+	}
+
+	if v, ok := any(params).(validator); ok {
+		var checks validation
+		v.validate(&checks)
+		if err := checks.err(); err != nil {
+			return params, err
+		}
 	}
 
 	return params, nil
 }
 
+// sendParamsError answers a request whose parameters extractParams, or a
+// check made after it, rejected.
+func sendParamsError(w http.ResponseWriter, err error) {
+
+	var tooLarge *http.MaxBytesError
+	var invalid *validationError
+	switch {
+	case errors.Is(err, errUnsupportedMediaType):
+		sendJSONResponse(w, http.StatusUnsupportedMediaType, jsonErr{Error: fmt.Sprintf("%s", err)})
+	case errors.As(err, &tooLarge):
+		sendJSONResponse(w, http.StatusRequestEntityTooLarge, jsonErr{Error: fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit)})
+	case errors.As(err, &invalid):
+		sendJSONResponse(w, http.StatusBadRequest, validationErr{Error: "Invalid parameters", Fields: invalid.fields})
+	default:
+		sendJSONResponse(w, http.StatusBadRequest, jsonErr{Error: fmt.Sprintf("%s", err)})
+	}
+
+}
+
+func (cfg *apiConfig) authenticate(req *http.Request) (uuid.UUID, error) {
+
+	claims, err := cfg.authorize(req)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return claims.UserID, nil
+}
+
+type authorizationKey struct{}
+
+// authorization is the outcome of checking a request's credentials, kept on
+// its context so they are only checked once.
+type authorization struct {
+	claims auth.Claims
+	err    error
+}
+
+// withAuthorization authorizes req and returns it with the outcome on its
+// context, for authorize to return to handlers.
+func (cfg *apiConfig) withAuthorization(req *http.Request) (*http.Request, auth.Claims, error) {
+
+	claims, err := cfg.authorize(req)
+	req = req.WithContext(context.WithValue(req.Context(), authorizationKey{}, authorization{claims: claims, err: err}))
+
+	return req, claims, err
+}
+
+// authorize validates the bearer token or personal API key and, for tokens
+// issued to OAuth clients, checks that the grant has not been revoked since.
+// The user is recorded on the request's log lines. Behind the auth
+// middleware it returns what the middleware found instead of checking again.
+func (cfg *apiConfig) authorize(req *http.Request) (auth.Claims, error) {
+
+	if a, ok := req.Context().Value(authorizationKey{}).(authorization); ok {
+		return a.claims, a.err
+	}
+
+	var claims auth.Claims
+	if apiKey, err := auth.GetAPIKey(req.Header); err == nil {
+		claims, err = cfg.parseAPIKey(req.Context(), apiKey)
+		if err != nil {
+			return auth.Claims{}, err
+		}
+	} else {
+		bearer, err := auth.GetBearerToken(req.Header)
+		if err != nil {
+			return auth.Claims{}, err
+		}
+		claims, err = cfg.parseAccessToken(req.Context(), bearer)
+		if err != nil {
+			return auth.Claims{}, err
+		}
+	}
+
+	logging.SetUserID(req.Context(), claims.UserID.String())
+
+	return claims, nil
+}
+
+func (cfg *apiConfig) parseAPIKey(ctx context.Context, apiKey string) (auth.Claims, error) {
+
+	key, err := cfg.db.GetAPIKeyByHash(ctx, auth.HashToken(apiKey))
+	if err != nil {
+		return auth.Claims{}, errors.New("Invalid API key")
+	}
+	if key.RevokedAt.Valid {
+		return auth.Claims{}, errors.New("API key has been revoked")
+	}
+	if key.ExpiresAt.Valid && time.Now().After(key.ExpiresAt.Time) {
+		return auth.Claims{}, errors.New("API key has expired")
+	}
+
+	// last_used_at is only shown to the owner, so a write per request is not
+	// worth it for keys in constant use.
+	if !key.LastUsedAt.Valid || time.Since(key.LastUsedAt.Time) >= API_KEY_TOUCH_INTERVAL {
+		if err := cfg.db.TouchAPIKey(ctx, key.ID); err != nil {
+			logging.FromContext(ctx).Error("Error recording API key use", "api_key_id", key.ID, "err", err)
+		}
+	}
+
+	return auth.Claims{
+		UserID:    key.UserID,
+		APIKeyID:  key.ID,
+		Scopes:    key.Scopes,
+		ExpiresAt: key.ExpiresAt.Time,
+	}, nil
+}
+
+func (cfg *apiConfig) parseAccessToken(ctx context.Context, token string) (auth.Claims, error) {
+
+	claims, err := auth.ParseJWT(token, cfg.serverSecret)
+	if err != nil {
+		return auth.Claims{}, err
+	}
+
+	if claims.ClientID == "" {
+		return claims, nil
+	}
+
+	grant, err := cfg.db.GetOAuthAccessToken(ctx, claims.TokenID)
+	if err != nil || grant.RevokedAt.Valid {
+		return auth.Claims{}, errors.New("Token has been revoked")
+	}
+
+	return claims, nil
+}
+
+func parsePagination(req *http.Request) (int32, int32, error) {
+
+	limit, offset := int64(DEFAULT_PAGE_SIZE), int64(0)
+
+	if v := req.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || parsed < 1 || parsed > MAX_PAGE_SIZE {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", MAX_PAGE_SIZE)
+		}
+		limit = parsed
+	}
+
+	if v := req.URL.Query().Get("offset"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || parsed < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+		offset = parsed
+	}
+
+	return int32(limit), int32(offset), nil
+}
+
+// markBookmarks fills in BookmarkedByMe when the request carries valid
+// credentials with the read scope; anonymous requests leave the field out
+// of the response.
+func (cfg *apiConfig) markBookmarks(req *http.Request, chirps []Chirp) error {
+
+	claims, err := cfg.authorize(req)
+	if err != nil || !claims.HasScope(SCOPE_READ) || len(chirps) == 0 {
+		return nil
+	}
+	userID := claims.UserID
+
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, c := range chirps {
+		ids = append(ids, c.ID)
+	}
+
+	bookmarked, err := cfg.db.GetBookmarkedChirpIDs(
+		req.Context(),
+		database.GetBookmarkedChirpIDsParams{
+			UserID:   userID,
+			ChirpIds: ids,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	for i := range chirps {
+		isBookmarked := slices.Contains(bookmarked, chirps[i].ID)
+		chirps[i].BookmarkedByMe = &isBookmarked
+	}
+
+	return nil
+}
+
 func sendJSONResponse[T any](w http.ResponseWriter, statusCode int, v T) {
 
 	data, err := json.Marshal(v)
 	if err != nil {
-		log.Printf("Error marshalling JSON: %s", err)
+		slog.Error("Error marshalling JSON", "err", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -45,14 +269,209 @@ func sendJSONResponse[T any](w http.ResponseWriter, statusCode int, v T) {
 
 }
 
-func cleanChirp(chirp string) string {
+// validateChirp reports problems as a validationError on the body field,
+// whether the body came from a request or a stored draft.
+func (cfg *apiConfig) validateChirp(body string) (string, error) {
+
+	var checks validation
+	if checks.required("body", body) {
+		checks.maxLength("body", body, cfg.chirps.MaxLength)
+	}
+	if err := checks.err(); err != nil {
+		return "", err
+	}
+
+	return cleanChirp(body, cfg.chirps.ProfaneWords), nil
+}
+
+func cleanChirp(chirp string, profaneWords []string) string {
 
 	words := strings.Split(chirp, " ")
 	for i := range words {
-		if PROFANE_WORDS[strings.ToLower(words[i])] {
+		if slices.ContainsFunc(profaneWords, func(w string) bool { return strings.EqualFold(w, words[i]) }) {
 			words[i] = "****"
 		}
 	}
 
 	return strings.Join(words, " ")
 }
+
+func toScheduledChirp(s database.ScheduledChirp) ScheduledChirp {
+
+	scheduled := ScheduledChirp{
+		ID:          s.ID,
+		CreatedAt:   s.CreatedAt,
+		UpdatedAt:   s.UpdatedAt,
+		Body:        s.Body,
+		UserID:      s.UserID,
+		ScheduledAt: s.ScheduledAt,
+		Attempts:    s.Attempts,
+		LastError:   nullString(s.LastError),
+		Failed:      s.Attempts >= scheduler.MaxAttempts,
+	}
+	if s.NextAttemptAt.Valid && !scheduled.Failed {
+		scheduled.NextAttemptAt = &s.NextAttemptAt.Time
+	}
+
+	return scheduled
+}
+
+func nullString(s sql.NullString) string {
+	if !s.Valid {
+		return ""
+	}
+	return s.String
+}
+
+func toDraft(d database.Draft) Draft {
+	return Draft{
+		ID:        d.ID,
+		CreatedAt: d.CreatedAt,
+		UpdatedAt: d.UpdatedAt,
+		Body:      d.Body,
+		UserID:    d.UserID,
+	}
+}
+
+func toChirp(c database.Chirp) Chirp {
+	return Chirp{
+		ID:        c.ID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		Body:      c.Body,
+		UserID:    c.UserID,
+	}
+}
+
+func toUserResponse(u database.User) updateUserResponse {
+	return updateUserResponse{
+		ID:          u.ID,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+		Email:       u.Email,
+		IsChirpyRed: u.IsChirpyRed,
+	}
+}
+
+func toCollection(c database.Collection) Collection {
+	return Collection{
+		ID:        c.ID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		UserID:    c.UserID,
+		Name:      c.Name,
+	}
+}
+
+func toNotification(n database.Notification) Notification {
+
+	notification := Notification{
+		ID:        n.ID,
+		CreatedAt: n.CreatedAt,
+		Type:      n.Type,
+		ActorID:   n.ActorID,
+		Read:      n.ReadAt.Valid,
+	}
+	if n.ChirpID.Valid {
+		notification.ChirpID = &n.ChirpID.UUID
+	}
+
+	return notification
+}
+
+func toWebhookSubscription(s database.WebhookSubscription) WebhookSubscription {
+	return WebhookSubscription{
+		ID:        s.ID,
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+		URL:       s.Url,
+		Events:    s.Events,
+	}
+}
+
+func toWebhookDelivery(d database.WebhookDelivery) WebhookDelivery {
+
+	delivery := WebhookDelivery{
+		ID:            d.ID,
+		CreatedAt:     d.CreatedAt,
+		UpdatedAt:     d.UpdatedAt,
+		Event:         d.Event,
+		Status:        d.Status,
+		Attempts:      d.Attempts,
+		NextAttemptAt: d.NextAttemptAt,
+		Payload:       json.RawMessage(d.Payload),
+	}
+	if d.DeliveredAt.Valid {
+		delivery.DeliveredAt = &d.DeliveredAt.Time
+	}
+
+	return delivery
+}
+
+func toWebhookDeliveryAttempt(a database.WebhookDeliveryAttempt) WebhookDeliveryAttempt {
+
+	attempt := WebhookDeliveryAttempt{
+		ID:         a.ID,
+		CreatedAt:  a.CreatedAt,
+		Error:      nullString(a.Error),
+		DurationMs: a.DurationMs,
+	}
+	if a.StatusCode.Valid {
+		attempt.StatusCode = &a.StatusCode.Int32
+	}
+
+	return attempt
+}
+
+func toOAuthClient(c database.OauthClient) OAuthClient {
+	return OAuthClient{
+		ID:           c.ID,
+		CreatedAt:    c.CreatedAt,
+		UpdatedAt:    c.UpdatedAt,
+		Name:         c.Name,
+		RedirectURIs: c.RedirectUris,
+		Scopes:       c.Scopes,
+		Confidential: c.SecretHash.Valid,
+	}
+}
+
+func toOAuthConsent(c database.OauthConsent) OAuthConsent {
+	return OAuthConsent{
+		ClientID:  c.ClientID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		Scopes:    c.Scopes,
+	}
+}
+
+func toAPIKey(k database.ApiKey) APIKey {
+
+	key := APIKey{
+		ID:        k.ID,
+		CreatedAt: k.CreatedAt,
+		UpdatedAt: k.UpdatedAt,
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		Scopes:    k.Scopes,
+	}
+	if k.ExpiresAt.Valid {
+		key.ExpiresAt = &k.ExpiresAt.Time
+	}
+	if k.LastUsedAt.Valid {
+		key.LastUsedAt = &k.LastUsedAt.Time
+	}
+	if k.RevokedAt.Valid {
+		key.RevokedAt = &k.RevokedAt.Time
+	}
+
+	return key
+}
+
+func toIdentity(i database.Identity) Identity {
+	return Identity{
+		ID:        i.ID,
+		CreatedAt: i.CreatedAt,
+		Provider:  i.Provider,
+		Email:     i.Email,
+	}
+}
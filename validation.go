@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rivo/uniseg"
+)
+
+var errUnsupportedMediaType = errors.New("Request body must be " + CONTENT_TYPE_JSON)
+
+// validator is implemented by request parameters with rules beyond their
+// JSON shape. extractParams runs validate once the body is decoded.
+type validator interface {
+	validate(v *validation)
+}
+
+// unknownFieldsAllowed is implemented by parameters sent by third parties,
+// who may add fields to their payloads at any time.
+type unknownFieldsAllowed interface {
+	allowUnknownFields()
+}
+
+// validation collects every problem with a request's fields, so a client
+// can fix them all in one round trip.
+type validation struct {
+	fields []fieldError
+}
+
+func (v *validation) fail(field, format string, args ...any) {
+	v.fields = append(v.fields, fieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validation) required(field, value string) bool {
+
+	if strings.TrimSpace(value) == "" {
+		v.fail(field, "is required")
+		return false
+	}
+
+	return true
+}
+
+// maxLength counts user-perceived characters, so an emoji made of several
+// code points, or a letter with combining accents, counts once.
+func (v *validation) maxLength(field, value string, max int) {
+	if uniseg.GraphemeClusterCount(value) > max {
+		v.fail(field, "must be at most %d characters", max)
+	}
+}
+
+// email accepts a bare address, without a display name or angle brackets.
+func (v *validation) email(field, value string) {
+
+	if !v.required(field, value) {
+		return
+	}
+
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Name != "" || addr.Address != value {
+		v.fail(field, "must be an email address")
+	}
+
+}
+
+func (v *validation) uuid(field, value string) {
+	if _, err := uuid.Parse(value); err != nil {
+		v.fail(field, "must be a UUID")
+	}
+}
+
+// httpURL accepts absolute http and https URLs, without a fragment when
+// noFragment is set, and reports whether value passed.
+func (v *validation) httpURL(field, value string, noFragment bool) bool {
+
+	target, err := url.Parse(value)
+	if err != nil || (target.Scheme != "https" && target.Scheme != "http") || target.Host == "" {
+		v.fail(field, "must be an absolute http(s) URL")
+		return false
+	}
+	if noFragment && target.Fragment != "" {
+		v.fail(field, "must not have a fragment")
+		return false
+	}
+
+	return true
+}
+
+func (v *validation) err() error {
+
+	if len(v.fields) == 0 {
+		return nil
+	}
+
+	return &validationError{fields: v.fields}
+}
+
+type validationError struct {
+	fields []fieldError
+}
+
+func (e *validationError) Error() string {
+
+	problems := make([]string, len(e.fields))
+	for i, f := range e.fields {
+		problems[i] = f.Field + " " + f.Message
+	}
+
+	return "Invalid parameters: " + strings.Join(problems, ", ")
+}
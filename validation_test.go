@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidation(t *testing.T) {
+	var v validation
+	v.email("email", "alice@example.com")
+	v.email("display_name", "Alice <alice@example.com>")
+	v.email("empty", "")
+	v.maxLength("flags", "🇳🇱🇮🇹", 2)
+	v.maxLength("accents", "ééé", 2)
+	v.uuid("chirp_id", "not-a-uuid")
+	v.httpURL("redirect_uri", "https://example.com/cb#frag", true)
+	v.httpURL("url", "ftp://example.com", false)
+
+	var got []string
+	for _, f := range v.fields {
+		got = append(got, f.Field)
+	}
+	want := []string{"display_name", "empty", "accents", "chirp_id", "redirect_uri", "url"}
+	if !slices.Equal(got, want) {
+		t.Errorf("failed fields = %v, want %v", got, want)
+	}
+
+	if err := (&validation{}).err(); err != nil {
+		t.Errorf("err() with no failures = %v, want nil", err)
+	}
+	if err := v.err(); err == nil || !strings.HasPrefix(err.Error(), "Invalid parameters: display_name must be an email address") {
+		t.Errorf("err() = %v, want every failure listed", err)
+	}
+}
+
+func TestExtractParams(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		want        int
+	}{
+		{name: "charset", target: "/api/users", contentType: "application/json; charset=utf-8", body: `{"email":"alice@example.com","password":"x"}`, want: http.StatusCreated},
+		{name: "no content type", target: "/api/users", body: `{"email":"bob@example.com","password":"x"}`, want: http.StatusUnsupportedMediaType},
+		{name: "form", target: "/api/users", contentType: "application/x-www-form-urlencoded", body: "email=bob@example.com", want: http.StatusUnsupportedMediaType},
+		{name: "unknown field", target: "/api/users", contentType: CONTENT_TYPE_JSON, body: `{"email":"bob@example.com","password":"x","admin":true}`, want: http.StatusBadRequest},
+		{name: "trailing data", target: "/api/users", contentType: CONTENT_TYPE_JSON, body: `{"email":"bob@example.com","password":"x"} {}`, want: http.StatusBadRequest},
+		{name: "too large", target: "/api/users", contentType: CONTENT_TYPE_JSON, body: `{"email":"` + strings.Repeat("b", MAX_REQUEST_BODY_SIZE) + `"}`, want: http.StatusRequestEntityTooLarge},
+		{name: "third party fields", target: "/api/polka/webhooks", contentType: CONTENT_TYPE_JSON, body: `{"event":"user.payment_failed","data":{"user_id":"x","amount":5},"id":"evt_1"}`, want: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := s.request("POST", tt.target, tt.body)
+			req.Header.Del("Content-Type")
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			req.Header.Set("Authorization", "ApiKey "+testPolkaSecret)
+
+			expectStatus(t, s.serve(req), tt.want)
+		})
+	}
+}